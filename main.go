@@ -1,58 +1,636 @@
 package main
 
 import (
+	"context"
+	"expvar"
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
-	_ "github.com/GoogleCloudPlatform/cloudsql-proxy/proxy/dialers/postgres"
+	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/johannaojeling/go-rest-api/pkg/api"
+	"github.com/johannaojeling/go-rest-api/pkg/audit"
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/cache"
 	"github.com/johannaojeling/go-rest-api/pkg/database"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/grpcapi"
+	"github.com/johannaojeling/go-rest-api/pkg/history"
+	"github.com/johannaojeling/go-rest-api/pkg/httpcache"
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
 	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/passwordreset"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
 	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+	"github.com/johannaojeling/go-rest-api/pkg/verification"
+	"github.com/johannaojeling/go-rest-api/pkg/webhooks"
+)
+
+const (
+	brokerBufferSize = 256
+	brokerReplaySize = 1024
+	relayInterval    = time.Second
+	relayBatchSize   = 100
+	pruneInterval    = time.Hour
+	tokenIssuer      = "go-rest-api"
+	accessTokenTTL   = 15 * time.Minute
+	refreshTokenTTL  = 30 * 24 * time.Hour
+	verificationTTL  = 24 * time.Hour
+	passwordResetTTL = time.Hour
+	resetsPerEmail   = 3
+	resetsPerIP      = 20
+	sendsPerUser     = 3
+	userCacheSize    = 10_000
+	negativeCacheTTL = 5 * time.Second
+	replicaInterval  = 10 * time.Second
+	replicaTimeout   = 2 * time.Second
 )
 
 var (
-	dbDriver = os.Getenv("DB_DRIVER")
-	dbUrl    = os.Getenv("DB_URL")
-	port     = os.Getenv("PORT")
+	dbDriver        = os.Getenv("DB_DRIVER")
+	dbUrl           = os.Getenv("DB_URL")
+	dbMode          = os.Getenv("DB_MODE")
+	dbSocketDir     = os.Getenv("DB_SOCKET_DIR")
+	dbIAMAuth       = os.Getenv("DB_IAM_AUTH")
+	dbMaxOpenConns  = os.Getenv("DB_MAX_OPEN_CONNS")
+	dbMaxIdleConns  = os.Getenv("DB_MAX_IDLE_CONNS")
+	dbConnLifetime  = os.Getenv("DB_CONN_MAX_LIFETIME")
+	dbConnIdleTime  = os.Getenv("DB_CONN_MAX_IDLE_TIME")
+	dbRetries       = os.Getenv("DB_CONNECT_RETRIES")
+	dbReplicaUrls   = os.Getenv("DB_REPLICA_URLS")
+	dbReplicaPolicy = os.Getenv("DB_REPLICA_POLICY")
+	dbStickyWindow  = os.Getenv("DB_STICKY_WINDOW")
+	dbJobsUrl       = os.Getenv("DB_JOBS_URL")
+	port            = os.Getenv("PORT")
+	debugAddr       = os.Getenv("DEBUG_ADDR")
+	apiTokens       = os.Getenv("API_TOKENS")
+	pubSubEndpoint  = os.Getenv("PUBSUB_ENDPOINT")
+	pubSubProject   = os.Getenv("PUBSUB_PROJECT")
+	pubSubTopic     = os.Getenv("PUBSUB_TOPIC")
+	historyRetain   = os.Getenv("HISTORY_RETENTION")
+	jwtSecret       = os.Getenv("JWT_SECRET")
+	adminUserIds    = os.Getenv("ADMIN_USER_IDS")
+	userPlans       = os.Getenv("USER_PLANS")
+	argon2Params    = os.Getenv("ARGON2_PARAMS")
+	publicUrl       = os.Getenv("PUBLIC_URL")
+	smtpAddr        = os.Getenv("SMTP_ADDR")
+	smtpUsername    = os.Getenv("SMTP_USERNAME")
+	smtpPassword    = os.Getenv("SMTP_PASSWORD")
+	mailFrom        = os.Getenv("MAIL_FROM")
+	tenantDomain    = os.Getenv("TENANT_DOMAIN")
+	tenantRLS       = os.Getenv("TENANT_RLS")
+	rateLimits      = os.Getenv("RATE_LIMITS")
+	rateLimitKey    = os.Getenv("RATE_LIMIT_KEY")
+	rateLimitAlgo   = os.Getenv("RATE_LIMIT_ALGORITHM")
+	redisUrl        = os.Getenv("REDIS_URL")
+	userCacheTTL    = os.Getenv("USER_CACHE_TTL")
+	cacheControl    = os.Getenv("CACHE_CONTROL")
+	trustedProxies  = os.Getenv("TRUSTED_PROXIES")
+	trustedPlatform = os.Getenv("TRUSTED_PLATFORM")
 )
 
 func init() {
 	if dbDriver == "" {
 		dbDriver = "postgres"
 	}
+	if dbMode == "" {
+		dbMode = string(database.TCP)
+	}
+	if dbSocketDir == "" {
+		dbSocketDir = "/cloudsql"
+	}
+	if dbRetries == "" {
+		dbRetries = "5"
+	}
 	if port == "" {
 		port = "8080"
 	}
+	if debugAddr == "" {
+		debugAddr = "localhost:6060"
+	}
+	if dbReplicaPolicy == "" {
+		dbReplicaPolicy = string(database.RoundRobin)
+	}
+	if dbStickyWindow == "" {
+		dbStickyWindow = "5s"
+	}
+	if rateLimitKey == "" {
+		rateLimitKey = string(ratelimit.KeyIP)
+	}
+	if rateLimitAlgo == "" {
+		rateLimitAlgo = string(ratelimit.TokenBucket)
+	}
+	if publicUrl == "" {
+		publicUrl = "http://localhost:" + port
+	}
 }
 
 func main() {
-	gormDB, err := setUpDatabase(dbDriver, dbUrl)
+	ctx := context.Background()
+
+	dbConfig, err := setUpDatabaseConfig(ctx)
+	if err != nil {
+		log.Fatalf("error parsing database config: %v", err)
+	}
+	gormDB, err := setUpDatabase(dbConfig)
 	if err != nil {
 		log.Fatalf("error setting up database: %v", err)
 	}
 
-	userRepository := repositories.NewSQLUserRepository(gormDB)
-	app := api.NewApp(userRepository)
+	// Replicas are registered before the database is shared with background
+	// jobs, which would otherwise race with the registration of callbacks.
+	var appOptions []api.Option
+	if dbReplicaUrls != "" {
+		option, err := setUpReplicas(ctx, gormDB, dbConfig)
+		if err != nil {
+			log.Fatalf("error setting up read replicas: %v", err)
+		}
+		appOptions = append(appOptions, option)
+	}
+
+	jobsDB, err := setUpJobsDatabase(gormDB, dbConfig)
+	if err != nil {
+		log.Fatalf("error setting up database of background jobs: %v", err)
+	}
+
+	redisClient, err := setUpRedis()
+	if err != nil {
+		log.Fatalf("error setting up redis: %v", err)
+	}
+
+	sqlUserRepository := repositories.NewSQLUserRepository(
+		gormDB,
+		verification.NewHook(),
+		events.NewOutboxWriter(),
+		audit.NewWriter(),
+		history.NewWriter(),
+	)
+	credentialRepository := repositories.NewSQLCredentialRepository(gormDB)
+	groupRepository := repositories.NewSQLGroupRepository(gormDB)
+	verificationRepository := repositories.NewSQLVerificationRepository(gormDB)
+	if tenantRLS == "true" {
+		err = tenancy.MigrateRowLevelSecurity(gormDB)
+		if err != nil {
+			log.Fatalf("error setting up row-level security: %v", err)
+		}
+		if dbJobsUrl == "" {
+			log.Printf("background jobs are restricted by row-level security without DB_JOBS_URL")
+		}
+		sqlUserRepository.WithRowLevelSecurity()
+		credentialRepository.WithRowLevelSecurity()
+		groupRepository.WithRowLevelSecurity()
+		verificationRepository.WithRowLevelSecurity()
+	}
+	var userRepository repositories.UserRepository = sqlUserRepository
+	if userCacheTTL != "" {
+		userRepository, err = setUpUserCache(sqlUserRepository, redisClient)
+		if err != nil {
+			log.Fatalf("error setting up user cache: %v", err)
+		}
+	}
+
+	mailer := setUpMailer()
+	verificationService := verification.NewService(
+		userRepository,
+		verificationRepository,
+		mailer,
+		strings.TrimSuffix(publicUrl, "/")+"/verify-email",
+		verificationTTL,
+	)
+
+	dispatcher := webhooks.NewDispatcher(repositories.NewSQLWebhookRepository(jobsDB))
+	go dispatcher.Run(ctx)
+
+	broker := events.NewBroker(brokerBufferSize, brokerReplaySize)
+	pqListener, err := database.NewListener(dbConfig)
+	if err != nil {
+		log.Fatalf("error creating database listener: %v", err)
+	}
+	listener := events.NewListener(pqListener, broker)
+	go func() {
+		if err := listener.Run(ctx); err != nil {
+			log.Fatalf("error running event listener: %v", err)
+		}
+	}()
+
+	notifyPublisher := events.NewNotifyPublisher(jobsDB)
+	publisher, err := setUpPublisher(ctx, events.MultiPublisher{
+		notifyPublisher,
+		dispatcher,
+		verification.NewPublisher(verificationService),
+	})
+	if err != nil {
+		log.Fatalf("error setting up event publisher: %v", err)
+	}
+	relay := events.NewRelay(jobsDB, publisher, relayInterval, relayBatchSize)
+	go relay.Run(ctx)
+
+	var authenticators auth.ChainAuthenticator
+	if apiTokens != "" {
+		principals, err := auth.ParseStaticTokens(apiTokens)
+		if err != nil {
+			log.Fatalf("error parsing api tokens: %v", err)
+		}
+		authenticators = append(authenticators, auth.NewStaticTokenAuthenticator(principals))
+	}
+
+	tenantRepository := repositories.NewSQLTenantRepository(gormDB)
+	appOptions = append(
+		appOptions,
+		api.WithTenancy(tenantRepository, tenantDomain),
+		api.WithAudit(repositories.NewSQLAuditRepository(gormDB)),
+		api.WithWebhooks(repositories.NewSQLWebhookRepository(gormDB)),
+		api.WithOutbox(repositories.NewSQLOutboxRepository(gormDB)),
+		api.WithGroups(groupRepository),
+		api.WithUserEvents(broker, events.NewOutboxHistory(gormDB)),
+		api.WithEmailVerification(
+			verificationService,
+			ratelimit.NewMemoryLimiter(sendsPerUser, time.Hour),
+		),
+	)
+	if jwtSecret != "" {
+		params := passwords.DefaultParams
+		if argon2Params != "" {
+			params, err = passwords.ParseParams(argon2Params)
+			if err != nil {
+				log.Fatalf("error parsing argon2 parameters: %v", err)
+			}
+		}
+		var denylist auth.Denylist = auth.NewMemoryDenylist()
+		if redisClient != nil {
+			denylist = auth.NewRedisDenylist(redisClient, "denylist:")
+		}
+		issuer := auth.NewTokenIssuer([]byte(jwtSecret), tokenIssuer, accessTokenTTL).
+			WithDenylist(denylist)
+		authenticators = append(authenticators, issuer)
+		sessionManager := sessions.NewManager(
+			repositories.NewSQLSessionRepository(gormDB),
+			issuer,
+			denylist,
+			refreshTokenTTL,
+		)
+		if adminUserIds != "" {
+			sessionManager.WithAdmins(strings.Split(adminUserIds, ","))
+		}
+		if userPlans != "" {
+			plans, err := sessions.ParsePlans(userPlans)
+			if err != nil {
+				log.Fatalf("error parsing user plans: %v", err)
+			}
+			sessionManager.WithPlans(plans)
+		}
+		hasher := passwords.NewHasher(params)
+		passwordResetService := passwordreset.NewService(
+			userRepository,
+			credentialRepository,
+			repositories.NewSQLPasswordResetRepository(gormDB),
+			sessionManager,
+			hasher,
+			passwords.DefaultPolicy,
+			mailer,
+			strings.TrimSuffix(publicUrl, "/")+"/reset-password",
+			passwordResetTTL,
+		)
+		appOptions = append(
+			appOptions,
+			api.WithPasswordAuth(credentialRepository, hasher, issuer, sessionManager),
+			api.WithPasswordReset(
+				passwordResetService,
+				ratelimit.NewMemoryLimiter(resetsPerEmail, time.Hour),
+				ratelimit.NewMemoryLimiter(resetsPerIP, time.Hour),
+			),
+			api.WithMFA(mfa.NewService(repositories.NewSQLMFARepository(gormDB), tokenIssuer)),
+		)
+	}
+
+	var authenticator auth.Authenticator
+	if len(authenticators) > 0 {
+		authenticator = authenticators
+		appOptions = append(
+			appOptions,
+			api.WithSCIM(strings.TrimSuffix(publicUrl, "/")+"/scim/v2"),
+		)
+	}
+
+	if rateLimits != "" {
+		option, err := setUpRateLimit(redisClient)
+		if err != nil {
+			log.Fatalf("error setting up rate limiting: %v", err)
+		}
+		appOptions = append(appOptions, option)
+	}
+
+	if trustedProxies != "" || trustedPlatform != "" {
+		option, err := setUpTrustedProxies()
+		if err != nil {
+			log.Fatalf("error setting up trusted proxies: %v", err)
+		}
+		appOptions = append(appOptions, option)
+	}
+
+	if cacheControl != "" {
+		policies, err := httpcache.ParsePolicies(cacheControl)
+		if err != nil {
+			log.Fatalf("error parsing cache control: %v", err)
+		}
+		appOptions = append(appOptions, api.WithCacheControl(policies))
+	}
+
+	var retention time.Duration
+	if historyRetain != "" {
+		retention, err = time.ParseDuration(historyRetain)
+		if err != nil {
+			log.Fatalf("error parsing history retention: %v", err)
+		}
+	}
+	historyRepository := repositories.NewSQLHistoryRepository(gormDB)
+	if retention > 0 {
+		pruneRepository := repositories.NewSQLHistoryRepository(jobsDB)
+		go history.NewPruner(pruneRepository, retention, pruneInterval).Run(ctx)
+	}
+
+	appOptions = append(
+		appOptions,
+		api.WithAuthenticator(authenticator),
+		api.WithHistory(history.NewReader(userRepository, historyRepository, retention)),
+	)
+	app := api.NewApp(userRepository, appOptions...)
+	grpcServer := grpcapi.NewServer(
+		userRepository,
+		authenticator,
+		tenancy.NewResolver(tenantRepository, tenantDomain),
+	)
+
+	go serveDebug()
+
+	server := &http.Server{
+		Addr:    ":" + port,
+		Handler: grpcapi.Multiplex(grpcServer, app),
+	}
 
 	log.Printf("listening on port %s\n", port)
-	if err := app.Run(":" + port); err != nil {
-		log.Fatalf("error running app: %v", err)
+	if err := server.ListenAndServe(); err != nil {
+		log.Fatalf("error running server: %v", err)
 	}
 }
 
-func setUpDatabase(driver string, dsn string) (*gorm.DB, error) {
-	gormDB, err := database.GetConnection(driver, dsn)
+// serveDebug serves the published variables on an internal address, apart
+// from the public port.
+func serveDebug() {
+	handler := http.NewServeMux()
+	handler.Handle("/debug/vars", expvar.Handler())
+	server := &http.Server{Addr: debugAddr, Handler: handler}
+
+	log.Printf("serving debug variables on %s\n", debugAddr)
+	if err := server.ListenAndServe(); err != nil {
+		log.Printf("error serving debug variables: %v", err)
+	}
+}
+
+func setUpDatabaseConfig(ctx context.Context) (database.Config, error) {
+	config := database.Config{Driver: dbDriver, DSN: dbUrl, SocketDir: dbSocketDir}
+	var err error
+	config.Mode, err = database.ParseMode(dbMode)
+	if err != nil {
+		return config, err
+	}
+	if config.Mode == database.Connector {
+		config.Dialer, err = database.NewCloudSQLDialer(ctx, dbIAMAuth == "true")
+		if err != nil {
+			return config, err
+		}
+	}
+
+	config.ConnectRetries, err = strconv.Atoi(dbRetries)
+	if err != nil {
+		return config, fmt.Errorf("error parsing connect retries: %v", err)
+	}
+	if dbMaxOpenConns != "" {
+		config.Pool.MaxOpenConns, err = strconv.Atoi(dbMaxOpenConns)
+		if err != nil {
+			return config, fmt.Errorf("error parsing max open connections: %v", err)
+		}
+	}
+	if dbMaxIdleConns != "" {
+		config.Pool.MaxIdleConns, err = strconv.Atoi(dbMaxIdleConns)
+		if err != nil {
+			return config, fmt.Errorf("error parsing max idle connections: %v", err)
+		}
+	}
+	if dbConnLifetime != "" {
+		config.Pool.ConnMaxLifetime, err = time.ParseDuration(dbConnLifetime)
+		if err != nil {
+			return config, fmt.Errorf("error parsing connection max lifetime: %v", err)
+		}
+	}
+	if dbConnIdleTime != "" {
+		config.Pool.ConnMaxIdleTime, err = time.ParseDuration(dbConnIdleTime)
+		if err != nil {
+			return config, fmt.Errorf("error parsing connection max idle time: %v", err)
+		}
+	}
+	return config, nil
+}
+
+func setUpDatabase(config database.Config) (*gorm.DB, error) {
+	gormDB, err := database.GetConnection(config)
 	if err != nil {
 		return nil, fmt.Errorf("error getting database connection: %v", err)
 	}
 
-	err = gormDB.AutoMigrate(&models.User{})
+	// Existing users and groups are moved to the default tenant, which has to
+	// exist before the foreign keys are added.
+	err = gormDB.AutoMigrate(&models.Tenant{})
+	if err != nil {
+		return nil, fmt.Errorf("error migrating tenants: %v", err)
+	}
+	err = gormDB.Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.Tenant{Id: tenancy.DefaultTenant, Name: "Default"}).
+		Error
+	if err != nil {
+		return nil, fmt.Errorf("error creating default tenant: %v", err)
+	}
+
+	err = gormDB.AutoMigrate(
+		&models.User{},
+		&models.OutboxMessage{},
+		&models.AuditEntry{},
+		&models.UserFieldChange{},
+		&models.Credential{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.VerificationToken{},
+		&models.PasswordResetToken{},
+		&models.TOTPFactor{},
+		&models.RecoveryCode{},
+		&models.Group{},
+		&models.GroupMember{},
+		&models.GroupNesting{},
+		&models.Webhook{},
+		&models.WebhookDelivery{},
+		&models.WebhookAttempt{},
+	)
 	if err != nil {
 		return nil, fmt.Errorf("error migrating database: %v", err)
 	}
 	return gormDB, nil
 }
+
+// setUpJobsDatabase connects background jobs as the role of DB_JOBS_URL, which
+// bypasses row-level security so that jobs can work across tenants. Without it,
+// jobs share the database of requests.
+func setUpJobsDatabase(gormDB *gorm.DB, config database.Config) (*gorm.DB, error) {
+	if dbJobsUrl == "" {
+		return gormDB, nil
+	}
+	config.DSN = dbJobsUrl
+	jobsDB, err := database.GetConnection(config)
+	if err != nil {
+		return nil, fmt.Errorf("error getting database connection of background jobs: %v", err)
+	}
+	return jobsDB, nil
+}
+
+func setUpPublisher(
+	ctx context.Context,
+	publishers events.MultiPublisher,
+) (events.Publisher, error) {
+	if pubSubTopic == "" {
+		return publishers, nil
+	}
+
+	pubSubPublisher, err := events.NewPubSubPublisher(
+		ctx,
+		pubSubEndpoint,
+		pubSubProject,
+		pubSubTopic,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return append(events.MultiPublisher{pubSubPublisher}, publishers...), nil
+}
+
+func setUpRedis() (*redis.Client, error) {
+	if redisUrl == "" {
+		return nil, nil
+	}
+	options, err := redis.ParseURL(redisUrl)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing redis url: %v", err)
+	}
+	return redis.NewClient(options), nil
+}
+
+func setUpUserCache(
+	userRepository repositories.UserRepository,
+	redisClient *redis.Client,
+) (*repositories.CachedUserRepository, error) {
+	ttl, err := time.ParseDuration(userCacheTTL)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing user cache ttl: %v", err)
+	}
+
+	var userCache cache.Cache = cache.NewLRU(userCacheSize)
+	if redisClient != nil {
+		userCache = cache.NewRedis(redisClient, "cache:")
+	}
+	cachedUserRepository := repositories.NewCachedUserRepository(
+		userRepository,
+		userCache,
+		ttl,
+		negativeCacheTTL,
+	)
+	if dbReplicaUrls != "" {
+		window, err := time.ParseDuration(dbStickyWindow)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing sticky window: %v", err)
+		}
+		cachedUserRepository.WithStickyWindow(window)
+	}
+	expvar.Publish("user_cache", expvar.Func(func() any {
+		return cachedUserRepository.Stats()
+	}))
+	return cachedUserRepository, nil
+}
+
+func setUpReplicas(
+	ctx context.Context,
+	gormDB *gorm.DB,
+	config database.Config,
+) (api.Option, error) {
+	policy, err := database.ParsePolicy(dbReplicaPolicy)
+	if err != nil {
+		return nil, err
+	}
+	window, err := time.ParseDuration(dbStickyWindow)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing sticky window: %v", err)
+	}
+
+	replicas, err := database.UseReplicas(
+		gormDB,
+		config,
+		strings.Split(dbReplicaUrls, ","),
+		policy,
+	)
+	if err != nil {
+		return nil, err
+	}
+	go replicas.Run(ctx, replicaInterval, replicaTimeout)
+	return api.WithReadYourWrites(window), nil
+}
+
+func setUpRateLimit(redisClient *redis.Client) (api.Option, error) {
+	rules, err := ratelimit.ParseRules(rateLimits)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ratelimit.ParseKey(rateLimitKey)
+	if err != nil {
+		return nil, err
+	}
+	algorithm, err := ratelimit.ParseAlgorithm(rateLimitAlgo)
+	if err != nil {
+		return nil, err
+	}
+
+	var store ratelimit.Store = ratelimit.NewMemoryStore()
+	if redisClient != nil {
+		store = ratelimit.NewRedisStore(redisClient, "ratelimit:")
+	}
+	return api.WithRateLimit(ratelimit.NewPolicy(store, algorithm, rules), key), nil
+}
+
+func setUpTrustedProxies() (api.Option, error) {
+	var proxies []string
+	if trustedProxies != "" {
+		proxies = strings.Split(trustedProxies, ",")
+	}
+	for _, proxy := range proxies {
+		_, _, err := net.ParseCIDR(proxy)
+		if err != nil && net.ParseIP(proxy) == nil {
+			return nil, fmt.Errorf("invalid proxy %q, expecting IP or CIDR", proxy)
+		}
+	}
+	return api.WithTrustedProxies(proxies, trustedPlatform), nil
+}
+
+func setUpMailer() mail.Mailer {
+	if smtpAddr == "" {
+		return mail.NewLogMailer()
+	}
+	return mail.NewSMTPMailer(smtpAddr, smtpUsername, smtpPassword, mailFrom)
+}
@@ -1,29 +1,425 @@
 package api
 
 import (
+	"log"
+	"net/http"
+	"time"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/johannaojeling/go-rest-api/pkg/api/endpoints"
+	"github.com/johannaojeling/go-rest-api/pkg/audit"
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/database"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/graphqlapi"
+	"github.com/johannaojeling/go-rest-api/pkg/history"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/passwordreset"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
 	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/scimapi"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+	"github.com/johannaojeling/go-rest-api/pkg/verification"
+	"github.com/johannaojeling/go-rest-api/pkg/wsapi"
+)
+
+const (
+	apiTitle   = "Go REST API"
+	apiVersion = "1.0.0"
 )
 
 type App struct {
-	router         *gin.Engine
-	userRepository repositories.UserRepository
+	router            *gin.Engine
+	spec              *openapi.Builder
+	userRepository    repositories.UserRepository
+	webhookRepository repositories.WebhookRepository
+	outboxRepository  repositories.OutboxRepository
+	groupRepository   repositories.GroupRepository
+	tenantRepository  repositories.TenantRepository
+	tenantResolver    *tenancy.Resolver
+	rateLimiting      *rateLimiting
+	cachePolicies     map[string]string
+	stickiness        *database.Stickiness
+	auditRepository   repositories.AuditRepository
+	userHistory       *history.Reader
+	passwordAuth      *passwordAuth
+	verification      *emailVerification
+	passwordReset     *passwordReset
+	mfa               *mfa.Service
+	scimBaseURL       string
+	authenticator     auth.Authenticator
+	broker            *events.Broker
+	history           events.History
+	onResponseError   openapi.ResponseErrorHandler
+	trustedProxies    []string
+	trustedPlatform   string
+}
+
+type Option func(app *App)
+
+type rateLimiting struct {
+	policy *ratelimit.Policy
+	key    ratelimit.Key
+}
+
+type passwordReset struct {
+	service      *passwordreset.Service
+	emailLimiter ratelimit.Limiter
+	ipLimiter    ratelimit.Limiter
+}
+
+type emailVerification struct {
+	service     *verification.Service
+	userLimiter ratelimit.Limiter
+}
+
+type passwordAuth struct {
+	credentialRepository repositories.CredentialRepository
+	hasher               *passwords.Hasher
+	issuer               *auth.TokenIssuer
+	sessions             *sessions.Manager
+}
+
+// WithResponseValidation validates responses against the OpenAPI document and
+// reports mismatches to handler.
+func WithResponseValidation(handler openapi.ResponseErrorHandler) Option {
+	return func(app *App) {
+		app.onResponseError = handler
+	}
+}
+
+// WithWebhooks serves webhook subscriptions and their deliveries at /webhooks.
+func WithWebhooks(webhookRepository repositories.WebhookRepository) Option {
+	return func(app *App) {
+		app.webhookRepository = webhookRepository
+	}
+}
+
+// WithOutbox serves the events that the outbox relay gave up on to admins at
+// /outbox, so they can be retried.
+func WithOutbox(outboxRepository repositories.OutboxRepository) Option {
+	return func(app *App) {
+		app.outboxRepository = outboxRepository
+	}
+}
+
+// WithGroups serves groups and their members at /groups.
+func WithGroups(groupRepository repositories.GroupRepository) Option {
+	return func(app *App) {
+		app.groupRepository = groupRepository
+	}
+}
+
+// WithTenancy resolves the tenant of every request and serves tenant
+// management at /tenants. Hosts below baseDomain select the tenant by their
+// first label.
+func WithTenancy(tenantRepository repositories.TenantRepository, baseDomain string) Option {
+	return func(app *App) {
+		app.tenantRepository = tenantRepository
+		app.tenantResolver = tenancy.NewResolver(tenantRepository, baseDomain)
+	}
+}
+
+// WithRateLimit limits the requests of each caller, told apart by key, to the
+// quotas of policy.
+func WithRateLimit(policy *ratelimit.Policy, key ratelimit.Key) Option {
+	return func(app *App) {
+		app.rateLimiting = &rateLimiting{policy: policy, key: key}
+	}
+}
+
+// WithCacheControl sets Cache-Control directives by route, as "GET /users/".
+func WithCacheControl(policies map[string]string) Option {
+	return func(app *App) {
+		app.cachePolicies = policies
+	}
+}
+
+// WithReadYourWrites reads from the primary database for callers that wrote
+// within window, when reads otherwise go to replicas.
+func WithReadYourWrites(window time.Duration) Option {
+	return func(app *App) {
+		app.stickiness = database.NewStickiness(window)
+	}
+}
+
+// WithAudit serves the audit log of users.
+func WithAudit(auditRepository repositories.AuditRepository) Option {
+	return func(app *App) {
+		app.auditRepository = auditRepository
+	}
+}
+
+// WithHistory serves the field history of users.
+func WithHistory(reader *history.Reader) Option {
+	return func(app *App) {
+		app.userHistory = reader
+	}
+}
+
+// WithPasswordAuth serves password login, token refresh and the sessions of
+// users.
+func WithPasswordAuth(
+	credentialRepository repositories.CredentialRepository,
+	hasher *passwords.Hasher,
+	issuer *auth.TokenIssuer,
+	sessionManager *sessions.Manager,
+) Option {
+	return func(app *App) {
+		app.passwordAuth = &passwordAuth{
+			credentialRepository: credentialRepository,
+			hasher:               hasher,
+			issuer:               issuer,
+			sessions:             sessionManager,
+		}
+	}
+}
+
+// WithPasswordReset serves password reset requests, limited by email and IP.
+func WithPasswordReset(
+	service *passwordreset.Service,
+	emailLimiter ratelimit.Limiter,
+	ipLimiter ratelimit.Limiter,
+) Option {
+	return func(app *App) {
+		app.passwordReset = &passwordReset{
+			service:      service,
+			emailLimiter: emailLimiter,
+			ipLimiter:    ipLimiter,
+		}
+	}
+}
+
+// WithEmailVerification serves email verification, limiting the emails sent
+// to each user.
+func WithEmailVerification(service *verification.Service, userLimiter ratelimit.Limiter) Option {
+	return func(app *App) {
+		app.verification = &emailVerification{
+			service:     service,
+			userLimiter: userLimiter,
+		}
+	}
+}
+
+// WithMFA serves MFA enrollment and requires a second factor at login from
+// users who enrolled.
+func WithMFA(service *mfa.Service) Option {
+	return func(app *App) {
+		app.mfa = service
+	}
 }
 
-func NewApp(userRepository repositories.UserRepository) *App {
+// WithSCIM serves SCIM 2.0 provisioning at /scim/v2, which baseURL is the
+// public URL of.
+func WithSCIM(baseURL string) Option {
+	return func(app *App) {
+		app.scimBaseURL = baseURL
+	}
+}
+
+// WithAuthenticator identifies callers by the bearer token of their requests.
+func WithAuthenticator(authenticator auth.Authenticator) Option {
+	return func(app *App) {
+		app.authenticator = authenticator
+	}
+}
+
+// WithUserEvents streams user events over server-sent events and WebSockets,
+// replaying missed events from history.
+func WithUserEvents(broker *events.Broker, history events.History) Option {
+	return func(app *App) {
+		app.broker = broker
+		app.history = history
+	}
+}
+
+// WithTrustedProxies takes the client IP from the X-Forwarded-For and X-Real-IP
+// headers of requests sent by proxies, given as IPs or CIDRs, or from the
+// platformHeader set by a platform such as gin.PlatformCloudflare. By default
+// the client IP is the address the request came from.
+func WithTrustedProxies(proxies []string, platformHeader string) Option {
+	return func(app *App) {
+		app.trustedProxies = proxies
+		app.trustedPlatform = platformHeader
+	}
+}
+
+func NewApp(userRepository repositories.UserRepository, options ...Option) *App {
 	app := &App{
 		router:         gin.Default(),
+		spec:           openapi.NewBuilder(apiTitle, apiVersion),
 		userRepository: userRepository,
 	}
+	for _, option := range options {
+		option(app)
+	}
+
+	// Rate limits and the audit log rely on the client IP, which any client
+	// could choose if every proxy was trusted.
+	err := app.router.SetTrustedProxies(app.trustedProxies)
+	if err != nil {
+		log.Printf("error setting trusted proxies, trusting none: %v", err)
+		_ = app.router.SetTrustedProxies(nil)
+	}
+	app.router.TrustedPlatform = app.trustedPlatform
+
+	app.registerMiddleware()
 	app.registerHandlers()
 	return app
 }
 
+func (app *App) registerMiddleware() {
+	validator := openapi.NewValidator(app.spec.Document())
+	if app.onResponseError != nil {
+		validator.WithResponseValidation(app.onResponseError)
+	}
+	app.router.Use(audit.Middleware())
+	if app.authenticator != nil {
+		app.router.Use(identify(app.authenticator))
+	}
+	if app.tenantResolver != nil {
+		app.router.Use(resolveTenant(app.tenantResolver))
+	}
+	if app.rateLimiting != nil {
+		app.router.Use(limitRate(app.rateLimiting.policy, app.rateLimiting.key))
+	}
+	if app.stickiness != nil {
+		app.router.Use(stickToPrimary(app.stickiness))
+	}
+	if len(app.cachePolicies) > 0 {
+		app.router.Use(cacheControl(app.cachePolicies))
+	}
+	app.router.Use(validator.Middleware())
+}
+
 func (app *App) registerHandlers() {
 	userGroup := app.router.Group("/users")
-	endpoints.NewUsersHandler(app.userRepository).Register(userGroup)
+	usersHandler := endpoints.NewUsersHandler(app.userRepository)
+	if app.userHistory != nil {
+		usersHandler.WithHistory(app.userHistory)
+	}
+	usersHandler.Register(userGroup)
+	app.spec.AddRoutes(userGroup.BasePath(), usersHandler.Routes())
+
+	if app.broker != nil {
+		userEventsHandler := endpoints.NewUserEventsHandler(app.broker, app.history)
+		userEventsHandler.Register(userGroup)
+		app.spec.AddRoutes(userGroup.BasePath(), userEventsHandler.Routes())
+	}
+
+	graphqlGroup := app.router.Group("/graphql")
+	graphqlHandler := graphqlapi.NewHandler(app.userRepository, graphqlapi.DefaultLimits)
+	graphqlHandler.Register(graphqlGroup)
+	app.spec.AddRoutes(graphqlGroup.BasePath(), graphqlHandler.Routes())
+
+	if app.auditRepository != nil {
+		auditGroup := app.router.Group("")
+		auditHandler := endpoints.NewAuditHandler(app.auditRepository)
+		auditHandler.Register(auditGroup)
+		app.spec.AddRoutes(auditGroup.BasePath(), auditHandler.Routes())
+	}
+
+	if app.passwordAuth != nil {
+		authGroup := app.router.Group("")
+		authHandler := endpoints.NewAuthHandler(
+			app.userRepository,
+			app.passwordAuth.credentialRepository,
+			app.passwordAuth.hasher,
+			passwords.DefaultPolicy,
+			app.passwordAuth.issuer,
+			app.passwordAuth.sessions,
+		)
+		if app.mfa != nil {
+			authHandler.WithMFA(app.mfa)
+		}
+		authHandler.Register(authGroup)
+		app.spec.AddRoutes(authGroup.BasePath(), authHandler.Routes())
+
+		sessionsGroup := app.router.Group("")
+		sessionsHandler := endpoints.NewSessionsHandler(app.passwordAuth.sessions)
+		sessionsHandler.Register(sessionsGroup)
+		app.spec.AddRoutes(sessionsGroup.BasePath(), sessionsHandler.Routes())
+	}
+
+	if app.passwordReset != nil {
+		passwordResetGroup := app.router.Group("")
+		passwordResetHandler := endpoints.NewPasswordResetHandler(
+			app.passwordReset.service,
+			app.passwordReset.emailLimiter,
+			app.passwordReset.ipLimiter,
+		)
+		passwordResetHandler.Register(passwordResetGroup)
+		app.spec.AddRoutes(passwordResetGroup.BasePath(), passwordResetHandler.Routes())
+	}
+
+	if app.verification != nil {
+		verificationGroup := app.router.Group("")
+		verificationHandler := endpoints.NewVerificationHandler(
+			app.verification.service,
+			app.verification.userLimiter,
+		)
+		verificationHandler.Register(verificationGroup)
+		app.spec.AddRoutes(verificationGroup.BasePath(), verificationHandler.Routes())
+	}
+
+	if app.mfa != nil {
+		mfaGroup := app.router.Group("")
+		mfaHandler := endpoints.NewMFAHandler(app.userRepository, app.mfa)
+		mfaHandler.Register(mfaGroup)
+		app.spec.AddRoutes(mfaGroup.BasePath(), mfaHandler.Routes())
+	}
+
+	if app.webhookRepository != nil {
+		webhookGroup := app.router.Group("/webhooks")
+		webhooksHandler := endpoints.NewWebhooksHandler(app.webhookRepository)
+		webhooksHandler.Register(webhookGroup)
+		app.spec.AddRoutes(webhookGroup.BasePath(), webhooksHandler.Routes())
+	}
+
+	if app.outboxRepository != nil {
+		outboxGroup := app.router.Group("/outbox")
+		outboxHandler := endpoints.NewOutboxHandler(app.outboxRepository)
+		outboxHandler.Register(outboxGroup)
+		app.spec.AddRoutes(outboxGroup.BasePath(), outboxHandler.Routes())
+	}
+
+	if app.groupRepository != nil {
+		groupsGroup := app.router.Group("")
+		groupsHandler := endpoints.NewGroupsHandler(app.groupRepository)
+		groupsHandler.Register(groupsGroup)
+		app.spec.AddRoutes(groupsGroup.BasePath(), groupsHandler.Routes())
+	}
+
+	if app.tenantRepository != nil {
+		tenantsGroup := app.router.Group("/tenants")
+		tenantsHandler := endpoints.NewTenantsHandler(app.tenantRepository)
+		tenantsHandler.Register(tenantsGroup)
+		app.spec.AddRoutes(tenantsGroup.BasePath(), tenantsHandler.Routes())
+	}
+
+	if app.broker != nil {
+		websocketGroup := app.router.Group("/ws")
+		websocketHandler := wsapi.NewHandler(app.broker, wsapi.DefaultLimits)
+		websocketHandler.Register(websocketGroup)
+		app.spec.AddRoutes(websocketGroup.BasePath(), websocketHandler.Routes())
+	}
+
+	// SCIM describes itself through its discovery endpoints and is not part of
+	// the OpenAPI document, which also keeps its requests out of validation.
+	if app.scimBaseURL != "" {
+		scimGroup := app.router.Group("/scim/v2")
+		scimapi.NewHandler(app.userRepository, app.scimBaseURL).Register(scimGroup)
+	}
+
+	openapi.NewDocsHandler(app.spec.Document()).Register(app.router)
+}
+
+func (app *App) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	app.router.ServeHTTP(writer, request)
 }
 
 func (app *App) Run(port string) error {
@@ -0,0 +1,473 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/audit"
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/history"
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/passwordreset"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+	"github.com/johannaojeling/go-rest-api/pkg/verification"
+)
+
+// Routes that are not described by the OpenAPI document. SCIM describes
+// itself through its discovery endpoints.
+var docsRoutes = map[string]bool{
+	"/openapi.json":                  true,
+	"/docs":                          true,
+	"/swagger-ui/*filepath":          true,
+	"/scim/v2/Users":                 true,
+	"/scim/v2/Users/:id":             true,
+	"/scim/v2/ServiceProviderConfig": true,
+	"/scim/v2/ResourceTypes":         true,
+	"/scim/v2/ResourceTypes/:id":     true,
+	"/scim/v2/Schemas":               true,
+	"/scim/v2/Schemas/:id":           true,
+}
+
+func TestApp_SpecDescribesAllRoutes(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+	sessionManager := sessions.NewManager(
+		repositories.NewSQLSessionRepository(nil),
+		issuer,
+		auth.NewMemoryDenylist(),
+		time.Hour,
+	)
+	hasher := passwords.NewHasher(passwords.DefaultParams)
+	mailer := mail.NewMemoryMailer()
+
+	app := NewApp(
+		userRepository,
+		WithAudit(repositories.NewSQLAuditRepository(nil)),
+		WithWebhooks(repositories.NewMemoryWebhookRepository()),
+		WithOutbox(repositories.NewSQLOutboxRepository(nil)),
+		WithUserEvents(events.NewBroker(1, 0), nil),
+		WithHistory(
+			history.NewReader(userRepository, repositories.NewSQLHistoryRepository(nil), 0),
+		),
+		WithPasswordAuth(
+			repositories.NewSQLCredentialRepository(nil),
+			hasher,
+			issuer,
+			sessionManager,
+		),
+		WithPasswordReset(
+			passwordreset.NewService(
+				userRepository,
+				repositories.NewSQLCredentialRepository(nil),
+				repositories.NewSQLPasswordResetRepository(nil),
+				sessionManager,
+				hasher,
+				passwords.DefaultPolicy,
+				mailer,
+				"http://localhost/reset-password",
+				time.Hour,
+			),
+			ratelimit.NewMemoryLimiter(1, time.Hour),
+			ratelimit.NewMemoryLimiter(1, time.Hour),
+		),
+		WithEmailVerification(
+			verification.NewService(
+				userRepository,
+				repositories.NewSQLVerificationRepository(nil),
+				mailer,
+				"http://localhost/verify-email",
+				time.Hour,
+			),
+			ratelimit.NewMemoryLimiter(1, time.Hour),
+		),
+		WithMFA(mfa.NewService(repositories.NewSQLMFARepository(nil), "test")),
+		WithGroups(repositories.NewSQLGroupRepository(nil)),
+		WithTenancy(repositories.NewSQLTenantRepository(nil), ""),
+		WithSCIM("http://localhost/scim/v2"),
+	)
+	document := app.spec.Document()
+
+	for _, route := range app.router.Routes() {
+		if docsRoutes[route.Path] {
+			continue
+		}
+
+		specPath := openapi.ToSpecPath(route.Path)
+		item, ok := document.Paths[specPath]
+		if !assert.Truef(t, ok, "Should describe path %s", specPath) {
+			continue
+		}
+		assert.NotNilf(
+			t,
+			item.Operation(route.Method),
+			"Should describe operation %s %s",
+			route.Method,
+			specPath,
+		)
+	}
+}
+
+func TestApp_ServeDocs(t *testing.T) {
+	app := NewApp(nil)
+
+	for _, path := range []string{"/docs", "/swagger-ui/swagger-ui-bundle.js", "/swagger-ui/swagger-ui.css"} {
+		request, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+
+		recorder := httptest.NewRecorder()
+		app.router.ServeHTTP(recorder, request)
+
+		assert.Equalf(t, 200, recorder.Code, "Should serve %s", path)
+		assert.NotContainsf(
+			t,
+			recorder.Body.String(),
+			"unpkg.com",
+			"Should not load %s from CDN",
+			path,
+		)
+	}
+}
+
+func TestApp_ServeSpec(t *testing.T) {
+	app := NewApp(nil)
+
+	request, err := http.NewRequest("GET", "/openapi.json", nil)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	app.router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 200, recorder.Code, "Should match response code")
+
+	var actualBody map[string]interface{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+
+	assert.Equal(t, openapi.Version, actualBody["openapi"], "Should match OpenAPI version")
+
+	userRequest := actualBody["components"].(map[string]interface{})["schemas"].(map[string]interface{})["UserRequest"]
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"first_name": map[string]interface{}{"type": "string"},
+				"last_name":  map[string]interface{}{"type": "string"},
+				"email":      map[string]interface{}{"type": "string", "format": "email"},
+			},
+			"required": []interface{}{"first_name", "last_name", "email"},
+		},
+		userRequest,
+		"Should translate binding tags to JSON Schema constraints",
+	)
+}
+
+func TestApp_ValidateRequest(t *testing.T) {
+	app := NewApp(nil)
+
+	jsonBody := []byte(`{"first_name":"Jane","last_name":"Doe","email":"jane"}`)
+	request, err := http.NewRequest("POST", "/users/", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	recorder := httptest.NewRecorder()
+	app.router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 400, recorder.Code, "Should match response code")
+
+	var actualBody map[string]interface{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"details": "request validation failed",
+			"errors": []interface{}{
+				map[string]interface{}{
+					"in":      "body",
+					"field":   "email",
+					"message": "must be a valid email",
+				},
+			},
+		},
+		actualBody,
+		"Should match response body",
+	)
+}
+
+func TestApp_RateLimit(t *testing.T) {
+	rules, err := ratelimit.ParseRules("*=100/1m,GET /users/=1/1m")
+	if err != nil {
+		t.Fatalf("error parsing rules: %v", err)
+	}
+	policy := ratelimit.NewPolicy(ratelimit.NewMemoryStore(), ratelimit.TokenBucket, rules)
+	app := NewApp(repositories.NewMemoryUserRepository(), WithRateLimit(policy, ratelimit.KeyIP))
+
+	send := func(remoteAddr string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", "/users/", nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.RemoteAddr = remoteAddr
+		recorder := httptest.NewRecorder()
+		app.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send("10.0.0.1:1234")
+	assert.Equal(t, 200, recorder.Code, "Should allow request within quota")
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"), "Should set limit of route")
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"), "Should set remaining")
+
+	recorder = send("10.0.0.1:1234")
+	assert.Equal(t, 429, recorder.Code, "Should reject request over quota")
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"), "Should set retry after")
+
+	recorder = send("10.0.0.2:1234")
+	assert.Equal(t, 200, recorder.Code, "Should limit clients separately")
+}
+
+func TestApp_RateLimitForwardedFor(t *testing.T) {
+	rules, err := ratelimit.ParseRules("GET /users/=1/1m")
+	if err != nil {
+		t.Fatalf("error parsing rules: %v", err)
+	}
+
+	testCases := []struct {
+		reason       string
+		options      []Option
+		expectedCode int
+	}{
+		{
+			reason:       "Should ignore forwarded IP of untrusted client",
+			expectedCode: 429,
+		},
+		{
+			reason:       "Should use forwarded IP of trusted proxy",
+			options:      []Option{WithTrustedProxies([]string{"10.0.0.0/8"}, "")},
+			expectedCode: 200,
+		},
+	}
+
+	for i, tc := range testCases {
+		policy := ratelimit.NewPolicy(ratelimit.NewMemoryStore(), ratelimit.TokenBucket, rules)
+		options := append(tc.options, WithRateLimit(policy, ratelimit.KeyIP))
+		app := NewApp(repositories.NewMemoryUserRepository(), options...)
+
+		var recorder *httptest.ResponseRecorder
+		for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2"} {
+			request, err := http.NewRequest("GET", "/users/", nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			request.RemoteAddr = "10.0.0.1:1234"
+			request.Header.Set("X-Forwarded-For", forwardedFor)
+			recorder = httptest.NewRecorder()
+			app.router.ServeHTTP(recorder, request)
+		}
+
+		assert.Equal(t, tc.expectedCode, recorder.Code, fmt.Sprintf("Test %d: %s", i, tc.reason))
+	}
+}
+
+func TestApp_PasswordResetForwardedFor(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	service := passwordreset.NewService(
+		userRepository,
+		repositories.NewSQLCredentialRepository(nil),
+		repositories.NewSQLPasswordResetRepository(nil),
+		nil,
+		passwords.NewHasher(passwords.DefaultParams),
+		passwords.DefaultPolicy,
+		mail.NewMemoryMailer(),
+		"http://localhost/reset-password",
+		time.Hour,
+	)
+	app := NewApp(
+		userRepository,
+		WithPasswordReset(
+			service,
+			ratelimit.NewMemoryLimiter(0, time.Hour),
+			ratelimit.NewMemoryLimiter(1, time.Hour),
+		),
+	)
+
+	var recorder *httptest.ResponseRecorder
+	for _, forwardedFor := range []string{"203.0.113.1", "203.0.113.2"} {
+		request, err := http.NewRequest(
+			"POST",
+			"/auth/password-reset",
+			strings.NewReader(`{"email":"jane.doe@mail.com"}`),
+		)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("Content-Type", "application/json")
+		request.Header.Set("X-Forwarded-For", forwardedFor)
+		recorder = httptest.NewRecorder()
+		app.router.ServeHTTP(recorder, request)
+	}
+
+	assert.Equal(t, 429, recorder.Code, "Should ignore forwarded IP of untrusted client")
+}
+
+func TestApp_AuditIP(t *testing.T) {
+	testCases := []struct {
+		reason     string
+		options    []Option
+		expectedIP string
+	}{
+		{
+			reason:     "Should ignore forwarded IP of untrusted client",
+			expectedIP: "10.0.0.1",
+		},
+		{
+			reason:     "Should use forwarded IP of trusted proxy",
+			options:    []Option{WithTrustedProxies([]string{"10.0.0.1"}, "")},
+			expectedIP: "203.0.113.1",
+		},
+	}
+
+	for i, tc := range testCases {
+		app := NewApp(repositories.NewMemoryUserRepository(), tc.options...)
+		var actualIP string
+		app.router.GET("/ip", func(ctx *gin.Context) {
+			metadata, _ := audit.MetadataFromContext(ctx.Request.Context())
+			actualIP = metadata.IP
+		})
+
+		request, err := http.NewRequest("GET", "/ip", nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.RemoteAddr = "10.0.0.1:1234"
+		request.Header.Set("X-Forwarded-For", "203.0.113.1")
+		app.router.ServeHTTP(httptest.NewRecorder(), request)
+
+		assert.Equal(t, tc.expectedIP, actualIP, fmt.Sprintf("Test %d: %s", i, tc.reason))
+	}
+}
+
+func TestApp_RateLimitPlans(t *testing.T) {
+	rules, err := ratelimit.ParseRules("*=1/1m,@pro=2/1m")
+	if err != nil {
+		t.Fatalf("error parsing rules: %v", err)
+	}
+	principals, err := auth.ParseStaticTokens("partner:partner-token::pro,admin:admin-token:pro")
+	if err != nil {
+		t.Fatalf("error parsing tokens: %v", err)
+	}
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+	policy := ratelimit.NewPolicy(ratelimit.NewMemoryStore(), ratelimit.TokenBucket, rules)
+	app := NewApp(
+		repositories.NewMemoryUserRepository(),
+		WithAuthenticator(auth.ChainAuthenticator{
+			auth.NewStaticTokenAuthenticator(principals),
+			issuer,
+		}),
+		WithRateLimit(policy, ratelimit.KeyAPIKey),
+	)
+
+	send := func(token string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", "/users/", nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		app.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send("partner-token")
+	assert.Equal(t, "2", recorder.Header().Get("RateLimit-Limit"), "Should limit by plan")
+
+	recorder = send("admin-token")
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Limit"), "Should not use roles as plans")
+
+	var tokens []string
+	for _, sessionId := range []string{"def456", "ghi789"} {
+		token, err := issuer.IssueAccessToken("abc123", sessionId, "", nil, "")
+		if err != nil {
+			t.Fatalf("error issuing token: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	recorder = send(tokens[0])
+	assert.Equal(t, 200, recorder.Code, "Should allow request within quota")
+	recorder = send(tokens[1])
+	assert.Equal(t, 429, recorder.Code, "Should limit access tokens of a user together")
+}
+
+func TestApp_CacheControl(t *testing.T) {
+	app := NewApp(
+		repositories.NewMemoryUserRepository(),
+		WithCacheControl(map[string]string{"GET /users/": "private, max-age=60"}),
+	)
+
+	send := func(path string, headers map[string]string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		for key, value := range headers {
+			request.Header.Set(key, value)
+		}
+		recorder := httptest.NewRecorder()
+		app.router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send("/users/", nil)
+	assert.Equal(t, 200, recorder.Code, "Should list users")
+	assert.Equal(
+		t,
+		"private, max-age=60",
+		recorder.Header().Get("Cache-Control"),
+		"Should set policy",
+	)
+	assert.Equal(t, "Authorization, X-Tenant-ID", recorder.Header().Get("Vary"), "Should vary")
+
+	recorder = send("/users/", map[string]string{"If-None-Match": recorder.Header().Get("ETag")})
+	assert.Equal(t, 304, recorder.Code, "Should not be modified")
+	assert.Equal(
+		t,
+		"private, max-age=60",
+		recorder.Header().Get("Cache-Control"),
+		"Should set policy",
+	)
+
+	recorder = send("/users/?limit=0", nil)
+	assert.Equal(t, 400, recorder.Code, "Should reject invalid query")
+	assert.Empty(t, recorder.Header().Get("Cache-Control"), "Should not cache errors")
+
+	recorder = send("/users/abc123", nil)
+	assert.Empty(t, recorder.Header().Get("Cache-Control"), "Should only set policy of route")
+}
@@ -0,0 +1,177 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const (
+	auditTag          = "audit"
+	defaultAuditLimit = 50
+)
+
+type AuditHandler struct {
+	auditRepository repositories.AuditRepository
+}
+
+func NewAuditHandler(auditRepository repositories.AuditRepository) *AuditHandler {
+	return &AuditHandler{
+		auditRepository: auditRepository,
+	}
+}
+
+// Register serves the routes to admins.
+func (handler *AuditHandler) Register(routerGroup *gin.RouterGroup) {
+	routerGroup.Use(requireAdmin)
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *AuditHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/users/:id/audit",
+			Handler:     handler.GetUserAudit,
+			OperationId: "getUserAudit",
+			Summary:     "List audit entries of a user",
+			Tags:        []string{auditTag},
+			URI:         schemas.UserURI{},
+			Query:       schemas.AuditPageQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.AuditEntryResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/audit",
+			Handler:     handler.GetAudit,
+			OperationId: "listAudit",
+			Summary:     "Query audit entries",
+			Tags:        []string{auditTag},
+			Query:       schemas.AuditQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.AuditEntryResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *AuditHandler) GetUserAudit(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	var pageQuery schemas.AuditPageQuery
+	err = ctx.ShouldBindQuery(&pageQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting limit and offset"),
+		)
+		return
+	}
+
+	handler.respond(ctx, repositories.AuditFilter{
+		UserId: userUri.Id,
+		Limit:  pageQuery.Limit,
+		Offset: pageQuery.Offset,
+	})
+}
+
+func (handler *AuditHandler) GetAudit(ctx *gin.Context) {
+	var auditQuery schemas.AuditQuery
+	err := ctx.ShouldBindQuery(&auditQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query"),
+		)
+		return
+	}
+
+	handler.respond(ctx, repositories.AuditFilter{
+		UserId: auditQuery.UserId,
+		Actor:  auditQuery.Actor,
+		Action: auditQuery.Action,
+		Since:  auditQuery.Since,
+		Until:  auditQuery.Until,
+		Limit:  auditQuery.Limit,
+		Offset: auditQuery.Offset,
+	})
+}
+
+func (handler *AuditHandler) respond(ctx *gin.Context, filter repositories.AuditFilter) {
+	if filter.Limit == 0 {
+		filter.Limit = defaultAuditLimit
+	}
+
+	entries, err := handler.auditRepository.GetAuditEntries(ctx.Request.Context(), filter)
+	if err != nil {
+		log.Printf("error getting audit entries: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving audit entries"),
+		)
+		return
+	}
+
+	auditResponseList := make([]schemas.AuditEntryResponse, len(entries))
+	for i, entry := range entries {
+		auditResponseList[i] = auditModelToAuditResponse(entry)
+	}
+
+	ctx.JSON(http.StatusOK, auditResponseList)
+}
+
+func auditModelToAuditResponse(entry *models.AuditEntry) schemas.AuditEntryResponse {
+	return schemas.AuditEntryResponse{
+		Id:        entry.Id,
+		Action:    entry.Action,
+		UserId:    entry.UserId,
+		Actor:     entry.Actor,
+		RequestId: entry.RequestId,
+		IP:        entry.IP,
+		Before:    snapshotToMap(entry.Before),
+		After:     snapshotToMap(entry.After),
+		CreatedAt: entry.CreatedAt,
+	}
+}
+
+func snapshotToMap(data []byte) map[string]any {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var snapshot map[string]any
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		log.Printf("error unmarshaling snapshot: %v", err)
+	}
+	return snapshot
+}
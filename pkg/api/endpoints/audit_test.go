@@ -0,0 +1,93 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestAuditHandler_GetAudit(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(
+			ctx.Request.Context(),
+			&auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}},
+		))
+	})
+	NewAuditHandler(repositories.NewSQLAuditRepository(db)).Register(router.Group(""))
+
+	since := time.Date(2022, 3, 3, 0, 0, 0, 0, time.UTC)
+	query := `SELECT * FROM "audit_log" WHERE actor = $1 AND action = $2 AND created_at >= $3 ` +
+		`ORDER BY id DESC LIMIT 50`
+	rows := sqlmock.NewRows([]string{"id", "action", "user_id", "actor", "before", "after"}).
+		AddRow(1, "update", "abc123", "admin", `{"email":"jane@mail.com"}`, `{"email":"j@mail.com"}`)
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("admin", "update", since).
+		WillReturnRows(rows)
+
+	request, err := http.NewRequest(
+		"GET",
+		"/audit?actor=admin&action=update&since=2022-03-03T00:00:00Z",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 200, recorder.Code, "Should match response code")
+
+	var actualBody []map[string]interface{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+	assert.Equal(
+		t,
+		map[string]interface{}{"email": "jane@mail.com"},
+		actualBody[0]["before"],
+		"Should include before snapshot",
+	)
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should filter audit entries")
+}
+
+func TestAuditHandler_RequireAdmin(t *testing.T) {
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(
+			auth.WithPrincipal(ctx.Request.Context(), &auth.Principal{Subject: "abc123"}),
+		)
+	})
+	NewAuditHandler(repositories.NewSQLAuditRepository(nil)).Register(router.Group(""))
+
+	request, err := http.NewRequest("GET", "/users/abc123/audit", nil)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 403, recorder.Code, "Should require admin role")
+}
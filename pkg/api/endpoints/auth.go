@@ -0,0 +1,576 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const authTag = "auth"
+
+type AuthHandler struct {
+	userRepository       repositories.UserRepository
+	credentialRepository repositories.CredentialRepository
+	hasher               *passwords.Hasher
+	policy               passwords.Policy
+	issuer               *auth.TokenIssuer
+	sessions             *sessions.Manager
+	mfa                  *mfa.Service
+}
+
+func NewAuthHandler(
+	userRepository repositories.UserRepository,
+	credentialRepository repositories.CredentialRepository,
+	hasher *passwords.Hasher,
+	policy passwords.Policy,
+	issuer *auth.TokenIssuer,
+	sessionManager *sessions.Manager,
+) *AuthHandler {
+	return &AuthHandler{
+		userRepository:       userRepository,
+		credentialRepository: credentialRepository,
+		hasher:               hasher,
+		policy:               policy,
+		issuer:               issuer,
+		sessions:             sessionManager,
+	}
+}
+
+// WithMFA makes users who enabled MFA complete login with a second factor.
+func (handler *AuthHandler) WithMFA(service *mfa.Service) *AuthHandler {
+	handler.mfa = service
+	return handler
+}
+
+func (handler *AuthHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *AuthHandler) Routes() []openapi.Route {
+	routes := []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/auth/login",
+			Handler:     handler.Login,
+			OperationId: "login",
+			Summary:     "Log in with email and password",
+			Tags:        []string{authTag},
+			Body:        schemas.LoginRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.TokenResponse{},
+				http.StatusAccepted:            schemas.MFAChallengeResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/auth/refresh",
+			Handler:     handler.Refresh,
+			OperationId: "refreshToken",
+			Summary:     "Exchange a refresh token for new tokens",
+			Tags:        []string{authTag},
+			Body:        schemas.RefreshRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.TokenResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/users/:id/password",
+			Handler:     handler.SetPassword,
+			OperationId: "setPassword",
+			Summary:     "Set the password of a user",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Body:        schemas.SetPasswordRequest{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/users/:id/password/change",
+			Handler:     handler.ChangePassword,
+			OperationId: "changePassword",
+			Summary:     "Change the password of a user",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Body:        schemas.ChangePasswordRequest{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+
+	if handler.mfa != nil {
+		routes = append(routes, openapi.Route{
+			Method:      http.MethodPost,
+			Path:        "/auth/login/mfa",
+			Handler:     handler.LoginMFA,
+			OperationId: "loginMFA",
+			Summary:     "Complete a login with a TOTP or recovery code",
+			Tags:        []string{authTag},
+			Body:        schemas.MFALoginRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.TokenResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusTooManyRequests:     models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		})
+	}
+	return routes
+}
+
+func (handler *AuthHandler) Login(ctx *gin.Context) {
+	var loginRequest schemas.LoginRequest
+	err := ctx.ShouldBindJSON(&loginRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	credential, err := handler.credentialRepository.GetCredentialByEmail(
+		ctx.Request.Context(),
+		loginRequest.Email,
+	)
+	if errors.Is(err, repositories.ErrCredentialNotFound) {
+		// Hash anyway so unknown emails take as long as wrong passwords.
+		_, _ = handler.hasher.Hash(loginRequest.Password)
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("invalid email or password"),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting credential: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error logging in"),
+		)
+		return
+	}
+
+	match, rehash, err := handler.hasher.Verify(loginRequest.Password, credential.PasswordHash)
+	if err != nil {
+		log.Printf("error verifying password: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error logging in"),
+		)
+		return
+	}
+	if !match {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("invalid email or password"),
+		)
+		return
+	}
+	if rehash {
+		err = handler.rehashPassword(ctx, credential.UserId, loginRequest.Password)
+		if err != nil {
+			log.Printf("error rehashing password: %v", err)
+		}
+	}
+
+	if handler.mfa != nil {
+		enabled, err := handler.mfa.Enabled(ctx.Request.Context(), credential.UserId)
+		if err != nil {
+			log.Printf("error getting mfa status: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				models.NewErrorMessage("error logging in"),
+			)
+			return
+		}
+		if enabled {
+			handler.challengeMFA(ctx, credential.UserId)
+			return
+		}
+	}
+	if handler.sessions.IsAdmin(credential.UserId) {
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			models.NewErrorMessage("admins must enable mfa before logging in"),
+		)
+		return
+	}
+
+	handler.issueTokens(ctx, credential.UserId)
+}
+
+func (handler *AuthHandler) LoginMFA(ctx *gin.Context) {
+	var loginRequest schemas.MFALoginRequest
+	err := ctx.ShouldBindJSON(&loginRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	tenantId, _ := tenancy.TenantFromContext(ctx.Request.Context())
+	principal, err := handler.issuer.ParseMFAToken(
+		ctx.Request.Context(),
+		loginRequest.MFAToken,
+	)
+	if err == nil && principal.TenantId != tenantId {
+		err = tenancy.ErrTenantMismatch
+	}
+	if err != nil {
+		log.Printf("invalid mfa token: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("invalid or expired mfa token"),
+		)
+		return
+	}
+
+	err = handler.mfa.Verify(ctx.Request.Context(), principal.Subject, loginRequest.Code)
+	if errors.Is(err, mfa.ErrLocked) {
+		revokeErr := handler.issuer.RevokeMFAToken(ctx.Request.Context(), principal)
+		if revokeErr != nil {
+			log.Printf("error revoking mfa token: %v", revokeErr)
+		}
+		ctx.AbortWithStatusJSON(
+			http.StatusTooManyRequests,
+			models.NewErrorMessage("too many invalid codes, log in again later"),
+		)
+		return
+	}
+	if errors.Is(err, mfa.ErrInvalidCode) || errors.Is(err, repositories.ErrMFANotEnrolled) {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("invalid or already used code"),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error verifying mfa code: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error logging in"),
+		)
+		return
+	}
+
+	handler.issueTokens(ctx, principal.Subject)
+}
+
+func (handler *AuthHandler) Refresh(ctx *gin.Context) {
+	var refreshRequest schemas.RefreshRequest
+	err := ctx.ShouldBindJSON(&refreshRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	tokens, err := handler.sessions.Refresh(
+		ctx.Request.Context(),
+		refreshRequest.RefreshToken,
+		requestMetadata(ctx),
+	)
+	if errors.Is(err, repositories.ErrRefreshTokenInvalid) ||
+		errors.Is(err, repositories.ErrRefreshTokenReused) {
+		log.Printf("rejected refresh token: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("invalid refresh token"),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error refreshing tokens: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error issuing tokens"),
+		)
+		return
+	}
+
+	writeTokens(ctx, tokens)
+}
+
+func (handler *AuthHandler) SetPassword(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	principal, ok := authorizeUser(ctx, userUri.Id)
+	if !ok {
+		return
+	}
+
+	// Users replace a password by proving the current one, so that a stolen
+	// access token cannot take over the account. Admins may reset it.
+	if !principal.HasRole(auth.RoleAdmin) {
+		_, err = handler.credentialRepository.GetCredentialByUserId(
+			ctx.Request.Context(),
+			userUri.Id,
+		)
+		if err == nil {
+			ctx.AbortWithStatusJSON(
+				http.StatusConflict,
+				models.NewErrorMessage(
+					"user with id %q already has a password, change it with the current password",
+					userUri.Id,
+				),
+			)
+			return
+		}
+		if !errors.Is(err, repositories.ErrCredentialNotFound) {
+			log.Printf("error getting credential: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				models.NewErrorMessage("error setting password"),
+			)
+			return
+		}
+	}
+
+	var passwordRequest schemas.SetPasswordRequest
+	err = ctx.ShouldBindJSON(&passwordRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	handler.updatePassword(ctx, userUri.Id, passwordRequest.Password)
+}
+
+func (handler *AuthHandler) ChangePassword(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	_, ok := authorizeUser(ctx, userUri.Id)
+	if !ok {
+		return
+	}
+
+	var changeRequest schemas.ChangePasswordRequest
+	err = ctx.ShouldBindJSON(&changeRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	credential, err := handler.credentialRepository.GetCredentialByUserId(
+		ctx.Request.Context(),
+		userUri.Id,
+	)
+	if errors.Is(err, repositories.ErrCredentialNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("user with id %q has no password", userUri.Id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting credential: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error changing password"),
+		)
+		return
+	}
+
+	match, _, err := handler.hasher.Verify(changeRequest.CurrentPassword, credential.PasswordHash)
+	if err != nil {
+		log.Printf("error verifying password: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error changing password"),
+		)
+		return
+	}
+	if !match {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("current password is incorrect"),
+		)
+		return
+	}
+
+	handler.updatePassword(ctx, userUri.Id, changeRequest.NewPassword)
+}
+
+func (handler *AuthHandler) updatePassword(ctx *gin.Context, id string, password string) {
+	user, err := handler.userRepository.GetUserById(ctx.Request.Context(), id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no user with id %q exists", id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error setting password"),
+		)
+		return
+	}
+
+	localPart, _, _ := strings.Cut(user.Email, "@")
+	err = handler.policy.Check(password, user.FirstName, user.LastName, localPart)
+	if err != nil {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorMessage(err.Error()))
+		return
+	}
+
+	err = handler.savePassword(ctx, id, password)
+	if err != nil {
+		log.Printf("error saving password: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error setting password"),
+		)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// savePassword stores a new password and signs the user out of all sessions.
+func (handler *AuthHandler) savePassword(ctx *gin.Context, id string, password string) error {
+	hash, err := handler.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	err = handler.credentialRepository.SaveCredential(
+		ctx.Request.Context(),
+		&models.Credential{UserId: id, PasswordHash: hash, PasswordChangedAt: time.Now()},
+	)
+	if err != nil {
+		return err
+	}
+	return handler.sessions.RevokeAll(ctx.Request.Context(), id)
+}
+
+func (handler *AuthHandler) rehashPassword(ctx *gin.Context, id string, password string) error {
+	hash, err := handler.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return handler.credentialRepository.UpdatePasswordHash(ctx.Request.Context(), id, hash)
+}
+
+func (handler *AuthHandler) challengeMFA(ctx *gin.Context, subject string) {
+	tenantId, _ := tenancy.TenantFromContext(ctx.Request.Context())
+	token, expiresIn, err := handler.issuer.IssueMFAToken(subject, tenantId)
+	if err != nil {
+		log.Printf("error issuing mfa token: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error issuing tokens"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, schemas.MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    token,
+		ExpiresIn:   int(expiresIn.Seconds()),
+	})
+}
+
+func (handler *AuthHandler) issueTokens(ctx *gin.Context, subject string) {
+	tokens, err := handler.sessions.Start(ctx.Request.Context(), subject, requestMetadata(ctx))
+	if err != nil {
+		log.Printf("error issuing tokens: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error issuing tokens"),
+		)
+		return
+	}
+
+	writeTokens(ctx, tokens)
+}
+
+func writeTokens(ctx *gin.Context, tokens *sessions.Tokens) {
+	ctx.JSON(http.StatusOK, schemas.TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(tokens.ExpiresIn.Seconds()),
+		SessionId:    tokens.SessionId,
+	})
+}
+
+func requestMetadata(ctx *gin.Context) sessions.Metadata {
+	return sessions.Metadata{
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+	}
+}
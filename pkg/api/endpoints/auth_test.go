@@ -0,0 +1,468 @@
+package endpoints
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func newTestSessionManager(db *gorm.DB, issuer *auth.TokenIssuer) *sessions.Manager {
+	return sessions.NewManager(
+		repositories.NewSQLSessionRepository(db),
+		issuer,
+		auth.NewMemoryDenylist(),
+		time.Hour,
+	)
+}
+
+func expectCreateSession(mock sqlmock.Sqlmock, userId string) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "sessions"`).
+		WithArgs(
+			userId,
+			tenancy.DefaultTenant,
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			nil,
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("def456"))
+	mock.ExpectQuery(`INSERT INTO "refresh_tokens"`).
+		WithArgs("def456", sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+}
+
+func TestAuthHandler_Login(t *testing.T) {
+	params := passwords.Params{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	hasher := passwords.NewHasher(params)
+	hash, err := hasher.Hash("Tr0ub4dor&3-horse")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+
+	testCases := []struct {
+		name         string
+		password     string
+		expectedCode int
+	}{
+		{name: "valid password", password: "Tr0ub4dor&3-horse", expectedCode: 200},
+		{name: "wrong password", password: "Tr0ub4dor&4-horse", expectedCode: 401},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sql mock: %v", err)
+			}
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("error opening db connection: %v", err)
+			}
+
+			router := gin.Default()
+			NewAuthHandler(
+				repositories.NewSQLUserRepository(db),
+				repositories.NewSQLCredentialRepository(db),
+				hasher,
+				passwords.DefaultPolicy,
+				issuer,
+				newTestSessionManager(db, issuer),
+			).Register(router.Group(""))
+
+			mock.ExpectQuery(`SELECT .* FROM "credentials" JOIN users ON users.id = credentials.user_id ` +
+				`WHERE lower\(users.email\) = lower\(\$1\)`).
+				WithArgs("jane.doe@mail.com").
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "password_hash"}).
+					AddRow("abc123", hash))
+			if tc.expectedCode == 200 {
+				expectCreateSession(mock, "abc123")
+			}
+
+			body := `{"email":"jane.doe@mail.com","password":"` + tc.password + `"}`
+			request, err := http.NewRequest("POST", "/auth/login", strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			assert.NoError(t, mock.ExpectationsWereMet())
+			if tc.expectedCode != 200 {
+				return
+			}
+
+			var tokens map[string]any
+			err = json.Unmarshal(recorder.Body.Bytes(), &tokens)
+			if err != nil {
+				t.Fatalf("error unmarshaling response: %v", err)
+			}
+			principal, err := issuer.Authenticate(
+				context.Background(),
+				tokens["access_token"].(string),
+			)
+			assert.NoError(t, err, "Should issue valid access token")
+			assert.Equal(t, "abc123", principal.Subject, "Should issue token for user")
+			assert.Equal(t, "def456", principal.SessionId, "Should issue token for session")
+
+			_, err = issuer.Authenticate(context.Background(), tokens["refresh_token"].(string))
+			assert.Error(t, err, "Should not accept refresh token as access token")
+		})
+	}
+}
+
+func TestAuthHandler_LoginWithMFA(t *testing.T) {
+	hasher := passwords.NewHasher(passwords.Params{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	hash, err := hasher.Hash("Tr0ub4dor&3-horse")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	router := gin.Default()
+	NewAuthHandler(
+		repositories.NewSQLUserRepository(db),
+		repositories.NewSQLCredentialRepository(db),
+		hasher,
+		passwords.DefaultPolicy,
+		issuer,
+		newTestSessionManager(db, issuer),
+	).WithMFA(mfa.NewService(repositories.NewSQLMFARepository(db), "test")).
+		Register(router.Group(""))
+
+	mock.ExpectQuery(`SELECT .* FROM "credentials" JOIN users ON users.id = credentials.user_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "password_hash"}).
+			AddRow("abc123", hash))
+	mock.ExpectQuery(`SELECT \* FROM "mfa_totp" WHERE user_id = \$1`).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "confirmed_at"}).
+			AddRow("abc123", "GEZDGNBVGY3TQOJQ", time.Now()))
+
+	body := `{"email":"jane.doe@mail.com","password":"Tr0ub4dor&3-horse"}`
+	request, err := http.NewRequest("POST", "/auth/login", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 202, recorder.Code, "Should require second factor")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	var challenge map[string]any
+	err = json.Unmarshal(recorder.Body.Bytes(), &challenge)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+	assert.NotContains(t, challenge, "access_token", "Should not issue tokens")
+
+	principal, err := issuer.ParseMFAToken(context.Background(), challenge["mfa_token"].(string))
+	assert.NoError(t, err, "Should issue valid mfa token")
+	assert.Equal(t, "abc123", principal.Subject, "Should issue mfa token for user")
+
+	_, err = issuer.Authenticate(context.Background(), challenge["mfa_token"].(string))
+	assert.Error(t, err, "Should not accept mfa token as access token")
+}
+
+func TestAuthHandler_LoginAdminWithoutMFA(t *testing.T) {
+	hasher := passwords.NewHasher(passwords.Params{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	})
+	hash, err := hasher.Hash("Tr0ub4dor&3-horse")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	router := gin.Default()
+	NewAuthHandler(
+		repositories.NewSQLUserRepository(db),
+		repositories.NewSQLCredentialRepository(db),
+		hasher,
+		passwords.DefaultPolicy,
+		issuer,
+		newTestSessionManager(db, issuer).WithAdmins([]string{"abc123"}),
+	).WithMFA(mfa.NewService(repositories.NewSQLMFARepository(db), "test")).
+		Register(router.Group(""))
+
+	mock.ExpectQuery(`SELECT .* FROM "credentials" JOIN users ON users.id = credentials.user_id`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "password_hash"}).
+			AddRow("abc123", hash))
+	mock.ExpectQuery(`SELECT \* FROM "mfa_totp" WHERE user_id = \$1`).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "confirmed_at"}))
+
+	body := `{"email":"jane.doe@mail.com","password":"Tr0ub4dor&3-horse"}`
+	request, err := http.NewRequest("POST", "/auth/login", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	assert.Equal(t, 403, recorder.Code, "Should require admins to enable mfa")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should not start a session")
+}
+
+func TestAuthHandler_LoginMFALockout(t *testing.T) {
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute).
+		WithDenylist(auth.NewMemoryDenylist())
+
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	router := gin.Default()
+	NewAuthHandler(
+		repositories.NewSQLUserRepository(db),
+		repositories.NewSQLCredentialRepository(db),
+		passwords.NewHasher(passwords.DefaultParams),
+		passwords.DefaultPolicy,
+		issuer,
+		newTestSessionManager(db, issuer),
+	).WithMFA(mfa.NewService(repositories.NewSQLMFARepository(db), "test")).
+		Register(router.Group(""))
+
+	factorColumns := []string{"user_id", "secret", "confirmed_at", "failed_attempts"}
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows(factorColumns).
+			AddRow("abc123", "GEZDGNBVGY3TQOJQ", time.Now(), 4))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_recovery_codes" SET "used_at"=$1`)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows(factorColumns).
+			AddRow("abc123", "GEZDGNBVGY3TQOJQ", time.Now(), 4))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "failed_attempts"=$1,"locked_until"=$2`)).
+		WithArgs(0, sqlmock.AnyArg(), sqlmock.AnyArg(), "abc123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	mfaToken, _, err := issuer.IssueMFAToken("abc123", "")
+	if err != nil {
+		t.Fatalf("error issuing mfa token: %v", err)
+	}
+	body := fmt.Sprintf(`{"mfa_token":%q,"code":"invalid"}`, mfaToken)
+	expectedCodes := []int{429, 401}
+	for i, expectedCode := range expectedCodes {
+		request, err := http.NewRequest("POST", "/auth/login/mfa", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		assert.Equal(t, expectedCode, recorder.Code, fmt.Sprintf("Attempt %d", i))
+	}
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should not verify codes of revoked token")
+}
+
+func expectSetPassword(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "first_name", "last_name", "email"}).
+				AddRow("abc123", "Jane", "Doe", "jane.doe@mail.com"),
+		)
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "credentials"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`UPDATE "sessions" SET "revoked_at"=$1`)).
+		WithArgs(sqlmock.AnyArg(), "abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+}
+
+func TestAuthHandler_PasswordAuthorization(t *testing.T) {
+	credentialQuery := regexp.QuoteMeta(`SELECT * FROM "credentials" WHERE user_id = $1`)
+	testCases := []struct {
+		name         string
+		principal    *auth.Principal
+		method       string
+		path         string
+		body         string
+		expect       func(mock sqlmock.Sqlmock)
+		expectedCode int
+	}{
+		{
+			name:         "set password anonymously",
+			method:       "PUT",
+			path:         "/users/abc123/password",
+			body:         `{"password":"Tr0ub4dor&3-horse"}`,
+			expectedCode: 401,
+		},
+		{
+			name:         "set password of other user",
+			principal:    &auth.Principal{Subject: "abc124"},
+			method:       "PUT",
+			path:         "/users/abc123/password",
+			body:         `{"password":"Tr0ub4dor&3-horse"}`,
+			expectedCode: 403,
+		},
+		{
+			name:         "change password of other user",
+			principal:    &auth.Principal{Subject: "abc124"},
+			method:       "POST",
+			path:         "/users/abc123/password/change",
+			body:         `{"current_password":"Tr0ub4dor&3-horse","new_password":"correct-Horse-battery-1"}`,
+			expectedCode: 403,
+		},
+		{
+			name:      "set existing password",
+			principal: &auth.Principal{Subject: "abc123"},
+			method:    "PUT",
+			path:      "/users/abc123/password",
+			body:      `{"password":"Tr0ub4dor&3-horse"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(credentialQuery).
+					WithArgs("abc123").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("abc123"))
+			},
+			expectedCode: 409,
+		},
+		{
+			name:      "set first password",
+			principal: &auth.Principal{Subject: "abc123"},
+			method:    "PUT",
+			path:      "/users/abc123/password",
+			body:      `{"password":"Tr0ub4dor&3-horse"}`,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(credentialQuery).
+					WithArgs("abc123").
+					WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+				expectSetPassword(mock)
+			},
+			expectedCode: 204,
+		},
+		{
+			name: "reset password as admin",
+			principal: &auth.Principal{
+				Subject: "admin",
+				Roles:   []string{auth.RoleAdmin},
+			},
+			method:       "PUT",
+			path:         "/users/abc123/password",
+			body:         `{"password":"Tr0ub4dor&3-horse"}`,
+			expect:       expectSetPassword,
+			expectedCode: 204,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sql mock: %v", err)
+			}
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("error opening db connection: %v", err)
+			}
+			if tc.expect != nil {
+				tc.expect(mock)
+			}
+
+			issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)
+			router := gin.Default()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			NewAuthHandler(
+				repositories.NewSQLUserRepository(db),
+				repositories.NewSQLCredentialRepository(db),
+				passwords.NewHasher(passwords.DefaultParams),
+				passwords.DefaultPolicy,
+				issuer,
+				newTestSessionManager(db, issuer),
+			).Register(router.Group(""))
+
+			request, err := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
@@ -0,0 +1,548 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const groupsTag = "groups"
+
+type GroupsHandler struct {
+	groupRepository repositories.GroupRepository
+}
+
+func NewGroupsHandler(groupRepository repositories.GroupRepository) *GroupsHandler {
+	return &GroupsHandler{
+		groupRepository: groupRepository,
+	}
+}
+
+func (handler *GroupsHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *GroupsHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/groups",
+			Handler:     handler.CreateGroup,
+			OperationId: "createGroup",
+			Summary:     "Create a group",
+			Tags:        []string{groupsTag},
+			Body:        schemas.GroupRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             schemas.GroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/groups",
+			Handler:     handler.GetAllGroups,
+			OperationId: "listGroups",
+			Summary:     "List groups",
+			Tags:        []string{groupsTag},
+			Query:       schemas.GroupListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.GroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/groups/:id",
+			Handler:     handler.GetGroup,
+			OperationId: "getGroup",
+			Summary:     "Get a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.GroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/groups/:id",
+			Handler:     handler.UpdateGroup,
+			OperationId: "updateGroup",
+			Summary:     "Update a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupURI{},
+			Body:        schemas.GroupRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.GroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/groups/:id",
+			Handler:     handler.DeleteGroup,
+			OperationId: "deleteGroup",
+			Summary:     "Delete a group with its memberships",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/groups/:id/members",
+			Handler:     handler.GetMembers,
+			OperationId: "listGroupMembers",
+			Summary:     "List direct members of a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.UserResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/groups/:id/members/:userId",
+			Handler:     handler.AddMember,
+			OperationId: "addGroupMember",
+			Summary:     "Add a user to a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupMemberURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/groups/:id/members/:userId",
+			Handler:     handler.RemoveMember,
+			OperationId: "removeGroupMember",
+			Summary:     "Remove a user from a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupMemberURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/groups/:id/subgroups",
+			Handler:     handler.GetSubgroups,
+			OperationId: "listSubgroups",
+			Summary:     "List subgroups of a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.GroupURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.GroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/groups/:id/subgroups/:groupId",
+			Handler:     handler.AddSubgroup,
+			OperationId: "addSubgroup",
+			Summary:     "Nest a group in a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.SubgroupURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/groups/:id/subgroups/:groupId",
+			Handler:     handler.RemoveSubgroup,
+			OperationId: "removeSubgroup",
+			Summary:     "Remove a nested group from a group",
+			Tags:        []string{groupsTag},
+			URI:         schemas.SubgroupURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/users/:id/groups",
+			Handler:     handler.GetUserGroups,
+			OperationId: "listUserGroups",
+			Summary:     "List groups of a user including inherited groups",
+			Tags:        []string{groupsTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.UserGroupResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *GroupsHandler) CreateGroup(ctx *gin.Context) {
+	var groupRequest schemas.GroupRequest
+	err := ctx.ShouldBindJSON(&groupRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	group := groupRequestToGroupModel(groupRequest)
+	err = handler.groupRepository.CreateGroup(ctx.Request.Context(), group)
+	if err != nil {
+		log.Printf("error creating group: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error creating group"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, groupModelToGroupResponse(group))
+}
+
+func (handler *GroupsHandler) GetAllGroups(ctx *gin.Context) {
+	var listQuery schemas.GroupListQuery
+	err := ctx.ShouldBindQuery(&listQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting limit and offset"),
+		)
+		return
+	}
+
+	options := repositories.ListOptions{
+		Limit:  listQuery.Limit,
+		Offset: listQuery.Offset,
+	}
+	groups, err := handler.groupRepository.GetAllGroups(ctx.Request.Context(), options)
+	if err != nil {
+		log.Printf("error getting groups: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving groups"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groupModelsToGroupResponses(groups))
+}
+
+func (handler *GroupsHandler) GetGroup(ctx *gin.Context) {
+	groupUri, ok := bindGroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	group, err := handler.groupRepository.GetGroupById(ctx.Request.Context(), groupUri.Id)
+	if !handleGroupError(ctx, err, "retrieving group") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groupModelToGroupResponse(group))
+}
+
+func (handler *GroupsHandler) UpdateGroup(ctx *gin.Context) {
+	groupUri, ok := bindGroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	var groupRequest schemas.GroupRequest
+	err := ctx.ShouldBindJSON(&groupRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	group, err := handler.groupRepository.UpdateGroupById(
+		ctx.Request.Context(),
+		groupUri.Id,
+		groupRequestToGroupModel(groupRequest),
+	)
+	if !handleGroupError(ctx, err, "updating group") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groupModelToGroupResponse(group))
+}
+
+func (handler *GroupsHandler) DeleteGroup(ctx *gin.Context) {
+	groupUri, ok := bindGroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.groupRepository.DeleteGroupById(ctx.Request.Context(), groupUri.Id)
+	if !handleGroupError(ctx, err, "deleting group") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *GroupsHandler) GetMembers(ctx *gin.Context) {
+	groupUri, ok := bindGroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	users, err := handler.groupRepository.GetMembers(ctx.Request.Context(), groupUri.Id)
+	if !handleGroupError(ctx, err, "retrieving group members") {
+		return
+	}
+
+	userResponseList := make([]schemas.UserResponse, len(users))
+	for i, user := range users {
+		userResponseList[i] = userModelToUserResponse(user)
+	}
+	ctx.JSON(http.StatusOK, userResponseList)
+}
+
+func (handler *GroupsHandler) AddMember(ctx *gin.Context) {
+	memberUri, ok := bindGroupMemberURI(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.groupRepository.AddMember(
+		ctx.Request.Context(),
+		memberUri.Id,
+		memberUri.UserId,
+	)
+	if !handleGroupError(ctx, err, "adding group member") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *GroupsHandler) RemoveMember(ctx *gin.Context) {
+	memberUri, ok := bindGroupMemberURI(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.groupRepository.RemoveMember(
+		ctx.Request.Context(),
+		memberUri.Id,
+		memberUri.UserId,
+	)
+	if !handleGroupError(ctx, err, "removing group member") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *GroupsHandler) GetSubgroups(ctx *gin.Context) {
+	groupUri, ok := bindGroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	groups, err := handler.groupRepository.GetSubgroups(ctx.Request.Context(), groupUri.Id)
+	if !handleGroupError(ctx, err, "retrieving subgroups") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, groupModelsToGroupResponses(groups))
+}
+
+func (handler *GroupsHandler) AddSubgroup(ctx *gin.Context) {
+	subgroupUri, ok := bindSubgroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.groupRepository.AddSubgroup(
+		ctx.Request.Context(),
+		subgroupUri.Id,
+		subgroupUri.GroupId,
+	)
+	if !handleGroupError(ctx, err, "adding subgroup") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *GroupsHandler) RemoveSubgroup(ctx *gin.Context) {
+	subgroupUri, ok := bindSubgroupURI(ctx)
+	if !ok {
+		return
+	}
+
+	err := handler.groupRepository.RemoveSubgroup(
+		ctx.Request.Context(),
+		subgroupUri.Id,
+		subgroupUri.GroupId,
+	)
+	if !handleGroupError(ctx, err, "removing subgroup") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *GroupsHandler) GetUserGroups(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	userGroups, err := handler.groupRepository.GetUserGroups(ctx.Request.Context(), userUri.Id)
+	if !handleGroupError(ctx, err, "retrieving groups of user") {
+		return
+	}
+
+	response := make([]schemas.UserGroupResponse, len(userGroups))
+	for i, userGroup := range userGroups {
+		response[i] = schemas.UserGroupResponse{
+			GroupResponse: groupModelToGroupResponse(&userGroup.Group),
+			Direct:        userGroup.Direct,
+		}
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func bindGroupURI(ctx *gin.Context) (schemas.GroupURI, bool) {
+	var groupUri schemas.GroupURI
+	err := ctx.BindUri(&groupUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return groupUri, false
+	}
+	return groupUri, true
+}
+
+func bindGroupMemberURI(ctx *gin.Context) (schemas.GroupMemberURI, bool) {
+	var memberUri schemas.GroupMemberURI
+	err := ctx.BindUri(&memberUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id and userId"),
+		)
+		return memberUri, false
+	}
+	return memberUri, true
+}
+
+func bindSubgroupURI(ctx *gin.Context) (schemas.SubgroupURI, bool) {
+	var subgroupUri schemas.SubgroupURI
+	err := ctx.BindUri(&subgroupUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id and groupId"),
+		)
+		return subgroupUri, false
+	}
+	return subgroupUri, true
+}
+
+func handleGroupError(ctx *gin.Context, err error, action string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repositories.ErrGroupNotFound),
+		errors.Is(err, repositories.ErrUserNotFound),
+		errors.Is(err, repositories.ErrNotGroupMember),
+		errors.Is(err, repositories.ErrNotSubgroup):
+		ctx.AbortWithStatusJSON(http.StatusNotFound, models.NewErrorMessage("%s", err))
+	case errors.Is(err, repositories.ErrGroupNestingLoop):
+		ctx.AbortWithStatusJSON(http.StatusConflict, models.NewErrorMessage("%s", err))
+	default:
+		log.Printf("error %s: %v", action, err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error %s", action),
+		)
+	}
+	return false
+}
+
+func groupRequestToGroupModel(groupRequest schemas.GroupRequest) *models.Group {
+	return &models.Group{
+		Name:        groupRequest.Name,
+		Description: groupRequest.Description,
+	}
+}
+
+func groupModelToGroupResponse(group *models.Group) schemas.GroupResponse {
+	return schemas.GroupResponse{
+		Id:          group.Id,
+		Name:        group.Name,
+		Description: group.Description,
+		CreatedAt:   group.CreatedAt,
+		UpdatedAt:   group.UpdatedAt,
+	}
+}
+
+func groupModelsToGroupResponses(groups []*models.Group) []schemas.GroupResponse {
+	groupResponseList := make([]schemas.GroupResponse, len(groups))
+	for i, group := range groups {
+		groupResponseList[i] = groupModelToGroupResponse(group)
+	}
+	return groupResponseList
+}
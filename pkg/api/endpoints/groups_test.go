@@ -0,0 +1,190 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+var groupColumns = []string{"id", "name", "description", "created_at", "updated_at"}
+
+func expectGetGroup(mock sqlmock.Sqlmock, id string) {
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "groups" WHERE id = $1`)).
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows(groupColumns).AddRow(id, id, "", nil, nil))
+}
+
+func TestGroupsHandler(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		path         string
+		expectations func(mock sqlmock.Sqlmock)
+		expectedCode int
+	}{
+		{
+			name:   "add member",
+			method: "POST",
+			path:   "/groups/admins/members/abc123",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectGetGroup(mock, "admins")
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "users" WHERE id = $1`)).
+					WithArgs("abc123").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("abc123"))
+				mock.ExpectExec(`INSERT INTO "group_members" .* ON CONFLICT DO NOTHING`).
+					WithArgs("admins", "abc123", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedCode: 204,
+		},
+		{
+			name:   "add unknown member",
+			method: "POST",
+			path:   "/groups/admins/members/abc123",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				expectGetGroup(mock, "admins")
+				mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "users" WHERE id = $1`)).
+					WithArgs("abc123").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}))
+				mock.ExpectRollback()
+			},
+			expectedCode: 404,
+		},
+		{
+			name:   "nest group",
+			method: "POST",
+			path:   "/groups/staff/subgroups/admins",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				expectGetGroup(mock, "staff")
+				expectGetGroup(mock, "admins")
+				mock.ExpectQuery(`WITH RECURSIVE descendants`).
+					WithArgs("admins", "staff").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "group_nestings"`)).
+					WithArgs("staff", "admins", sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedCode: 204,
+		},
+		{
+			name:   "nest group in its descendant",
+			method: "POST",
+			path:   "/groups/admins/subgroups/staff",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_advisory_xact_lock($1)`)).
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				expectGetGroup(mock, "admins")
+				expectGetGroup(mock, "staff")
+				mock.ExpectQuery(`WITH RECURSIVE descendants`).
+					WithArgs("staff", "admins").
+					WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+				mock.ExpectRollback()
+			},
+			expectedCode: 409,
+		},
+		{
+			name:   "remove missing member",
+			method: "DELETE",
+			path:   "/groups/admins/members/abc123",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "group_members"`)).
+					WithArgs("admins", "abc123").
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectCommit()
+			},
+			expectedCode: 404,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sql mock: %v", err)
+			}
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("error opening db connection: %v", err)
+			}
+
+			router := gin.Default()
+			NewGroupsHandler(repositories.NewSQLGroupRepository(db)).Register(router.Group(""))
+			tc.expectations(mock)
+
+			request, err := http.NewRequest(tc.method, tc.path, nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
+
+func TestGroupsHandler_GetUserGroups(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	router := gin.Default()
+	NewGroupsHandler(repositories.NewSQLGroupRepository(db)).Register(router.Group(""))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT "id" FROM "users" WHERE id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("abc123"))
+	mock.ExpectQuery(`WITH RECURSIVE memberships`).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows(append(groupColumns, "direct")).
+			AddRow("admins", "Admins", "", nil, nil, true).
+			AddRow("staff", "Staff", "", nil, nil, false))
+	mock.ExpectCommit()
+
+	request, err := http.NewRequest("GET", "/users/abc123/groups", nil)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var response []schemas.UserGroupResponse
+	err = json.Unmarshal(recorder.Body.Bytes(), &response)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+
+	assert.Equal(t, 200, recorder.Code, "Should match response code")
+	assert.Len(t, response, 2, "Should include inherited groups")
+	assert.True(t, response[0].Direct, "Should mark direct membership")
+	assert.Equal(t, "staff", response[1].Id, "Should include parent group")
+	assert.False(t, response[1].Direct, "Should mark inherited membership")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+}
@@ -0,0 +1,296 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const contentTypePNG = "image/png"
+
+type MFAHandler struct {
+	userRepository repositories.UserRepository
+	service        *mfa.Service
+}
+
+func NewMFAHandler(userRepository repositories.UserRepository, service *mfa.Service) *MFAHandler {
+	return &MFAHandler{
+		userRepository: userRepository,
+		service:        service,
+	}
+}
+
+func (handler *MFAHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *MFAHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/users/:id/mfa/totp",
+			Handler:     handler.EnrollTOTP,
+			OperationId: "enrollTOTP",
+			Summary:     "Start TOTP enrollment of a user",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusCreated:             schemas.TOTPEnrollmentResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/users/:id/mfa/totp/qr.png",
+			Handler:     handler.GetTOTPQRCode,
+			OperationId: "getTOTPQRCode",
+			Summary:     "Get the QR code of a pending TOTP enrollment",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			ContentType: contentTypePNG,
+			Responses: map[int]any{
+				http.StatusOK:                  []byte{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/users/:id/mfa/totp/confirm",
+			Handler:     handler.ConfirmTOTP,
+			OperationId: "confirmTOTP",
+			Summary:     "Enable TOTP with a first code and get recovery codes",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Body:        schemas.TOTPConfirmRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.RecoveryCodesResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/users/:id/mfa",
+			Handler:     handler.ResetMFA,
+			OperationId: "resetMFA",
+			Summary:     "Reset MFA of a user, requires the admin role",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *MFAHandler) EnrollTOTP(ctx *gin.Context) {
+	user, ok := handler.bindUser(ctx)
+	if !ok {
+		return
+	}
+
+	enrollment, err := handler.service.Enroll(ctx.Request.Context(), user)
+	if errors.Is(err, mfa.ErrAlreadyEnrolled) {
+		ctx.AbortWithStatusJSON(http.StatusConflict, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error enrolling totp: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error enrolling totp"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, schemas.TOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+	})
+}
+
+func (handler *MFAHandler) GetTOTPQRCode(ctx *gin.Context) {
+	user, ok := handler.bindUser(ctx)
+	if !ok {
+		return
+	}
+
+	png, err := handler.service.QRCode(ctx.Request.Context(), user)
+	if errors.Is(err, repositories.ErrMFANotEnrolled) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no totp enrollment is pending"),
+		)
+		return
+	}
+	if errors.Is(err, mfa.ErrAlreadyEnrolled) {
+		ctx.AbortWithStatusJSON(http.StatusConflict, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error generating qr code: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error generating qr code"),
+		)
+		return
+	}
+
+	ctx.Header("Cache-Control", "no-store")
+	ctx.Data(http.StatusOK, contentTypePNG, png)
+}
+
+func (handler *MFAHandler) ConfirmTOTP(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	_, ok := authorizeUser(ctx, userUri.Id)
+	if !ok {
+		return
+	}
+
+	var confirmRequest schemas.TOTPConfirmRequest
+	err = ctx.ShouldBindJSON(&confirmRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	codes, err := handler.service.Confirm(ctx.Request.Context(), userUri.Id, confirmRequest.Code)
+	if errors.Is(err, repositories.ErrMFANotEnrolled) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no totp enrollment is pending"),
+		)
+		return
+	}
+	if errors.Is(err, mfa.ErrAlreadyEnrolled) {
+		ctx.AbortWithStatusJSON(http.StatusConflict, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if errors.Is(err, mfa.ErrInvalidCode) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error confirming totp: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error confirming totp"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schemas.RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+func (handler *MFAHandler) ResetMFA(ctx *gin.Context) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok || !principal.HasRole(auth.RoleAdmin) {
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			models.NewErrorMessage("resetting mfa requires the %s role", auth.RoleAdmin),
+		)
+		return
+	}
+
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	err = handler.service.Reset(ctx.Request.Context(), userUri.Id)
+	if err != nil {
+		log.Printf("error resetting mfa: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error resetting mfa"),
+		)
+		return
+	}
+
+	log.Printf("mfa of user %s reset by %s", userUri.Id, principal.Subject)
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *MFAHandler) bindUser(ctx *gin.Context) (*models.User, bool) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return nil, false
+	}
+
+	_, ok := authorizeUser(ctx, userUri.Id)
+	if !ok {
+		return nil, false
+	}
+
+	user, err := handler.userRepository.GetUserById(ctx.Request.Context(), userUri.Id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no user with id %q exists", userUri.Id),
+		)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving user"),
+		)
+		return nil, false
+	}
+	return user, true
+}
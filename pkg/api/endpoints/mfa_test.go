@@ -0,0 +1,92 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mfa"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestMFAHandler_Authorization(t *testing.T) {
+	handler := NewMFAHandler(
+		repositories.NewMemoryUserRepository(),
+		mfa.NewService(repositories.NewSQLMFARepository(nil), "test"),
+	)
+
+	testCases := []struct {
+		principal    *auth.Principal
+		method       string
+		path         string
+		body         string
+		expectedCode int
+		reason       string
+	}{
+		{
+			method:       "POST",
+			path:         "/users/abc123/mfa/totp",
+			expectedCode: 401,
+			reason:       "Should require authentication to enroll",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc124"},
+			method:       "POST",
+			path:         "/users/abc123/mfa/totp",
+			expectedCode: 403,
+			reason:       "Should not enroll other users",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc124"},
+			method:       "GET",
+			path:         "/users/abc123/mfa/totp/qr.png",
+			expectedCode: 403,
+			reason:       "Should not show qr code of other users",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc124"},
+			method:       "POST",
+			path:         "/users/abc123/mfa/totp/confirm",
+			body:         `{"code":"123456"}`,
+			expectedCode: 403,
+			reason:       "Should not confirm enrollment of other users",
+		},
+		{
+			principal:    &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}},
+			method:       "POST",
+			path:         "/users/abc123/mfa/totp",
+			expectedCode: 404,
+			reason:       "Should let admins enroll users",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			router := gin.Default()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			handler.Register(router.Group(""))
+
+			request, err := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const (
+	outboxTag          = "outbox"
+	defaultOutboxLimit = 50
+)
+
+type OutboxHandler struct {
+	outboxRepository repositories.OutboxRepository
+}
+
+func NewOutboxHandler(outboxRepository repositories.OutboxRepository) *OutboxHandler {
+	return &OutboxHandler{
+		outboxRepository: outboxRepository,
+	}
+}
+
+// Register serves the routes to admins.
+func (handler *OutboxHandler) Register(routerGroup *gin.RouterGroup) {
+	routerGroup.Use(requireAdmin)
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *OutboxHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/dead",
+			Handler:     handler.GetDeadMessages,
+			OperationId: "listDeadOutboxMessages",
+			Summary:     "List events the relay gave up on",
+			Tags:        []string{outboxTag},
+			Query:       schemas.OutboxPageQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.OutboxMessageResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/dead/:id/retry",
+			Handler:     handler.RetryDeadMessage,
+			OperationId: "retryDeadOutboxMessage",
+			Summary:     "Schedule a dead event to be relayed again",
+			Tags:        []string{outboxTag},
+			URI:         schemas.OutboxMessageURI{},
+			Responses: map[int]any{
+				http.StatusAccepted:            schemas.OutboxMessageResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *OutboxHandler) GetDeadMessages(ctx *gin.Context) {
+	var pageQuery schemas.OutboxPageQuery
+	err := ctx.ShouldBindQuery(&pageQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting limit and offset"),
+		)
+		return
+	}
+
+	options := repositories.ListOptions{
+		Limit:  pageQuery.Limit,
+		Offset: pageQuery.Offset,
+	}
+	if options.Limit == 0 {
+		options.Limit = defaultOutboxLimit
+	}
+	messages, err := handler.outboxRepository.GetDeadMessages(ctx.Request.Context(), options)
+	if err != nil {
+		log.Printf("error getting dead outbox messages: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving dead outbox messages"),
+		)
+		return
+	}
+
+	messageResponseList := make([]schemas.OutboxMessageResponse, len(messages))
+	for i, message := range messages {
+		messageResponseList[i] = outboxMessageModelToOutboxMessageResponse(message)
+	}
+
+	ctx.JSON(http.StatusOK, messageResponseList)
+}
+
+func (handler *OutboxHandler) RetryDeadMessage(ctx *gin.Context) {
+	var messageUri schemas.OutboxMessageURI
+	err := ctx.BindUri(&messageUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	message, err := handler.outboxRepository.RetryDeadMessage(
+		ctx.Request.Context(),
+		messageUri.Id,
+	)
+	if errors.Is(err, repositories.ErrOutboxMessageNotFound) {
+		log.Printf("dead outbox message not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no dead outbox message with id %d exists", messageUri.Id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error retrying outbox message: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrying outbox message"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, outboxMessageModelToOutboxMessageResponse(message))
+}
+
+func outboxMessageModelToOutboxMessageResponse(
+	message *models.OutboxMessage,
+) schemas.OutboxMessageResponse {
+	return schemas.OutboxMessageResponse{
+		Id:            message.Id,
+		AggregateType: message.AggregateType,
+		AggregateId:   message.AggregateId,
+		EventType:     message.EventType,
+		Attempts:      message.Attempts,
+		LastError:     message.LastError,
+		CreatedAt:     message.CreatedAt,
+		DeadAt:        message.DeadAt,
+	}
+}
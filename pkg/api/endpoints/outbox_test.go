@@ -0,0 +1,128 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestOutboxHandler(t *testing.T) {
+	admin := &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}}
+	deadQuery := regexp.QuoteMeta(`SELECT * FROM "outbox" WHERE id = $1 AND dead_at IS NOT NULL`)
+	columns := []string{"id", "aggregate_type", "aggregate_id", "event_type", "attempts", "dead_at"}
+
+	testCases := []struct {
+		principal    *auth.Principal
+		method       string
+		path         string
+		expect       func(mock sqlmock.Sqlmock)
+		expectedCode int
+		reason       string
+	}{
+		{
+			method:       "GET",
+			path:         "/outbox/dead",
+			expectedCode: 401,
+			reason:       "Should require authentication",
+		},
+		{
+			principal: admin,
+			method:    "GET",
+			path:      "/outbox/dead",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(regexp.QuoteMeta(
+					`SELECT * FROM "outbox" WHERE dead_at IS NOT NULL ORDER BY id LIMIT 50`,
+				)).WillReturnRows(
+					sqlmock.NewRows(columns).
+						AddRow(1, "user", "abc123", "user.created", 10, time.Now()),
+				)
+			},
+			expectedCode: 200,
+			reason:       "Should list dead messages",
+		},
+		{
+			principal: admin,
+			method:    "POST",
+			path:      "/outbox/dead/1/retry",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(deadQuery).
+					WithArgs(1).
+					WillReturnRows(
+						sqlmock.NewRows(columns).
+							AddRow(1, "user", "abc123", "user.created", 10, time.Now()),
+					)
+				mock.ExpectExec(regexp.QuoteMeta(
+					`UPDATE "outbox" SET "dead_at"=$1,"attempts"=$2,"last_error"=$3 WHERE "id" = $4`,
+				)).
+					WithArgs(nil, 0, "", 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expectedCode: 202,
+			reason:       "Should hand dead message back to relay",
+		},
+		{
+			principal: admin,
+			method:    "POST",
+			path:      "/outbox/dead/2/retry",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectQuery(deadQuery).
+					WithArgs(2).
+					WillReturnRows(sqlmock.NewRows(columns))
+				mock.ExpectRollback()
+			},
+			expectedCode: 404,
+			reason:       "Should not retry messages that are not dead",
+		},
+	}
+
+	for i, tc := range testCases {
+		conn, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating sql mock: %v", err)
+		}
+		db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("error opening db connection: %v", err)
+		}
+		if tc.expect != nil {
+			tc.expect(mock)
+		}
+
+		router := gin.Default()
+		if tc.principal != nil {
+			router.Use(func(ctx *gin.Context) {
+				ctx.Request = ctx.Request.WithContext(
+					auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+				)
+			})
+		}
+		NewOutboxHandler(repositories.NewSQLOutboxRepository(db)).
+			Register(router.Group("/outbox"))
+
+		request, err := http.NewRequest(tc.method, tc.path, nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+
+		reason := fmt.Sprintf("Test %d: %s", i, tc.reason)
+		assert.Equal(t, tc.expectedCode, recorder.Code, reason)
+		assert.NoError(t, mock.ExpectationsWereMet(), reason)
+	}
+}
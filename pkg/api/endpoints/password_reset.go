@@ -0,0 +1,192 @@
+package endpoints
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/passwordreset"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const passwordResetTimeout = 30 * time.Second
+
+type PasswordResetHandler struct {
+	service      *passwordreset.Service
+	emailLimiter ratelimit.Limiter
+	ipLimiter    ratelimit.Limiter
+}
+
+func NewPasswordResetHandler(
+	service *passwordreset.Service,
+	emailLimiter ratelimit.Limiter,
+	ipLimiter ratelimit.Limiter,
+) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		service:      service,
+		emailLimiter: emailLimiter,
+		ipLimiter:    ipLimiter,
+	}
+}
+
+func (handler *PasswordResetHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *PasswordResetHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/auth/password-reset",
+			Handler:     handler.RequestPasswordReset,
+			OperationId: "requestPasswordReset",
+			Summary:     "Email a password reset link",
+			Tags:        []string{authTag},
+			Body:        schemas.PasswordResetRequest{},
+			Responses: map[int]any{
+				http.StatusAccepted:            nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusTooManyRequests:     models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/auth/password-reset/confirm",
+			Handler:     handler.ConfirmPasswordReset,
+			OperationId: "confirmPasswordReset",
+			Summary:     "Choose a new password with a reset token",
+			Tags:        []string{authTag},
+			Body:        schemas.PasswordResetConfirmRequest{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusTooManyRequests:     models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *PasswordResetHandler) RequestPasswordReset(ctx *gin.Context) {
+	var resetRequest schemas.PasswordResetRequest
+	err := ctx.ShouldBindJSON(&resetRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	if !handler.allowIP(ctx) {
+		return
+	}
+
+	// Requests over the per email limit are dropped silently, as telling the
+	// caller would reveal that the email is being reset.
+	email := strings.ToLower(strings.TrimSpace(resetRequest.Email))
+	result, err := handler.emailLimiter.Allow(ctx.Request.Context(), email)
+	if err != nil {
+		log.Printf("error checking rate limit: %v", err)
+	}
+	if err == nil && result.Allowed {
+		// The link is sent in the background so the response time does not
+		// depend on whether the email exists. The email is looked up in the
+		// tenant of the request.
+		backgroundCtx := context.Background()
+		if tenantId, ok := tenancy.TenantFromContext(ctx.Request.Context()); ok {
+			backgroundCtx = tenancy.WithTenant(backgroundCtx, tenantId)
+		}
+		go func() {
+			requestCtx, cancel := context.WithTimeout(backgroundCtx, passwordResetTimeout)
+			defer cancel()
+			if err := handler.service.Request(requestCtx, email); err != nil {
+				log.Printf("error requesting password reset: %v", err)
+			}
+		}()
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (handler *PasswordResetHandler) ConfirmPasswordReset(ctx *gin.Context) {
+	var confirmRequest schemas.PasswordResetConfirmRequest
+	err := ctx.ShouldBindJSON(&confirmRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	if !handler.allowIP(ctx) {
+		return
+	}
+
+	err = handler.service.Confirm(
+		ctx.Request.Context(),
+		confirmRequest.Token,
+		confirmRequest.Password,
+	)
+	if errors.Is(err, repositories.ErrPasswordResetTokenInvalid) ||
+		errors.Is(err, passwords.ErrWeakPassword) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error resetting password: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error resetting password"),
+		)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// allowIP limits requests by client IP, which is only taken from forwarding
+// headers set by trusted proxies.
+func (handler *PasswordResetHandler) allowIP(ctx *gin.Context) bool {
+	return allowRequest(ctx, handler.ipLimiter, ctx.ClientIP())
+}
+
+// allowRequest answers with 429 Too Many Requests when key is over the limit.
+// Requests are let through when the limit cannot be checked.
+func allowRequest(ctx *gin.Context, limiter ratelimit.Limiter, key string) bool {
+	result, err := limiter.Allow(ctx.Request.Context(), key)
+	if err != nil {
+		log.Printf("error checking rate limit: %v", err)
+		return true
+	}
+	if result.Allowed {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(result.ResetAfter.Seconds()))
+	ctx.Header("Retry-After", strconv.Itoa(retryAfter))
+	ctx.AbortWithStatusJSON(
+		http.StatusTooManyRequests,
+		models.NewErrorMessage("too many requests, retry in %d seconds", retryAfter),
+	)
+	return false
+}
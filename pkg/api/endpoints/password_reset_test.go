@@ -0,0 +1,86 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/passwordreset"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func TestPasswordResetHandler_RequestPasswordReset(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	mailer := mail.NewMemoryMailer()
+	service := passwordreset.NewService(
+		repositories.NewSQLUserRepository(db),
+		repositories.NewSQLCredentialRepository(db),
+		repositories.NewSQLPasswordResetRepository(db),
+		newTestSessionManager(db, auth.NewTokenIssuer([]byte("secret"), "test", time.Minute)),
+		passwords.NewHasher(passwords.DefaultParams),
+		passwords.DefaultPolicy,
+		mailer,
+		"https://example.com/reset-password",
+		time.Hour,
+	)
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(tenancy.WithTenant(ctx.Request.Context(), "acme"))
+	})
+	NewPasswordResetHandler(
+		service,
+		ratelimit.NewMemoryLimiter(5, time.Hour),
+		ratelimit.NewMemoryLimiter(1, time.Hour),
+	).Register(router.Group(""))
+
+	mock.ExpectQuery(`SELECT .* FROM "credentials" JOIN users`).
+		WithArgs("nobody@mail.com", "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "password_hash"}))
+
+	send := func() *httptest.ResponseRecorder {
+		request, err := http.NewRequest(
+			"POST",
+			"/auth/password-reset",
+			strings.NewReader(`{"email":"nobody@mail.com"}`),
+		)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send()
+	assert.Equal(t, 202, recorder.Code, "Should accept unknown emails")
+	assert.Eventually(t, func() bool {
+		return mock.ExpectationsWereMet() == nil
+	}, time.Second, 10*time.Millisecond, "Should look up email in tenant in background")
+	assert.Empty(t, mailer.Messages(), "Should not send mail to unknown emails")
+
+	recorder = send()
+	assert.Equal(t, 429, recorder.Code, "Should limit requests per IP")
+	assert.NotEmpty(t, recorder.Header().Get("Retry-After"), "Should set Retry-After")
+}
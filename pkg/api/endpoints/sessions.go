@@ -0,0 +1,193 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/sessions"
+)
+
+type SessionsHandler struct {
+	manager *sessions.Manager
+}
+
+func NewSessionsHandler(manager *sessions.Manager) *SessionsHandler {
+	return &SessionsHandler{manager: manager}
+}
+
+func (handler *SessionsHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *SessionsHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/users/:id/sessions",
+			Handler:     handler.ListSessions,
+			OperationId: "listSessions",
+			Summary:     "List active sessions of a user",
+			Tags:        []string{authTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.SessionResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/users/:id/sessions/:sid",
+			Handler:     handler.RevokeSession,
+			OperationId: "revokeSession",
+			Summary:     "Revoke a session of a user",
+			Tags:        []string{authTag},
+			URI:         schemas.SessionURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *SessionsHandler) ListSessions(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+
+	principal, ok := authorizeUser(ctx, userUri.Id)
+	if !ok {
+		return
+	}
+
+	userSessions, err := handler.manager.List(ctx.Request.Context(), userUri.Id)
+	if err != nil {
+		log.Printf("error listing sessions: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error listing sessions"),
+		)
+		return
+	}
+
+	response := make([]schemas.SessionResponse, 0, len(userSessions))
+	for _, session := range userSessions {
+		response = append(response, schemas.SessionResponse{
+			Id:         session.Id,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.Id == principal.SessionId,
+		})
+	}
+	ctx.JSON(http.StatusOK, response)
+}
+
+func (handler *SessionsHandler) RevokeSession(ctx *gin.Context) {
+	var sessionUri schemas.SessionURI
+	err := ctx.BindUri(&sessionUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id and sid"),
+		)
+		return
+	}
+
+	_, ok := authorizeUser(ctx, sessionUri.Id)
+	if !ok {
+		return
+	}
+
+	err = handler.manager.Revoke(ctx.Request.Context(), sessionUri.Id, sessionUri.SessionId)
+	if errors.Is(err, repositories.ErrSessionNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no active session with id %q exists", sessionUri.SessionId),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error revoking session: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error revoking session"),
+		)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+// authorizeUser allows the user itself and admins.
+func authorizeUser(ctx *gin.Context, userId string) (*auth.Principal, bool) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("authentication required"),
+		)
+		return nil, false
+	}
+	if principal.Subject != userId && !principal.HasRole(auth.RoleAdmin) {
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			models.NewErrorMessage("not allowed to access user %q", userId),
+		)
+		return nil, false
+	}
+	return principal, true
+}
+
+// requireAdmin allows admins, including those bound to a tenant.
+func requireAdmin(ctx *gin.Context) {
+	if _, ok := authorizeAdmin(ctx); ok {
+		ctx.Next()
+	}
+}
+
+func authorizeAdmin(ctx *gin.Context) (*auth.Principal, bool) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("authentication required"),
+		)
+		return nil, false
+	}
+	if !principal.HasRole(auth.RoleAdmin) {
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			models.NewErrorMessage("requires the %s role", auth.RoleAdmin),
+		)
+		return nil, false
+	}
+	return principal, true
+}
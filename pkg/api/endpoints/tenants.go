@@ -0,0 +1,308 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const tenantsTag = "tenants"
+
+type TenantsHandler struct {
+	tenantRepository repositories.TenantRepository
+}
+
+func NewTenantsHandler(tenantRepository repositories.TenantRepository) *TenantsHandler {
+	return &TenantsHandler{
+		tenantRepository: tenantRepository,
+	}
+}
+
+// Register serves the routes to admins that are not bound to a tenant.
+func (handler *TenantsHandler) Register(routerGroup *gin.RouterGroup) {
+	routerGroup.Use(requireOperator)
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *TenantsHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/",
+			Handler:     handler.CreateTenant,
+			OperationId: "createTenant",
+			Summary:     "Create a tenant",
+			Tags:        []string{tenantsTag},
+			Body:        schemas.TenantRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             schemas.TenantResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/",
+			Handler:     handler.GetAllTenants,
+			OperationId: "listTenants",
+			Summary:     "List tenants",
+			Tags:        []string{tenantsTag},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.TenantResponse{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id",
+			Handler:     handler.GetTenant,
+			OperationId: "getTenant",
+			Summary:     "Get a tenant",
+			Tags:        []string{tenantsTag},
+			URI:         schemas.TenantURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.TenantResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/:id",
+			Handler:     handler.UpdateTenant,
+			OperationId: "updateTenant",
+			Summary:     "Rename a tenant",
+			Tags:        []string{tenantsTag},
+			URI:         schemas.TenantURI{},
+			Body:        schemas.TenantUpdateRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.TenantResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/:id",
+			Handler:     handler.DeleteTenant,
+			OperationId: "deleteTenant",
+			Summary:     "Delete a tenant without users or groups",
+			Tags:        []string{tenantsTag},
+			URI:         schemas.TenantURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *TenantsHandler) CreateTenant(ctx *gin.Context) {
+	var tenantRequest schemas.TenantRequest
+	err := ctx.ShouldBindJSON(&tenantRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+	if !tenancy.ValidTenantId(tenantRequest.Id) {
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage(
+				"invalid tenant id %q, expecting lowercase letters, digits and hyphens",
+				tenantRequest.Id,
+			),
+		)
+		return
+	}
+
+	tenant := &models.Tenant{Id: tenantRequest.Id, Name: tenantRequest.Name}
+	err = handler.tenantRepository.CreateTenant(ctx.Request.Context(), tenant)
+	if errors.Is(err, repositories.ErrTenantExists) {
+		ctx.AbortWithStatusJSON(
+			http.StatusConflict,
+			models.NewErrorMessage("a tenant with id %q already exists", tenant.Id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error creating tenant: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error creating tenant"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, tenantModelToTenantResponse(tenant))
+}
+
+func (handler *TenantsHandler) GetAllTenants(ctx *gin.Context) {
+	tenants, err := handler.tenantRepository.GetAllTenants(ctx.Request.Context())
+	if err != nil {
+		log.Printf("error getting tenants: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving tenants"),
+		)
+		return
+	}
+
+	tenantResponseList := make([]schemas.TenantResponse, len(tenants))
+	for i, tenant := range tenants {
+		tenantResponseList[i] = tenantModelToTenantResponse(tenant)
+	}
+	ctx.JSON(http.StatusOK, tenantResponseList)
+}
+
+func (handler *TenantsHandler) GetTenant(ctx *gin.Context) {
+	tenantUri, ok := bindTenantURI(ctx)
+	if !ok {
+		return
+	}
+
+	tenant, err := handler.tenantRepository.GetTenantById(ctx.Request.Context(), tenantUri.Id)
+	if !handleTenantError(ctx, tenantUri.Id, err, "retrieving tenant") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tenantModelToTenantResponse(tenant))
+}
+
+func (handler *TenantsHandler) UpdateTenant(ctx *gin.Context) {
+	tenantUri, ok := bindTenantURI(ctx)
+	if !ok {
+		return
+	}
+
+	var updateRequest schemas.TenantUpdateRequest
+	err := ctx.ShouldBindJSON(&updateRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+
+	tenant, err := handler.tenantRepository.UpdateTenantById(
+		ctx.Request.Context(),
+		tenantUri.Id,
+		&models.Tenant{Name: updateRequest.Name},
+	)
+	if !handleTenantError(ctx, tenantUri.Id, err, "updating tenant") {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, tenantModelToTenantResponse(tenant))
+}
+
+func (handler *TenantsHandler) DeleteTenant(ctx *gin.Context) {
+	tenantUri, ok := bindTenantURI(ctx)
+	if !ok {
+		return
+	}
+	if tenantUri.Id == tenancy.DefaultTenant {
+		ctx.AbortWithStatusJSON(
+			http.StatusConflict,
+			models.NewErrorMessage("the %s tenant cannot be deleted", tenancy.DefaultTenant),
+		)
+		return
+	}
+
+	err := handler.tenantRepository.DeleteTenantById(ctx.Request.Context(), tenantUri.Id)
+	if errors.Is(err, repositories.ErrTenantInUse) {
+		ctx.AbortWithStatusJSON(
+			http.StatusConflict,
+			models.NewErrorMessage("tenant %q still has users or groups", tenantUri.Id),
+		)
+		return
+	}
+	if !handleTenantError(ctx, tenantUri.Id, err, "deleting tenant") {
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func bindTenantURI(ctx *gin.Context) (schemas.TenantURI, bool) {
+	var tenantUri schemas.TenantURI
+	err := ctx.BindUri(&tenantUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return tenantUri, false
+	}
+	return tenantUri, true
+}
+
+func handleTenantError(ctx *gin.Context, id string, err error, action string) bool {
+	if errors.Is(err, repositories.ErrTenantNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no tenant with id %q exists", id),
+		)
+		return false
+	}
+	if err != nil {
+		log.Printf("error %s: %v", action, err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error %s", action),
+		)
+		return false
+	}
+	return true
+}
+
+// requireOperator allows admins that are not bound to a tenant, such as those
+// of API tokens.
+func requireOperator(ctx *gin.Context) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok || !principal.HasRole(auth.RoleAdmin) || principal.TenantId != "" {
+		ctx.AbortWithStatusJSON(
+			http.StatusForbidden,
+			models.NewErrorMessage("managing tenants requires the %s role", auth.RoleAdmin),
+		)
+		return
+	}
+	ctx.Next()
+}
+
+func tenantModelToTenantResponse(tenant *models.Tenant) schemas.TenantResponse {
+	return schemas.TenantResponse{
+		Id:        tenant.Id,
+		Name:      tenant.Name,
+		CreatedAt: tenant.CreatedAt,
+		UpdatedAt: tenant.UpdatedAt,
+	}
+}
@@ -0,0 +1,126 @@
+package endpoints
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestTenantsHandler(t *testing.T) {
+	operator := &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}}
+
+	testCases := []struct {
+		name         string
+		principal    *auth.Principal
+		method       string
+		path         string
+		body         string
+		expectations func(mock sqlmock.Sqlmock)
+		expectedCode int
+	}{
+		{
+			name:   "create tenant",
+			method: "POST",
+			path:   "/tenants/",
+			body:   `{"id":"acme","name":"Acme"}`,
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "tenants"`)).
+					WithArgs("acme", "Acme", sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			principal:    operator,
+			expectedCode: 201,
+		},
+		{
+			name:         "create tenant with invalid id",
+			method:       "POST",
+			path:         "/tenants/",
+			body:         `{"id":"Acme Inc","name":"Acme"}`,
+			expectations: func(mock sqlmock.Sqlmock) {},
+			principal:    operator,
+			expectedCode: 400,
+		},
+		{
+			name:   "create tenant as tenant admin",
+			method: "POST",
+			path:   "/tenants/",
+			body:   `{"id":"globex","name":"Globex"}`,
+			principal: &auth.Principal{
+				Subject:  "abc123",
+				Roles:    []string{auth.RoleAdmin},
+				TenantId: "acme",
+			},
+			expectations: func(mock sqlmock.Sqlmock) {},
+			expectedCode: 403,
+		},
+		{
+			name:         "delete default tenant",
+			method:       "DELETE",
+			path:         "/tenants/default",
+			expectations: func(mock sqlmock.Sqlmock) {},
+			principal:    operator,
+			expectedCode: 409,
+		},
+		{
+			name:   "delete tenant with users",
+			method: "DELETE",
+			path:   "/tenants/acme",
+			expectations: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "tenants" WHERE id = $1`)).
+					WithArgs("acme").
+					WillReturnError(&pq.Error{Code: "23503", Constraint: "fk_users_tenant"})
+				mock.ExpectRollback()
+			},
+			principal:    operator,
+			expectedCode: 409,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sql mock: %v", err)
+			}
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("error opening db connection: %v", err)
+			}
+
+			router := gin.Default()
+			router.Use(func(ctx *gin.Context) {
+				ctx.Request = ctx.Request.WithContext(
+					auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+				)
+			})
+			NewTenantsHandler(repositories.NewSQLTenantRepository(db)).
+				Register(router.Group("/tenants"))
+			tc.expectations(mock)
+
+			request, err := http.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
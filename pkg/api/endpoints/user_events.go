@@ -0,0 +1,186 @@
+package endpoints
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const (
+	contentTypeEventStream = "text/event-stream"
+	heartbeatInterval      = 15 * time.Second
+	retryInterval          = 3 * time.Second
+	maxReplayEvents        = 1000
+)
+
+type UserEventsHandler struct {
+	broker            *events.Broker
+	history           events.History
+	heartbeatInterval time.Duration
+}
+
+func NewUserEventsHandler(broker *events.Broker, history events.History) *UserEventsHandler {
+	return &UserEventsHandler{
+		broker:            broker,
+		history:           history,
+		heartbeatInterval: heartbeatInterval,
+	}
+}
+
+func (handler *UserEventsHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *UserEventsHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "/events",
+			Handler:     handler.StreamEvents,
+			OperationId: "streamUserEvents",
+			Summary:     "Stream user changes as server-sent events",
+			Tags:        []string{usersTag},
+			Query:       schemas.UserEventsQuery{},
+			Header:      schemas.UserEventsHeader{},
+			ContentType: contentTypeEventStream,
+			Responses: map[int]any{
+				http.StatusOK:                  events.Event{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *UserEventsHandler) StreamEvents(ctx *gin.Context) {
+	var eventsQuery schemas.UserEventsQuery
+	err := ctx.ShouldBindQuery(&eventsQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting user_id"),
+		)
+		return
+	}
+
+	// Streams of all users are reserved for admins.
+	if eventsQuery.UserId == "" {
+		_, ok := authorizeAdmin(ctx)
+		if !ok {
+			return
+		}
+	} else if _, ok := authorizeUser(ctx, eventsQuery.UserId); !ok {
+		return
+	}
+
+	var eventsHeader schemas.UserEventsHeader
+	err = ctx.ShouldBindHeader(&eventsHeader)
+	if err != nil {
+		log.Printf("invalid header: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid header, expecting numeric Last-Event-ID"),
+		)
+		return
+	}
+
+	subscription := handler.broker.Subscribe()
+	defer subscription.Close()
+
+	var backlog []*events.Event
+	if eventsHeader.LastEventId > 0 {
+		backlog, err = handler.replay(ctx.Request.Context(), eventsHeader.LastEventId)
+		if err != nil {
+			log.Printf("error replaying events: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				models.NewErrorMessage("error replaying events"),
+			)
+			return
+		}
+	}
+
+	ctx.Header("Content-Type", contentTypeEventStream)
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Render(-1, sse.Event{Retry: uint(retryInterval.Milliseconds())})
+
+	tenantId, tenanted := tenancy.TenantFromContext(ctx.Request.Context())
+	matches := func(event *events.Event) bool {
+		if tenanted && event.TenantId != tenantId {
+			return false
+		}
+		return eventsQuery.UserId == "" || event.AggregateId == eventsQuery.UserId
+	}
+
+	replayed := map[int64]bool{}
+	for _, event := range backlog {
+		replayed[event.Id] = true
+		if matches(event) {
+			renderEvent(ctx, event)
+		}
+	}
+	ctx.Writer.Flush()
+
+	ticker := time.NewTicker(handler.heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			if replayed[event.Id] {
+				delete(replayed, event.Id)
+				continue
+			}
+			if !matches(event) {
+				continue
+			}
+			renderEvent(ctx, event)
+		case <-ticker.C:
+			_, _ = ctx.Writer.WriteString(": heartbeat\n\n")
+		}
+		ctx.Writer.Flush()
+	}
+}
+
+func (handler *UserEventsHandler) replay(
+	ctx context.Context,
+	lastEventId int64,
+) ([]*events.Event, error) {
+	backlog, ok := handler.broker.Replay(lastEventId)
+	if ok || handler.history == nil {
+		return backlog, nil
+	}
+	return handler.history.EventsAfter(ctx, lastEventId, maxReplayEvents)
+}
+
+func renderEvent(ctx *gin.Context, event *events.Event) {
+	ctx.Render(-1, sse.Event{
+		Id:    strconv.FormatInt(event.Id, 10),
+		Event: event.Type,
+		Data:  event,
+	})
+}
@@ -0,0 +1,176 @@
+package endpoints
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func readEventIds(t *testing.T, reader *bufio.Reader, count int) []string {
+	var ids []string
+	for len(ids) < count {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading stream: %v", err)
+		}
+		if strings.HasPrefix(line, "id:") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id:")))
+		}
+	}
+	return ids
+}
+
+func TestUserEventsHandler_Authorization(t *testing.T) {
+	testCases := []struct {
+		principal    *auth.Principal
+		path         string
+		expectedCode int
+		reason       string
+	}{
+		{
+			path:         "/users/events?user_id=abc123",
+			expectedCode: 401,
+			reason:       "Should require authentication",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc123"},
+			path:         "/users/events",
+			expectedCode: 403,
+			reason:       "Should reserve unfiltered stream for admins",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc123"},
+			path:         "/users/events?user_id=abc124",
+			expectedCode: 403,
+			reason:       "Should not stream events of other users",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			router := gin.New()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			NewUserEventsHandler(events.NewBroker(8, 8), nil).Register(router.Group("/users"))
+
+			request, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+		})
+	}
+}
+
+func TestUserEventsHandler_StreamEvents(t *testing.T) {
+	broker := events.NewBroker(8, 8)
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(
+			auth.WithPrincipal(ctx.Request.Context(), &auth.Principal{Subject: "abc123"}),
+		)
+	})
+	NewUserEventsHandler(broker, nil).Register(router.Group("/users"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	_ = broker.Publish(ctx, &events.Event{Id: 1, Type: events.UserCreated, AggregateId: "abc123"})
+	_ = broker.Publish(ctx, &events.Event{Id: 2, Type: events.UserCreated, AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 3, Type: events.UserUpdated, AggregateId: "abc123"})
+
+	requestCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(
+		requestCtx,
+		"GET",
+		server.URL+"/users/events?user_id=abc123",
+		nil,
+	)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	request.Header.Set("Last-Event-ID", "1")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("error opening stream: %v", err)
+	}
+	defer response.Body.Close()
+
+	assert.Equal(t, 200, response.StatusCode, "Should match response code")
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	assert.Equal(
+		t,
+		[]string{"3"},
+		readEventIds(t, reader, 1),
+		"Should replay filtered events after Last-Event-ID",
+	)
+
+	_ = broker.Publish(ctx, &events.Event{Id: 4, Type: events.UserDeleted, AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 5, Type: events.UserDeleted, AggregateId: "abc123"})
+
+	assert.Equal(t, []string{"5"}, readEventIds(t, reader, 1), "Should stream filtered events")
+}
+
+func TestUserEventsHandler_StreamTenantEvents(t *testing.T) {
+	broker := events.NewBroker(8, 8)
+	admin := &auth.Principal{Subject: "ops", TenantId: "acme", Roles: []string{auth.RoleAdmin}}
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		requestCtx := auth.WithPrincipal(ctx.Request.Context(), admin)
+		ctx.Request = ctx.Request.WithContext(tenancy.WithTenant(requestCtx, "acme"))
+	})
+	NewUserEventsHandler(broker, nil).Register(router.Group("/users"))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	ctx := context.Background()
+	_ = broker.Publish(ctx, &events.Event{Id: 1, TenantId: "acme", AggregateId: "abc123"})
+	_ = broker.Publish(ctx, &events.Event{Id: 2, TenantId: "globex", AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 3, TenantId: "acme", AggregateId: "abc125"})
+
+	requestCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	request, err := http.NewRequestWithContext(requestCtx, "GET", server.URL+"/users/events", nil)
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+	request.Header.Set("Last-Event-ID", "1")
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		t.Fatalf("error opening stream: %v", err)
+	}
+	defer response.Body.Close()
+
+	reader := bufio.NewReader(response.Body)
+	assert.Equal(t, []string{"3"}, readEventIds(t, reader, 1), "Should replay events of tenant")
+
+	_ = broker.Publish(ctx, &events.Event{Id: 4, TenantId: "globex", AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 5, TenantId: "acme", AggregateId: "abc123"})
+
+	assert.Equal(t, []string{"5"}, readEventIds(t, reader, 1), "Should stream events of tenant")
+}
@@ -7,13 +7,23 @@ import (
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/johannaojeling/go-rest-api/pkg/history"
+	"github.com/johannaojeling/go-rest-api/pkg/httpcache"
 	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
 	"github.com/johannaojeling/go-rest-api/pkg/repositories"
 	"github.com/johannaojeling/go-rest-api/pkg/schemas"
 )
 
+const (
+	usersTag            = "users"
+	defaultHistoryLimit = 50
+	maxFilteredUsers    = 100
+)
+
 type UsersHandler struct {
 	userRepository repositories.UserRepository
+	history        *history.Reader
 }
 
 func NewUsersHandler(userRepository repositories.UserRepository) *UsersHandler {
@@ -22,14 +32,121 @@ func NewUsersHandler(userRepository repositories.UserRepository) *UsersHandler {
 	}
 }
 
+func (handler *UsersHandler) WithHistory(reader *history.Reader) *UsersHandler {
+	handler.history = reader
+	return handler
+}
+
 func (handler *UsersHandler) Register(routerGroup *gin.RouterGroup) {
-	{
-		routerGroup.POST("/", handler.CreateUser)
-		routerGroup.GET("/:id", handler.GetUser)
-		routerGroup.GET("/", handler.GetAllUsers)
-		routerGroup.PUT("/:id", handler.UpdateUser)
-		routerGroup.DELETE("/:id", handler.DeleteUser)
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *UsersHandler) Routes() []openapi.Route {
+	routes := []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/",
+			Handler:     handler.CreateUser,
+			OperationId: "createUser",
+			Summary:     "Create a user",
+			Tags:        []string{usersTag},
+			Body:        schemas.UserRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             schemas.UserResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id",
+			Handler:     handler.GetUser,
+			OperationId: "getUser",
+			Summary:     "Get a user",
+			Tags:        []string{usersTag},
+			URI:         schemas.UserURI{},
+			Query:       schemas.UserQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.UserResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/",
+			Handler:     handler.GetAllUsers,
+			OperationId: "listUsers",
+			Summary:     "List users",
+			Tags:        []string{usersTag},
+			Query:       schemas.UserListQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.UserResponse{},
+				http.StatusNotModified:         nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/:id",
+			Handler:     handler.UpdateUser,
+			OperationId: "updateUser",
+			Summary:     "Update a user, creating it if it does not exist",
+			Tags:        []string{usersTag},
+			URI:         schemas.UserURI{},
+			Body:        schemas.UserRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.UserResponse{},
+				http.StatusCreated:             schemas.UserResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/:id",
+			Handler:     handler.DeleteUser,
+			OperationId: "deleteUser",
+			Summary:     "Delete a user",
+			Tags:        []string{usersTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
 	}
+	if handler.history != nil {
+		routes = append(routes, openapi.Route{
+			Method:      http.MethodGet,
+			Path:        "/:id/history",
+			Handler:     handler.GetUserHistory,
+			OperationId: "getUserHistory",
+			Summary:     "List field changes of a user",
+			Tags:        []string{usersTag},
+			URI:         schemas.UserURI{},
+			Query:       schemas.UserHistoryQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.UserFieldChangeResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		})
+	}
+	return routes
 }
 
 func (handler *UsersHandler) CreateUser(ctx *gin.Context) {
@@ -45,7 +162,14 @@ func (handler *UsersHandler) CreateUser(ctx *gin.Context) {
 	}
 
 	user := userRequestToUserModel(userRequest)
-	err = handler.userRepository.CreateUser(user)
+	err = handler.userRepository.CreateUser(ctx.Request.Context(), user)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		ctx.AbortWithStatusJSON(
+			http.StatusConflict,
+			models.NewErrorMessage("a user with email %q already exists", user.Email),
+		)
+		return
+	}
 	if err != nil {
 		log.Printf("error creating user: %v", err)
 		ctx.AbortWithStatusJSON(
@@ -72,7 +196,40 @@ func (handler *UsersHandler) GetUser(ctx *gin.Context) {
 	}
 	id := userUri.Id
 
-	user, err := handler.userRepository.GetUserById(id)
+	var userQuery schemas.UserQuery
+	err = ctx.ShouldBindQuery(&userQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting as_of timestamp"),
+		)
+		return
+	}
+
+	var user *models.User
+	switch {
+	case userQuery.AsOf.IsZero():
+		user, err = handler.userRepository.GetUserById(ctx.Request.Context(), id)
+	case handler.history == nil:
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("point-in-time reads are not enabled"),
+		)
+		return
+	default:
+		// Past versions may hold data that was since removed, so they are
+		// only shown to the user and admins.
+		if _, ok := authorizeUser(ctx, id); !ok {
+			return
+		}
+		user, err = handler.history.GetUserAsOf(ctx.Request.Context(), id, userQuery.AsOf)
+	}
+	if errors.Is(err, history.ErrOutsideRetention) {
+		log.Printf("invalid as_of: %v", err)
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorMessage(err.Error()))
+		return
+	}
 	if errors.Is(err, repositories.ErrUserNotFound) {
 		log.Printf("user not found: %v", err)
 		ctx.AbortWithStatusJSON(
@@ -95,7 +252,53 @@ func (handler *UsersHandler) GetUser(ctx *gin.Context) {
 }
 
 func (handler *UsersHandler) GetAllUsers(ctx *gin.Context) {
-	users, err := handler.userRepository.GetAllUsers()
+	var listQuery schemas.UserListQuery
+	err := ctx.ShouldBindQuery(&listQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting limit and offset"),
+		)
+		return
+	}
+
+	filter := userListFilter(listQuery)
+	version, err := handler.userRepository.GetUsersVersion(ctx.Request.Context(), filter)
+	if err != nil {
+		log.Printf("error getting users version: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving users"),
+		)
+		return
+	}
+
+	// The version is checked before the users are retrieved, so that
+	// unchanged lists cost a single aggregate query.
+	etag := httpcache.ETag(version.Count, version.LastModified.UnixNano(), listQuery)
+	ctx.Header("ETag", etag)
+	if !version.LastModified.IsZero() {
+		ctx.Header("Last-Modified", version.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if httpcache.NotModified(ctx.Request, etag, version.LastModified) {
+		ctx.Status(http.StatusNotModified)
+		return
+	}
+
+	options := repositories.ListOptions{
+		Limit:  listQuery.Limit,
+		Offset: listQuery.Offset,
+	}
+	var users []*models.User
+	if filter == nil {
+		users, err = handler.userRepository.GetAllUsers(ctx.Request.Context(), options)
+	} else {
+		if options.Limit == 0 {
+			options.Limit = maxFilteredUsers
+		}
+		users, _, err = handler.userRepository.SearchUsers(ctx.Request.Context(), filter, options)
+	}
 	if err != nil {
 		log.Printf("error getting users: %v", err)
 		ctx.AbortWithStatusJSON(
@@ -113,6 +316,35 @@ func (handler *UsersHandler) GetAllUsers(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, userResponseList)
 }
 
+// userListFilter matches users whose fields equal all given fields, ignoring
+// case, and is nil if no fields are given.
+func userListFilter(listQuery schemas.UserListQuery) *repositories.UserFilter {
+	fields := []struct {
+		field repositories.UserField
+		value string
+	}{
+		{repositories.UserFieldFirstName, listQuery.FirstName},
+		{repositories.UserFieldLastName, listQuery.LastName},
+		{repositories.UserFieldEmail, listQuery.Email},
+	}
+
+	filter := &repositories.UserFilter{}
+	for _, field := range fields {
+		if field.value == "" {
+			continue
+		}
+		filter.And = append(filter.And, &repositories.UserFilter{
+			Field:    field.field,
+			Operator: repositories.FilterEqual,
+			Value:    field.value,
+		})
+	}
+	if len(filter.And) == 0 {
+		return nil
+	}
+	return filter
+}
+
 func (handler *UsersHandler) UpdateUser(ctx *gin.Context) {
 	var userUri schemas.UserURI
 	err := ctx.BindUri(&userUri)
@@ -138,7 +370,7 @@ func (handler *UsersHandler) UpdateUser(ctx *gin.Context) {
 	}
 
 	updates := userRequestToUserModel(userRequest)
-	updatedUser, err := handler.userRepository.UpdateUserById(id, updates)
+	updatedUser, err := handler.userRepository.UpdateUserById(ctx.Request.Context(), id, updates)
 
 	if errors.Is(err, repositories.ErrUserNotFound) {
 		newUser := &models.User{
@@ -147,7 +379,14 @@ func (handler *UsersHandler) UpdateUser(ctx *gin.Context) {
 			LastName:  updates.LastName,
 			Email:     updates.Email,
 		}
-		err = handler.userRepository.CreateUser(newUser)
+		err = handler.userRepository.CreateUser(ctx.Request.Context(), newUser)
+		if errors.Is(err, repositories.ErrEmailTaken) {
+			ctx.AbortWithStatusJSON(
+				http.StatusConflict,
+				models.NewErrorMessage("a user with email %q already exists", newUser.Email),
+			)
+			return
+		}
 		if err != nil {
 			log.Printf("error creating user: %v", err)
 			ctx.AbortWithStatusJSON(
@@ -162,6 +401,13 @@ func (handler *UsersHandler) UpdateUser(ctx *gin.Context) {
 		return
 	}
 
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		ctx.AbortWithStatusJSON(
+			http.StatusConflict,
+			models.NewErrorMessage("a user with email %q already exists", updates.Email),
+		)
+		return
+	}
 	if err != nil {
 		log.Printf("error updating user: %v", err)
 		ctx.AbortWithStatusJSON(
@@ -188,7 +434,7 @@ func (handler *UsersHandler) DeleteUser(ctx *gin.Context) {
 	}
 	id := userUri.Id
 
-	err = handler.userRepository.DeleteUserById(id)
+	err = handler.userRepository.DeleteUserById(ctx.Request.Context(), id)
 	if errors.Is(err, repositories.ErrUserNotFound) {
 		log.Printf("user not found: %v", err)
 		ctx.AbortWithStatusJSON(
@@ -209,6 +455,72 @@ func (handler *UsersHandler) DeleteUser(ctx *gin.Context) {
 	ctx.Status(http.StatusNoContent)
 }
 
+func (handler *UsersHandler) GetUserHistory(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+	if _, ok := authorizeUser(ctx, userUri.Id); !ok {
+		return
+	}
+
+	var historyQuery schemas.UserHistoryQuery
+	err = ctx.ShouldBindQuery(&historyQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting limit and offset"),
+		)
+		return
+	}
+
+	options := repositories.ListOptions{
+		Limit:  historyQuery.Limit,
+		Offset: historyQuery.Offset,
+	}
+	if options.Limit == 0 {
+		options.Limit = defaultHistoryLimit
+	}
+	changes, err := handler.history.GetUserHistory(ctx.Request.Context(), userUri.Id, options)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		log.Printf("user not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no user with id %q exists", userUri.Id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting user history: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving user history"),
+		)
+		return
+	}
+
+	changeResponseList := make([]schemas.UserFieldChangeResponse, len(changes))
+	for i, change := range changes {
+		changeResponseList[i] = schemas.UserFieldChangeResponse{
+			Id:        change.Id,
+			Field:     change.Field,
+			OldValue:  change.OldValue,
+			NewValue:  change.NewValue,
+			Actor:     change.Actor,
+			ChangedAt: change.ChangedAt,
+		}
+	}
+
+	ctx.JSON(http.StatusOK, changeResponseList)
+}
+
 func userRequestToUserModel(userRequest schemas.UserRequest) *models.User {
 	return &models.User{
 		FirstName: userRequest.FirstName,
@@ -219,9 +531,10 @@ func userRequestToUserModel(userRequest schemas.UserRequest) *models.User {
 
 func userModelToUserResponse(user *models.User) schemas.UserResponse {
 	return schemas.UserResponse{
-		Id:        user.Id,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Email:     user.Email,
+		Id:            user.Id,
+		FirstName:     user.FirstName,
+		LastName:      user.LastName,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
 	}
 }
@@ -2,13 +2,16 @@ package endpoints
 
 import (
 	"bytes"
+	"context"
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
@@ -16,14 +19,29 @@ import (
 	"github.com/stretchr/testify/suite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/history"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
 	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
 )
 
 var (
 	columns = []string{"id", "first_name", "last_name", "email"}
 )
 
+func expectUsersVersion(mock sqlmock.Sqlmock, count int, lastModified *time.Time) {
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT count(*) AS count, greatest(max(updated_at), ` +
+			`(SELECT max(created_at) FROM "outbox" WHERE aggregate_type = $1 AND event_type = $2)) ` +
+			`AS last_modified FROM "users"`,
+	)).WillReturnRows(
+		sqlmock.NewRows([]string{"count", "last_modified"}).AddRow(count, lastModified),
+	)
+}
+
 type Suite struct {
 	suite.Suite
 	mock   sqlmock.Sqlmock
@@ -78,10 +96,11 @@ func (s *Suite) TestUsersHandler_CreateUser() {
 			returnRow:    []driver.Value{"abc123", "Jane", "Doe", "jane.doe@mail.com"},
 			expectedCode: 201,
 			expectedBody: map[string]interface{}{
-				"id":         "abc123",
-				"first_name": "Jane",
-				"last_name":  "Doe",
-				"email":      "jane.doe@mail.com",
+				"id":             "abc123",
+				"first_name":     "Jane",
+				"last_name":      "Doe",
+				"email":          "jane.doe@mail.com",
+				"email_verified": false,
 			},
 			reason: "Should return status 201 and newly created user",
 		},
@@ -102,13 +121,13 @@ func (s *Suite) TestUsersHandler_CreateUser() {
 	for i, tc := range testCases {
 		s.T().Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
 			if tc.returnRow != nil {
-				query := `INSERT INTO "users" ("first_name","last_name","email","created_at","updated_at") ` +
-					`VALUES ($1,$2,$3,$4,$5) RETURNING "id"`
+				query := `INSERT INTO "users" ("tenant_id","first_name","last_name","email","email_verified","created_at","updated_at") ` +
+					`VALUES ($1,$2,$3,$4,$5,$6,$7) RETURNING "id"`
 				rows := sqlmock.NewRows(columns).AddRow(tc.returnRow...)
 
 				s.mock.ExpectBegin()
 				s.mock.ExpectQuery(regexp.QuoteMeta(query)).
-					WithArgs(tc.requestBody["first_name"], tc.requestBody["last_name"], tc.requestBody["email"], sqlmock.AnyArg(), sqlmock.AnyArg()).
+					WithArgs(tenancy.DefaultTenant, tc.requestBody["first_name"], tc.requestBody["last_name"], tc.requestBody["email"], false, sqlmock.AnyArg(), sqlmock.AnyArg()).
 					WillReturnRows(rows)
 				s.mock.ExpectCommit()
 			}
@@ -157,10 +176,11 @@ func (s *Suite) TestUsersHandler_GetUser() {
 			returnRow:    []driver.Value{"abc123", "Jane", "Doe", "jane.doe@mail.com"},
 			expectedCode: 200,
 			expectedBody: map[string]interface{}{
-				"id":         "abc123",
-				"first_name": "Jane",
-				"last_name":  "Doe",
-				"email":      "jane.doe@mail.com",
+				"id":             "abc123",
+				"first_name":     "Jane",
+				"last_name":      "Doe",
+				"email":          "jane.doe@mail.com",
+				"email_verified": false,
 			},
 			reason: "Should return status 200 and user with id 'abc123'",
 		},
@@ -216,12 +236,16 @@ func (s *Suite) TestUsersHandler_GetUser() {
 
 func (s *Suite) TestUsersHandler_GetUsers() {
 	testCases := []struct {
+		path         string
+		query        string
 		returnRows   [][]driver.Value
 		expectedCode int
 		expectedBody []map[string]interface{}
 		reason       string
 	}{
 		{
+			path:  "/",
+			query: `SELECT * FROM "users"`,
 			returnRows: [][]driver.Value{
 				{"abc123", "Jane", "Doe", "jane.doe@mail.com"},
 				{"abc124", "John", "Doe", "john.doe@mail.com"},
@@ -229,41 +253,62 @@ func (s *Suite) TestUsersHandler_GetUsers() {
 			expectedCode: 200,
 			expectedBody: []map[string]interface{}{
 				{
-					"id":         "abc123",
-					"first_name": "Jane",
-					"last_name":  "Doe",
-					"email":      "jane.doe@mail.com",
+					"id":             "abc123",
+					"first_name":     "Jane",
+					"last_name":      "Doe",
+					"email":          "jane.doe@mail.com",
+					"email_verified": false,
 				},
 				{
-					"id":         "abc124",
-					"first_name": "John",
-					"last_name":  "Doe",
-					"email":      "john.doe@mail.com",
+					"id":             "abc124",
+					"first_name":     "John",
+					"last_name":      "Doe",
+					"email":          "john.doe@mail.com",
+					"email_verified": false,
 				},
 			},
 			reason: "Should return status 200 and list of users",
 		},
 		{
+			path:         "/",
+			query:        `SELECT * FROM "users"`,
 			returnRows:   [][]driver.Value{},
 			expectedCode: 200,
 			expectedBody: []map[string]interface{}{},
 			reason:       "Should return status 200 and empty list",
 		},
+		{
+			path:  "/?limit=1&offset=1",
+			query: `SELECT * FROM "users" ORDER BY id LIMIT 1 OFFSET 1`,
+			returnRows: [][]driver.Value{
+				{"abc124", "John", "Doe", "john.doe@mail.com"},
+			},
+			expectedCode: 200,
+			expectedBody: []map[string]interface{}{
+				{
+					"id":             "abc124",
+					"first_name":     "John",
+					"last_name":      "Doe",
+					"email":          "john.doe@mail.com",
+					"email_verified": false,
+				},
+			},
+			reason: "Should return status 200 and page of users when limit and offset are set",
+		},
 	}
 
 	for i, tc := range testCases {
 		s.T().Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
-			query := `SELECT * FROM "users"`
-
 			rows := sqlmock.NewRows(columns)
 			for _, row := range tc.returnRows {
 				rows = rows.AddRow(row...)
 			}
 
-			s.mock.ExpectQuery(regexp.QuoteMeta(query)).
+			expectUsersVersion(s.mock, len(tc.returnRows), nil)
+			s.mock.ExpectQuery(regexp.QuoteMeta(tc.query)).
 				WillReturnRows(rows)
 
-			request, err := http.NewRequest("GET", "/", nil)
+			request, err := http.NewRequest("GET", tc.path, nil)
 			if err != nil {
 				t.Fatalf("error creating request %v", err)
 			}
@@ -289,6 +334,94 @@ func (s *Suite) TestUsersHandler_GetUsers() {
 	}
 }
 
+func (s *Suite) TestUsersHandler_GetUsersConditional() {
+	lastModified := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+
+	expectUsersVersion(s.mock, 2, &lastModified)
+	s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users" WHERE (lower(email) = $1)`)).
+		WithArgs("jane.doe@mail.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	request, _ := http.NewRequest("GET", "/?email=jane.doe@mail.com", nil)
+	recorder := httptest.NewRecorder()
+	s.router.ServeHTTP(recorder, request)
+
+	etag := recorder.Header().Get("ETag")
+	assert.Equal(s.T(), 200, recorder.Code, "Should list filtered users")
+	assert.NotEmpty(s.T(), etag, "Should set ETag")
+	assert.Equal(
+		s.T(),
+		"Thu, 03 Mar 2022 12:00:00 GMT",
+		recorder.Header().Get("Last-Modified"),
+		"Should set Last-Modified to latest update",
+	)
+
+	deletedAt := lastModified.Add(time.Minute)
+	testCases := []struct {
+		header       string
+		value        string
+		count        int
+		lastModified *time.Time
+		expectedCode int
+		reason       string
+	}{
+		{
+			header:       "If-None-Match",
+			value:        etag,
+			count:        2,
+			expectedCode: 304,
+			reason:       "Should return status 304 when ETag matches",
+		},
+		{
+			header:       "If-Modified-Since",
+			value:        "Thu, 03 Mar 2022 12:00:00 GMT",
+			count:        2,
+			expectedCode: 304,
+			reason:       "Should return status 304 when not modified since",
+		},
+		{
+			header:       "If-None-Match",
+			value:        etag,
+			count:        1,
+			lastModified: &deletedAt,
+			expectedCode: 200,
+			reason:       "Should return status 200 when a user was removed",
+		},
+		{
+			header:       "If-Modified-Since",
+			value:        "Thu, 03 Mar 2022 12:00:00 GMT",
+			count:        1,
+			lastModified: &deletedAt,
+			expectedCode: 200,
+			reason:       "Should return status 200 when a user was removed since",
+		},
+	}
+
+	for i, tc := range testCases {
+		s.T().Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			version := &lastModified
+			if tc.lastModified != nil {
+				version = tc.lastModified
+			}
+			expectUsersVersion(s.mock, tc.count, version)
+			if tc.expectedCode == 200 {
+				s.mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM "users"`)).
+					WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			}
+
+			request, _ := http.NewRequest("GET", "/?email=jane.doe@mail.com", nil)
+			request.Header.Set(tc.header, tc.value)
+			recorder := httptest.NewRecorder()
+			s.router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			if tc.expectedCode == 304 {
+				assert.Empty(t, recorder.Body.String(), "Should not serialize body")
+			}
+		})
+	}
+}
+
 func (s *Suite) TestUsersHandler_UpdateUser() {
 	testCases := []struct {
 		id              string
@@ -309,10 +442,11 @@ func (s *Suite) TestUsersHandler_UpdateUser() {
 			updateReturnRow: []driver.Value{"abc123", "Jane", "Doe", "jane.doe@mail.com"},
 			expectedCode:    200,
 			expectedBody: map[string]interface{}{
-				"id":         "abc123",
-				"first_name": "Jane",
-				"last_name":  "Doe",
-				"email":      "jane@mail.com",
+				"id":             "abc123",
+				"first_name":     "Jane",
+				"last_name":      "Doe",
+				"email":          "jane@mail.com",
+				"email_verified": false,
 			},
 			reason: "Should return status 200 and newly updated user when user with id exists",
 		},
@@ -327,10 +461,11 @@ func (s *Suite) TestUsersHandler_UpdateUser() {
 			createReturnRow: []driver.Value{"abc123", "Jane", "Doe", "jane@mail.com"},
 			expectedCode:    201,
 			expectedBody: map[string]interface{}{
-				"id":         "abc123",
-				"first_name": "Jane",
-				"last_name":  "Doe",
-				"email":      "jane@mail.com",
+				"id":             "abc123",
+				"first_name":     "Jane",
+				"last_name":      "Doe",
+				"email":          "jane@mail.com",
+				"email_verified": false,
 			},
 			reason: "Should return status 201 and newly created user when no user with id exists",
 		},
@@ -345,25 +480,27 @@ func (s *Suite) TestUsersHandler_UpdateUser() {
 				updateRows = updateRows.AddRow(tc.updateReturnRow...)
 			}
 
+			s.mock.ExpectBegin()
 			s.mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
 				WithArgs(tc.id).
 				WillReturnRows(updateRows)
 
 			if tc.updateReturnRow != nil {
 				updateQuery := `UPDATE "users" SET "first_name"=$1,"last_name"=$2,"email"=$3,"updated_at"=$4 WHERE "id" = $5`
-				s.mock.ExpectBegin()
 				s.mock.ExpectExec(regexp.QuoteMeta(updateQuery)).
 					WithArgs(tc.requestBody["first_name"], tc.requestBody["last_name"], tc.requestBody["email"], sqlmock.AnyArg(), tc.id).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				s.mock.ExpectCommit()
 			} else {
-				createQuery := `INSERT INTO "users" ("first_name","last_name","email","created_at","updated_at","id") ` +
-					`VALUES ($1,$2,$3,$4,$5,$6) RETURNING "id"`
+				s.mock.ExpectRollback()
+
+				createQuery := `INSERT INTO "users" ("tenant_id","first_name","last_name","email","email_verified","created_at","updated_at","id") ` +
+					`VALUES ($1,$2,$3,$4,$5,$6,$7,$8) RETURNING "id"`
 				createRows := sqlmock.NewRows(columns).AddRow(tc.createReturnRow...)
 
 				s.mock.ExpectBegin()
 				s.mock.ExpectQuery(regexp.QuoteMeta(createQuery)).
-					WithArgs(tc.requestBody["first_name"], tc.requestBody["last_name"], tc.requestBody["email"], sqlmock.AnyArg(), sqlmock.AnyArg(), tc.id).
+					WithArgs(tenancy.DefaultTenant, tc.requestBody["first_name"], tc.requestBody["last_name"], tc.requestBody["email"], false, sqlmock.AnyArg(), sqlmock.AnyArg(), tc.id).
 					WillReturnRows(createRows)
 				s.mock.ExpectCommit()
 			}
@@ -434,17 +571,19 @@ func (s *Suite) TestUsersHandler_DeleteUser() {
 				rows = rows.AddRow(tc.returnRow...)
 			}
 
+			s.mock.ExpectBegin()
 			s.mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
 				WithArgs(tc.id).
 				WillReturnRows(rows)
 
 			if tc.returnRow != nil {
 				deleteQuery := `DELETE FROM "users" WHERE "users"."id" = $1`
-				s.mock.ExpectBegin()
 				s.mock.ExpectExec(regexp.QuoteMeta(deleteQuery)).
 					WithArgs(tc.id).
 					WillReturnResult(sqlmock.NewResult(1, 1))
 				s.mock.ExpectCommit()
+			} else {
+				s.mock.ExpectRollback()
 			}
 
 			request, err := http.NewRequest("DELETE", "/"+tc.id, nil)
@@ -474,3 +613,202 @@ func (s *Suite) TestUsersHandler_DeleteUser() {
 		})
 	}
 }
+
+// TestUsersHandler_RowLevelSecurity runs against the Postgres database of
+// TEST_DB_URL, which has to connect as a role that is neither superuser nor
+// BYPASSRLS. The database is opened without the tenancy plugin, so that only
+// the policy separates tenants.
+func TestUsersHandler_HistoryAuthorization(t *testing.T) {
+	historyQuery := regexp.QuoteMeta(`SELECT * FROM "user_history" WHERE user_id = $1`)
+	historyColumns := []string{"id", "user_id", "field", "old_value", "new_value"}
+	user := &auth.Principal{Subject: "abc123"}
+	admin := &auth.Principal{Subject: "admin", Roles: []string{auth.RoleAdmin}}
+
+	testCases := []struct {
+		name         string
+		principal    *auth.Principal
+		path         string
+		expectQuery  bool
+		expectedCode int
+	}{
+		{
+			name:         "history anonymously",
+			path:         "/abc123/history",
+			expectedCode: 401,
+		},
+		{
+			name:         "history of other user",
+			principal:    &auth.Principal{Subject: "def456"},
+			path:         "/abc123/history",
+			expectedCode: 403,
+		},
+		{
+			name:         "own history",
+			principal:    user,
+			path:         "/abc123/history",
+			expectQuery:  true,
+			expectedCode: 200,
+		},
+		{
+			name:         "history of unknown user as admin",
+			principal:    admin,
+			path:         "/unknown/history",
+			expectedCode: 404,
+		},
+		{
+			name:         "past version anonymously",
+			path:         "/abc123?as_of=2100-01-01T00:00:00Z",
+			expectedCode: 401,
+		},
+		{
+			name:         "past version of other user",
+			principal:    &auth.Principal{Subject: "def456"},
+			path:         "/abc123?as_of=2100-01-01T00:00:00Z",
+			expectedCode: 403,
+		},
+		{
+			name:         "past version as admin",
+			principal:    admin,
+			path:         "/abc123?as_of=2100-01-01T00:00:00Z",
+			expectQuery:  true,
+			expectedCode: 200,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			conn, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sql mock: %v", err)
+			}
+			db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+			if err != nil {
+				t.Fatalf("error opening db connection: %v", err)
+			}
+			if tc.expectQuery {
+				mock.ExpectQuery(historyQuery).
+					WillReturnRows(sqlmock.NewRows(historyColumns))
+			}
+
+			userRepository := repositories.NewMemoryUserRepository()
+			err = userRepository.CreateUser(context.Background(), &models.User{
+				Id:        "abc123",
+				FirstName: "Jane",
+				LastName:  "Doe",
+				Email:     "jane.doe@mail.com",
+			})
+			if err != nil {
+				t.Fatalf("error creating user: %v", err)
+			}
+
+			router := gin.Default()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			NewUsersHandler(userRepository).
+				WithHistory(
+					history.NewReader(userRepository, repositories.NewSQLHistoryRepository(db), 0),
+				).
+				Register(router.Group(""))
+
+			request, err := http.NewRequest("GET", tc.path, nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
+
+func TestUsersHandler_RowLevelSecurity(t *testing.T) {
+	dsn := os.Getenv("TEST_DB_URL")
+	if dsn == "" {
+		t.Skip("TEST_DB_URL is not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	var bypass bool
+	err = db.Raw(
+		"SELECT rolsuper OR rolbypassrls FROM pg_roles WHERE rolname = current_user",
+	).Scan(&bypass).Error
+	if err != nil {
+		t.Fatalf("error looking up role: %v", err)
+	}
+	if bypass {
+		t.Skip("TEST_DB_URL connects as a role that bypasses row-level security")
+	}
+
+	err = db.AutoMigrate(&models.Tenant{}, &models.User{})
+	if err != nil {
+		t.Fatalf("error migrating database: %v", err)
+	}
+	err = db.Clauses(clause.OnConflict{DoNothing: true}).
+		Create([]*models.Tenant{{Id: "acme", Name: "Acme"}, {Id: "globex", Name: "Globex"}}).
+		Error
+	if err != nil {
+		t.Fatalf("error creating tenants: %v", err)
+	}
+	err = tenancy.MigrateRowLevelSecurity(db)
+	if err != nil {
+		t.Fatalf("error enabling row-level security: %v", err)
+	}
+
+	userRepository := repositories.NewSQLUserRepository(db).WithRowLevelSecurity()
+	acmeCtx := tenancy.WithTenant(context.Background(), "acme")
+	user := &models.User{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     fmt.Sprintf("jane.doe+%d@mail.com", time.Now().UnixNano()),
+	}
+	err = userRepository.CreateUser(acmeCtx, user)
+	if err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	t.Cleanup(func() { _ = userRepository.DeleteUserById(acmeCtx, user.Id) })
+
+	var count int64
+	err = db.Model(&models.User{}).Where("id = ?", user.Id).Count(&count).Error
+	assert.NoError(t, err, "Should count users without tenant")
+	assert.Zero(t, count, "Should not return users without tenant")
+
+	router := gin.Default()
+	router.Use(func(ctx *gin.Context) {
+		ctx.Request = ctx.Request.WithContext(tenancy.WithTenant(ctx.Request.Context(), "globex"))
+	})
+	NewUsersHandler(userRepository).Register(router.Group(""))
+
+	send := func(path string) *httptest.ResponseRecorder {
+		request, err := http.NewRequest("GET", path, nil)
+		if err != nil {
+			t.Fatalf("error creating request %v", err)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := send("/" + user.Id)
+	assert.Equal(t, 404, recorder.Code, "Should not return user of other tenant")
+
+	recorder = send("/")
+	assert.Equal(t, 200, recorder.Code, "Should list users")
+	assert.NotContains(t, recorder.Body.String(), user.Id, "Should not list users of other tenant")
+
+	_, err = userRepository.UpdateUserById(
+		tenancy.WithTenant(context.Background(), "globex"),
+		user.Id,
+		&models.User{FirstName: "Mallory"},
+	)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound, "Should not update user of other tenant")
+}
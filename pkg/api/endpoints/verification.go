@@ -0,0 +1,153 @@
+package endpoints
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/verification"
+)
+
+type VerificationHandler struct {
+	service     *verification.Service
+	userLimiter ratelimit.Limiter
+}
+
+func NewVerificationHandler(
+	service *verification.Service,
+	userLimiter ratelimit.Limiter,
+) *VerificationHandler {
+	return &VerificationHandler{
+		service:     service,
+		userLimiter: userLimiter,
+	}
+}
+
+func (handler *VerificationHandler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *VerificationHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/users/:id/verify-email/send",
+			Handler:     handler.SendVerificationEmail,
+			OperationId: "sendVerificationEmail",
+			Summary:     "Send an email verification link to a user",
+			Tags:        []string{usersTag},
+			URI:         schemas.UserURI{},
+			Responses: map[int]any{
+				http.StatusAccepted:            nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusConflict:            models.ErrorMessage{},
+				http.StatusTooManyRequests:     models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/verify-email",
+			Handler:     handler.VerifyEmail,
+			OperationId: "verifyEmail",
+			Summary:     "Verify an email address with a token",
+			Tags:        []string{usersTag},
+			Query:       schemas.VerifyEmailQuery{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.VerifyEmailResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *VerificationHandler) SendVerificationEmail(ctx *gin.Context) {
+	var userUri schemas.UserURI
+	err := ctx.BindUri(&userUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return
+	}
+	id := userUri.Id
+
+	_, ok := authorizeUser(ctx, id)
+	if !ok {
+		return
+	}
+	if !allowRequest(ctx, handler.userLimiter, id) {
+		return
+	}
+
+	err = handler.service.Send(ctx.Request.Context(), id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no user with id %q exists", id),
+		)
+		return
+	}
+	if errors.Is(err, verification.ErrAlreadyVerified) {
+		ctx.AbortWithStatusJSON(http.StatusConflict, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error sending verification email: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error sending verification email"),
+		)
+		return
+	}
+
+	ctx.Status(http.StatusAccepted)
+}
+
+func (handler *VerificationHandler) VerifyEmail(ctx *gin.Context) {
+	var verifyQuery schemas.VerifyEmailQuery
+	err := ctx.ShouldBindQuery(&verifyQuery)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting token"),
+		)
+		return
+	}
+
+	token, err := handler.service.Verify(ctx.Request.Context(), verifyQuery.Token)
+	if errors.Is(err, repositories.ErrVerificationTokenInvalid) {
+		ctx.AbortWithStatusJSON(http.StatusBadRequest, models.NewErrorMessage(err.Error()))
+		return
+	}
+	if err != nil {
+		log.Printf("error verifying email: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error verifying email"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, schemas.VerifyEmailResponse{
+		UserId:        token.UserId,
+		Email:         token.Email,
+		EmailVerified: true,
+	})
+}
@@ -0,0 +1,78 @@
+package endpoints
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/verification"
+)
+
+func TestVerificationHandler_SendVerificationEmail(t *testing.T) {
+	service := verification.NewService(
+		repositories.NewMemoryUserRepository(),
+		repositories.NewSQLVerificationRepository(nil),
+		mail.NewMemoryMailer(),
+		"https://example.com/verify-email",
+		time.Hour,
+	)
+	handler := NewVerificationHandler(service, ratelimit.NewMemoryLimiter(1, time.Hour))
+
+	testCases := []struct {
+		principal    *auth.Principal
+		expectedCode int
+		reason       string
+	}{
+		{
+			expectedCode: 401,
+			reason:       "Should require authentication",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc124"},
+			expectedCode: 403,
+			reason:       "Should not send to other users",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc123"},
+			expectedCode: 404,
+			reason:       "Should send to own user",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc123"},
+			expectedCode: 429,
+			reason:       "Should limit sends per user",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			router := gin.Default()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			handler.Register(router.Group(""))
+
+			request, err := http.NewRequest("POST", "/users/abc123/verify-email/send", nil)
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+		})
+	}
+}
@@ -0,0 +1,533 @@
+package endpoints
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+	"github.com/johannaojeling/go-rest-api/pkg/webhooks"
+)
+
+const webhooksTag = "webhooks"
+
+type WebhooksHandler struct {
+	webhookRepository repositories.WebhookRepository
+}
+
+func NewWebhooksHandler(webhookRepository repositories.WebhookRepository) *WebhooksHandler {
+	return &WebhooksHandler{
+		webhookRepository: webhookRepository,
+	}
+}
+
+func (handler *WebhooksHandler) Register(routerGroup *gin.RouterGroup) {
+	routerGroup.Use(requireAdmin)
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *WebhooksHandler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "/",
+			Handler:     handler.CreateWebhook,
+			OperationId: "createWebhook",
+			Summary:     "Register a webhook",
+			Tags:        []string{webhooksTag},
+			Body:        schemas.WebhookRequest{},
+			Responses: map[int]any{
+				http.StatusCreated:             schemas.WebhookResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/",
+			Handler:     handler.GetAllWebhooks,
+			OperationId: "listWebhooks",
+			Summary:     "List webhooks",
+			Tags:        []string{webhooksTag},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.WebhookResponse{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id",
+			Handler:     handler.GetWebhook,
+			OperationId: "getWebhook",
+			Summary:     "Get a webhook",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.WebhookURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.WebhookResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPut,
+			Path:        "/:id",
+			Handler:     handler.UpdateWebhook,
+			OperationId: "updateWebhook",
+			Summary:     "Update a webhook",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.WebhookURI{},
+			Body:        schemas.WebhookRequest{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.WebhookResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodDelete,
+			Path:        "/:id",
+			Handler:     handler.DeleteWebhook,
+			OperationId: "deleteWebhook",
+			Summary:     "Delete a webhook and its delivery log",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.WebhookURI{},
+			Responses: map[int]any{
+				http.StatusNoContent:           nil,
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/deliveries",
+			Handler:     handler.GetDeliveries,
+			OperationId: "listWebhookDeliveries",
+			Summary:     "List deliveries of a webhook",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.WebhookURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  []schemas.DeliveryResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "/:id/deliveries/:delivery_id",
+			Handler:     handler.GetDelivery,
+			OperationId: "getWebhookDelivery",
+			Summary:     "Get a delivery with its attempts",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.DeliveryURI{},
+			Responses: map[int]any{
+				http.StatusOK:                  schemas.DeliveryDetailResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+		{
+			Method:      http.MethodPost,
+			Path:        "/:id/deliveries/:delivery_id/replay",
+			Handler:     handler.ReplayDelivery,
+			OperationId: "replayWebhookDelivery",
+			Summary:     "Schedule a delivery to be sent again",
+			Tags:        []string{webhooksTag},
+			URI:         schemas.DeliveryURI{},
+			Responses: map[int]any{
+				http.StatusAccepted:            schemas.DeliveryResponse{},
+				http.StatusBadRequest:          models.ValidationErrorMessage{},
+				http.StatusNotFound:            models.ErrorMessage{},
+				http.StatusUnauthorized:        models.ErrorMessage{},
+				http.StatusForbidden:           models.ErrorMessage{},
+				http.StatusInternalServerError: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *WebhooksHandler) CreateWebhook(ctx *gin.Context) {
+	var webhookRequest schemas.WebhookRequest
+	if !bindWebhookRequest(ctx, &webhookRequest) {
+		return
+	}
+
+	webhook := webhookRequestToWebhookModel(webhookRequest)
+	webhook.TenantId = webhookTenant(ctx)
+	err := handler.webhookRepository.CreateWebhook(webhook)
+	if err != nil {
+		log.Printf("error creating webhook: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error creating webhook"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, webhookModelToWebhookResponse(webhook))
+}
+
+func (handler *WebhooksHandler) GetAllWebhooks(ctx *gin.Context) {
+	webhooks, err := handler.webhookRepository.GetAllWebhooks()
+	if err != nil {
+		log.Printf("error getting webhooks: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving webhooks"),
+		)
+		return
+	}
+
+	tenantId := webhookTenant(ctx)
+	webhookResponseList := make([]schemas.WebhookResponse, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		if webhook.TenantId == tenantId {
+			webhookResponseList = append(
+				webhookResponseList,
+				webhookModelToWebhookResponse(webhook),
+			)
+		}
+	}
+
+	ctx.JSON(http.StatusOK, webhookResponseList)
+}
+
+func (handler *WebhooksHandler) GetWebhook(ctx *gin.Context) {
+	webhook, ok := handler.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookModelToWebhookResponse(webhook))
+}
+
+func (handler *WebhooksHandler) UpdateWebhook(ctx *gin.Context) {
+	webhook, ok := handler.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+	id := webhook.Id
+
+	var webhookRequest schemas.WebhookRequest
+	if !bindWebhookRequest(ctx, &webhookRequest) {
+		return
+	}
+
+	updates := webhookRequestToWebhookModel(webhookRequest)
+	webhook, err := handler.webhookRepository.UpdateWebhookById(id, updates)
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		log.Printf("webhook not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no webhook with id %q exists", id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error updating webhook: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error updating webhook"),
+		)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, webhookModelToWebhookResponse(webhook))
+}
+
+func (handler *WebhooksHandler) DeleteWebhook(ctx *gin.Context) {
+	webhook, ok := handler.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+	id := webhook.Id
+
+	err := handler.webhookRepository.DeleteWebhookById(id)
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		log.Printf("webhook not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no webhook with id %q exists", id),
+		)
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting webhook: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error deleting webhook"),
+		)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *WebhooksHandler) GetDeliveries(ctx *gin.Context) {
+	webhook, ok := handler.bindWebhook(ctx)
+	if !ok {
+		return
+	}
+
+	deliveries, err := handler.webhookRepository.GetDeliveriesByWebhookId(webhook.Id)
+	if err != nil {
+		log.Printf("error getting deliveries: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving deliveries"),
+		)
+		return
+	}
+
+	deliveryResponseList := make([]schemas.DeliveryResponse, len(deliveries))
+	for i, delivery := range deliveries {
+		deliveryResponseList[i] = deliveryModelToDeliveryResponse(delivery)
+	}
+
+	ctx.JSON(http.StatusOK, deliveryResponseList)
+}
+
+func (handler *WebhooksHandler) GetDelivery(ctx *gin.Context) {
+	var deliveryUri schemas.DeliveryURI
+	err := ctx.BindUri(&deliveryUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id and delivery_id"),
+		)
+		return
+	}
+
+	_, ok := handler.findWebhook(ctx, deliveryUri.Id)
+	if !ok {
+		return
+	}
+
+	delivery, err := handler.webhookRepository.GetDeliveryById(
+		deliveryUri.Id,
+		deliveryUri.DeliveryId,
+	)
+	if !handleDeliveryError(ctx, deliveryUri, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusOK, deliveryModelToDeliveryDetailResponse(delivery))
+}
+
+func (handler *WebhooksHandler) ReplayDelivery(ctx *gin.Context) {
+	var deliveryUri schemas.DeliveryURI
+	err := ctx.BindUri(&deliveryUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id and delivery_id"),
+		)
+		return
+	}
+
+	_, ok := handler.findWebhook(ctx, deliveryUri.Id)
+	if !ok {
+		return
+	}
+
+	delivery, err := handler.webhookRepository.ReplayDelivery(
+		deliveryUri.Id,
+		deliveryUri.DeliveryId,
+	)
+	if !handleDeliveryError(ctx, deliveryUri, err) {
+		return
+	}
+
+	ctx.JSON(http.StatusAccepted, deliveryModelToDeliveryResponse(delivery))
+}
+
+func (handler *WebhooksHandler) bindWebhook(ctx *gin.Context) (*models.Webhook, bool) {
+	var webhookUri schemas.WebhookURI
+	err := ctx.BindUri(&webhookUri)
+	if err != nil {
+		log.Printf("invalid uri: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid uri, expecting id"),
+		)
+		return nil, false
+	}
+	return handler.findWebhook(ctx, webhookUri.Id)
+}
+
+// findWebhook gets the webhook with id, answering 404 for webhooks of other
+// tenants.
+func (handler *WebhooksHandler) findWebhook(ctx *gin.Context, id string) (*models.Webhook, bool) {
+	webhook, err := handler.webhookRepository.GetWebhookById(id)
+	if err == nil && webhook.TenantId != webhookTenant(ctx) {
+		err = repositories.ErrWebhookNotFound
+	}
+	if errors.Is(err, repositories.ErrWebhookNotFound) {
+		log.Printf("webhook not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage("no webhook with id %q exists", id),
+		)
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("error getting webhook: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving webhook"),
+		)
+		return nil, false
+	}
+	return webhook, true
+}
+
+// webhookTenant returns the tenant that the webhooks of a request are
+// registered under.
+func webhookTenant(ctx *gin.Context) string {
+	tenantId, ok := tenancy.TenantFromContext(ctx.Request.Context())
+	if !ok {
+		return tenancy.DefaultTenant
+	}
+	return tenantId
+}
+
+func bindWebhookRequest(ctx *gin.Context, webhookRequest *schemas.WebhookRequest) bool {
+	err := ctx.ShouldBindJSON(webhookRequest)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return false
+	}
+
+	err = webhooks.ValidateURL(webhookRequest.URL)
+	if err != nil {
+		log.Printf("invalid webhook url: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("url must be a public http or https url"),
+		)
+		return false
+	}
+	return true
+}
+
+func handleDeliveryError(ctx *gin.Context, deliveryUri schemas.DeliveryURI, err error) bool {
+	if errors.Is(err, repositories.ErrDeliveryNotFound) {
+		log.Printf("delivery not found: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusNotFound,
+			models.NewErrorMessage(
+				"no delivery with id %d exists for webhook %q",
+				deliveryUri.DeliveryId,
+				deliveryUri.Id,
+			),
+		)
+		return false
+	}
+	if err != nil {
+		log.Printf("error getting delivery: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusInternalServerError,
+			models.NewErrorMessage("error retrieving delivery"),
+		)
+		return false
+	}
+	return true
+}
+
+func webhookRequestToWebhookModel(webhookRequest schemas.WebhookRequest) *models.Webhook {
+	active := true
+	if webhookRequest.Active != nil {
+		active = *webhookRequest.Active
+	}
+	return &models.Webhook{
+		URL:    webhookRequest.URL,
+		Events: webhookRequest.Events,
+		Secret: webhookRequest.Secret,
+		Active: active,
+	}
+}
+
+func webhookModelToWebhookResponse(webhook *models.Webhook) schemas.WebhookResponse {
+	return schemas.WebhookResponse{
+		Id:        webhook.Id,
+		URL:       webhook.URL,
+		Events:    webhook.Events,
+		Active:    webhook.Active,
+		CreatedAt: webhook.CreatedAt,
+		UpdatedAt: webhook.UpdatedAt,
+	}
+}
+
+func deliveryModelToDeliveryResponse(delivery *models.WebhookDelivery) schemas.DeliveryResponse {
+	return schemas.DeliveryResponse{
+		Id:             delivery.Id,
+		WebhookId:      delivery.WebhookId,
+		EventId:        delivery.EventId,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+func deliveryModelToDeliveryDetailResponse(
+	delivery *models.WebhookDelivery,
+) schemas.DeliveryDetailResponse {
+	var payload map[string]any
+	if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+		log.Printf("error unmarshaling delivery payload: %v", err)
+	}
+
+	attempts := make([]schemas.AttemptResponse, len(delivery.AttemptLog))
+	for i, attempt := range delivery.AttemptLog {
+		attempts[i] = schemas.AttemptResponse{
+			Attempt:     attempt.Attempt,
+			StatusCode:  attempt.StatusCode,
+			Error:       attempt.Error,
+			DurationMs:  attempt.DurationMs,
+			AttemptedAt: attempt.AttemptedAt,
+		}
+	}
+
+	return schemas.DeliveryDetailResponse{
+		DeliveryResponse: deliveryModelToDeliveryResponse(delivery),
+		Payload:          payload,
+		AttemptLog:       attempts,
+	}
+}
@@ -0,0 +1,209 @@
+package endpoints
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func TestWebhooksHandler(t *testing.T) {
+	webhookRepository := repositories.NewMemoryWebhookRepository()
+	admin := &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}}
+
+	webhook := &models.Webhook{
+		URL:    "https://example.com/hook",
+		Events: []string{"user.created"},
+		Secret: "0123456789abcdef",
+		Active: true,
+	}
+	_ = webhookRepository.CreateWebhook(webhook)
+	_ = webhookRepository.CreateDeliveries([]*models.WebhookDelivery{
+		{
+			WebhookId: webhook.Id,
+			EventId:   1,
+			EventType: "user.created",
+			Payload:   []byte(`{"id":1}`),
+			Status:    models.DeliveryDead,
+		},
+	})
+
+	tenantAdmin := &auth.Principal{
+		Subject:  "ops",
+		TenantId: "acme",
+		Roles:    []string{auth.RoleAdmin},
+	}
+
+	testCases := []struct {
+		principal    *auth.Principal
+		tenantId     string
+		method       string
+		path         string
+		requestBody  map[string]interface{}
+		expectedCode int
+		reason       string
+	}{
+		{
+			principal: admin,
+			method:    "POST",
+			path:      "/webhooks/",
+			requestBody: map[string]interface{}{
+				"url":    "https://example.com/other",
+				"events": []string{"*"},
+				"secret": "0123456789abcdef",
+			},
+			expectedCode: 201,
+			reason:       "Should create webhook",
+		},
+		{
+			method:       "GET",
+			path:         "/webhooks/",
+			expectedCode: 401,
+			reason:       "Should require authentication",
+		},
+		{
+			principal:    &auth.Principal{Subject: "abc123"},
+			method:       "GET",
+			path:         "/webhooks/",
+			expectedCode: 403,
+			reason:       "Should require admin role",
+		},
+		{
+			principal: admin,
+			method:    "POST",
+			path:      "/webhooks/",
+			requestBody: map[string]interface{}{
+				"url":    "http://169.254.169.254/latest/meta-data",
+				"events": []string{"*"},
+				"secret": "0123456789abcdef",
+			},
+			expectedCode: 400,
+			reason:       "Should reject internal targets",
+		},
+		{
+			principal: admin,
+			method:    "PUT",
+			path:      "/webhooks/" + webhook.Id,
+			requestBody: map[string]interface{}{
+				"url":    "http://localhost:8080/hook",
+				"events": []string{"*"},
+				"secret": "0123456789abcdef",
+			},
+			expectedCode: 400,
+			reason:       "Should reject internal targets on update",
+		},
+		{
+			principal: admin,
+			method:    "POST",
+			path:      "/webhooks/",
+			requestBody: map[string]interface{}{
+				"url":    "https://example.com/other",
+				"events": []string{"user.renamed"},
+				"secret": "short",
+			},
+			expectedCode: 400,
+			reason:       "Should reject unknown events and short secrets",
+		},
+		{
+			principal:    admin,
+			method:       "GET",
+			path:         "/webhooks/unknown",
+			expectedCode: 404,
+			reason:       "Should return 404 for unknown webhook",
+		},
+		{
+			principal:    admin,
+			method:       "GET",
+			path:         "/webhooks/" + webhook.Id + "/deliveries",
+			expectedCode: 200,
+			reason:       "Should list deliveries",
+		},
+		{
+			principal:    admin,
+			method:       "POST",
+			path:         "/webhooks/" + webhook.Id + "/deliveries/1/replay",
+			expectedCode: 202,
+			reason:       "Should replay delivery",
+		},
+		{
+			principal:    admin,
+			method:       "POST",
+			path:         "/webhooks/" + webhook.Id + "/deliveries/2/replay",
+			expectedCode: 404,
+			reason:       "Should return 404 for unknown delivery",
+		},
+		{
+			principal:    tenantAdmin,
+			tenantId:     "acme",
+			method:       "GET",
+			path:         "/webhooks/" + webhook.Id,
+			expectedCode: 404,
+			reason:       "Should hide webhooks of other tenants",
+		},
+		{
+			principal:    tenantAdmin,
+			tenantId:     "acme",
+			method:       "DELETE",
+			path:         "/webhooks/" + webhook.Id,
+			expectedCode: 404,
+			reason:       "Should not delete webhooks of other tenants",
+		},
+		{
+			principal:    tenantAdmin,
+			tenantId:     "acme",
+			method:       "POST",
+			path:         "/webhooks/" + webhook.Id + "/deliveries/1/replay",
+			expectedCode: 404,
+			reason:       "Should not replay deliveries of other tenants",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			jsonBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("error marshaling request body to json %v", err)
+			}
+
+			request, err := http.NewRequest(tc.method, tc.path, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+
+			router := gin.Default()
+			if tc.principal != nil {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						auth.WithPrincipal(ctx.Request.Context(), tc.principal),
+					)
+				})
+			}
+			if tc.tenantId != "" {
+				router.Use(func(ctx *gin.Context) {
+					ctx.Request = ctx.Request.WithContext(
+						tenancy.WithTenant(ctx.Request.Context(), tc.tenantId),
+					)
+				})
+			}
+			NewWebhooksHandler(webhookRepository).Register(router.Group("/webhooks"))
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+		})
+	}
+
+	delivery, _ := webhookRepository.GetDeliveryById(webhook.Id, 1)
+	assert.Equal(t, models.DeliveryPending, delivery.Status, "Should reschedule replayed delivery")
+}
@@ -0,0 +1,231 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/database"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/ratelimit"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+// identify authenticates callers with a bearer token and puts them in the
+// request context. Requests without an Authorization header are anonymous.
+func identify(authenticator auth.Authenticator) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		header := ctx.GetHeader("Authorization")
+		if header == "" {
+			ctx.Next()
+			return
+		}
+
+		token, ok := auth.BearerToken(header)
+		if !ok {
+			ctx.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				models.NewErrorMessage("expecting bearer token"),
+			)
+			return
+		}
+
+		principal, err := authenticator.Authenticate(ctx.Request.Context(), token)
+		if err != nil {
+			log.Printf("error authenticating request: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusUnauthorized,
+				models.NewErrorMessage("invalid token"),
+			)
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(auth.WithPrincipal(ctx.Request.Context(), principal))
+		ctx.Next()
+	}
+}
+
+// signInPaths authenticate anonymous callers within the tenant they request,
+// so that users of other tenants than the default can log in. The links of
+// emails are not among them, since their token selects the tenant.
+var signInPaths = map[string]bool{
+	"/auth/login":          true,
+	"/auth/login/mfa":      true,
+	"/auth/password-reset": true,
+}
+
+// resolveTenant puts the tenant of the request in its context, rejecting
+// tenants the caller does not belong to and tenants that do not exist.
+func resolveTenant(resolver *tenancy.Resolver) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requested := ctx.GetHeader(tenancy.Header)
+		principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+
+		var tenantId string
+		var err error
+		if !ok && signInPaths[ctx.FullPath()] {
+			tenantId, err = resolver.ResolveSignIn(
+				ctx.Request.Context(),
+				requested,
+				ctx.Request.Host,
+			)
+		} else {
+			tenantId, err = resolver.Resolve(
+				ctx.Request.Context(),
+				principal,
+				requested,
+				ctx.Request.Host,
+			)
+		}
+		if errors.Is(err, tenancy.ErrTenantMismatch) {
+			ctx.AbortWithStatusJSON(
+				http.StatusForbidden,
+				models.NewErrorMessage("token is not valid for the requested tenant"),
+			)
+			return
+		}
+		if errors.Is(err, tenancy.ErrUnknownTenant) {
+			ctx.AbortWithStatusJSON(
+				http.StatusNotFound,
+				models.NewErrorMessage("unknown tenant"),
+			)
+			return
+		}
+		if err != nil {
+			log.Printf("error resolving tenant: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusInternalServerError,
+				models.NewErrorMessage("error resolving tenant"),
+			)
+			return
+		}
+
+		ctx.Request = ctx.Request.WithContext(tenancy.WithTenant(ctx.Request.Context(), tenantId))
+		ctx.Next()
+	}
+}
+
+// limitRate limits requests per caller, falling back to the client IP for
+// callers without a user or API key. Requests are allowed if the store fails.
+func limitRate(policy *ratelimit.Policy, key ratelimit.Key) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		principal, _ := auth.PrincipalFromContext(ctx.Request.Context())
+		var plans []string
+		if principal != nil && principal.Plan != "" {
+			plans = []string{principal.Plan}
+		}
+
+		route := ctx.Request.Method + " " + ctx.FullPath()
+		result, ok, err := policy.Allow(ctx.Request.Context(), route, plans, clientKey(ctx, key))
+		if err != nil {
+			log.Printf("error checking rate limit: %v", err)
+			ctx.Next()
+			return
+		}
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		reset := int(math.Ceil(result.ResetAfter.Seconds()))
+		ctx.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+		ctx.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		ctx.Header("RateLimit-Reset", strconv.Itoa(reset))
+		if !result.Allowed {
+			ctx.Header("Retry-After", strconv.Itoa(reset))
+			ctx.AbortWithStatusJSON(
+				http.StatusTooManyRequests,
+				models.NewErrorMessage("too many requests, retry in %d seconds", reset),
+			)
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// stickToPrimary sends the reads of callers that wrote within the window of
+// stickiness to the primary database, so that they read their own writes.
+func stickToPrimary(stickiness *database.Stickiness) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		key := clientKey(ctx, ratelimit.KeyUser)
+		if stickiness.Sticky(key) {
+			ctx.Request = ctx.Request.WithContext(database.WithPrimary(ctx.Request.Context()))
+		}
+
+		ctx.Next()
+
+		switch ctx.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		if ctx.Writer.Status() < http.StatusBadRequest {
+			stickiness.Wrote(key)
+		}
+	}
+}
+
+// cacheControl sets the Cache-Control policy of the route on successful and not
+// modified responses. Responses vary by caller and tenant.
+func cacheControl(policies map[string]string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		directives, ok := policies[ctx.Request.Method+" "+ctx.FullPath()]
+		if !ok {
+			ctx.Next()
+			return
+		}
+
+		ctx.Header("Vary", "Authorization, X-Tenant-ID")
+		ctx.Writer = &cacheControlWriter{ResponseWriter: ctx.Writer, directives: directives}
+		ctx.Next()
+	}
+}
+
+type cacheControlWriter struct {
+	gin.ResponseWriter
+	directives string
+}
+
+func (writer *cacheControlWriter) WriteHeader(code int) {
+	if code < http.StatusMultipleChoices || code == http.StatusNotModified {
+		writer.Header().Set("Cache-Control", writer.directives)
+	}
+	writer.ResponseWriter.WriteHeader(code)
+}
+
+func (writer *cacheControlWriter) Write(data []byte) (int, error) {
+	if !writer.Written() {
+		writer.WriteHeader(writer.Status())
+	}
+	return writer.ResponseWriter.Write(data)
+}
+
+// clientKey tells callers apart by key, falling back to the client IP.
+func clientKey(ctx *gin.Context, key ratelimit.Key) string {
+	switch key {
+	case ratelimit.KeyUser:
+		principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+		if ok {
+			return "user:" + principal.Subject
+		}
+	case ratelimit.KeyAPIKey:
+		// Access tokens are rotated, so callers with a session are told apart
+		// by user. API tokens are hashed so they are not kept in the store.
+		principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+		if ok && principal.SessionId != "" {
+			return "user:" + principal.Subject
+		}
+		token, ok := auth.BearerToken(ctx.GetHeader("Authorization"))
+		if ok {
+			hash := sha256.Sum256([]byte(token))
+			return "key:" + hex.EncodeToString(hash[:])
+		}
+	}
+	return "ip:" + ctx.ClientIP()
+}
@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+)
+
+const (
+	RequestIdHeader = "X-Request-ID"
+	anonymousActor  = "anonymous"
+)
+
+type Metadata struct {
+	RequestId string
+	IP        string
+}
+
+type metadataKey struct{}
+
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, metadata)
+}
+
+func MetadataFromContext(ctx context.Context) (Metadata, bool) {
+	metadata, ok := ctx.Value(metadataKey{}).(Metadata)
+	return metadata, ok
+}
+
+func ActorFromContext(ctx context.Context) string {
+	principal, ok := auth.PrincipalFromContext(ctx)
+	if !ok {
+		return anonymousActor
+	}
+	return principal.Subject
+}
+
+// Middleware stores the request id and client IP of every request for the
+// audit log. The IP is only taken from forwarding headers of the proxies the
+// engine trusts, so the engine must not trust every proxy.
+func Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		requestId := ctx.GetHeader(RequestIdHeader)
+		if requestId == "" {
+			requestId = NewRequestId()
+		}
+		ctx.Header(RequestIdHeader, requestId)
+
+		metadata := Metadata{RequestId: requestId, IP: ctx.ClientIP()}
+		ctx.Request = ctx.Request.WithContext(WithMetadata(ctx.Request.Context(), metadata))
+		ctx.Next()
+	}
+}
+
+func NewRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,57 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+type Writer struct{}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+func (writer *Writer) OnUserChange(tx *gorm.DB, change *repositories.UserChange) error {
+	ctx := tx.Statement.Context
+	metadata, _ := MetadataFromContext(ctx)
+
+	before, err := snapshot(change.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(change.After)
+	if err != nil {
+		return err
+	}
+
+	entry := &models.AuditEntry{
+		Action:    string(change.Operation),
+		UserId:    change.UserId(),
+		Actor:     ActorFromContext(ctx),
+		RequestId: metadata.RequestId,
+		IP:        metadata.IP,
+		Before:    before,
+		After:     after,
+	}
+	err = tx.Create(entry).Error
+	if err != nil {
+		return fmt.Errorf("error writing audit entry: %v", err)
+	}
+	return nil
+}
+
+func snapshot(user *models.User) (models.NullJSON, error) {
+	if user == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(user)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling audit snapshot: %v", err)
+	}
+	return data, nil
+}
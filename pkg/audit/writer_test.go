@@ -0,0 +1,64 @@
+package audit
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestWriter_SameTransaction(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	userRepository := repositories.NewSQLUserRepository(db, NewWriter())
+
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "admin"})
+	ctx = WithMetadata(ctx, Metadata{RequestId: "req-1", IP: "10.0.0.1"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "email"}).
+			AddRow("abc123", "Jane", "Doe", "jane.doe@mail.com"))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM "users"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "audit_log"`)).
+		WithArgs(
+			"delete",
+			"abc123",
+			"admin",
+			"req-1",
+			"10.0.0.1",
+			sqlmock.AnyArg(),
+			nil,
+			sqlmock.AnyArg(),
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err = userRepository.DeleteUserById(ctx, "abc123")
+
+	assert.NoError(t, err, "Should delete user")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should write audit entry in user transaction")
+}
+
+func TestActorFromContext(t *testing.T) {
+	ctx := context.Background()
+	assert.Equal(t, "anonymous", ActorFromContext(ctx), "Should default to anonymous")
+
+	ctx = auth.WithPrincipal(ctx, &auth.Principal{Subject: "admin"})
+	assert.Equal(t, "admin", ActorFromContext(ctx), "Should use principal subject")
+}
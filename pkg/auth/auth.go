@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"strings"
+)
+
+const (
+	RoleAdmin = "admin"
+	RoleSCIM  = "scim"
+)
+
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+type Principal struct {
+	Subject   string
+	Roles     []string
+	SessionId string
+	TenantId  string
+	Plan      string
+}
+
+func (principal *Principal) HasRole(role string) bool {
+	for _, candidate := range principal.Roles {
+		if candidate == role {
+			return true
+		}
+	}
+	return false
+}
+
+type Authenticator interface {
+	Authenticate(ctx context.Context, token string) (*Principal, error)
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+type StaticTokenAuthenticator struct {
+	principals map[string]*Principal
+}
+
+func NewStaticTokenAuthenticator(principals map[string]*Principal) *StaticTokenAuthenticator {
+	return &StaticTokenAuthenticator{principals: principals}
+}
+
+func (authenticator *StaticTokenAuthenticator) Authenticate(
+	_ context.Context,
+	token string,
+) (*Principal, error) {
+	var match *Principal
+	for candidate, principal := range authenticator.principals {
+		if subtle.ConstantTimeCompare([]byte(candidate), []byte(token)) == 1 {
+			match = principal
+		}
+	}
+	if match == nil {
+		return nil, ErrUnauthenticated
+	}
+	return match, nil
+}
+
+func ParseStaticTokens(value string) (map[string]*Principal, error) {
+	principals := map[string]*Principal{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		subject, rest, ok := strings.Cut(entry, ":")
+		token, rest, _ := strings.Cut(rest, ":")
+		roles, plan, _ := strings.Cut(rest, ":")
+		if !ok || subject == "" || token == "" {
+			return nil, errors.New(
+				"expecting tokens as comma separated subject:token[:role+role[:plan]] entries",
+			)
+		}
+		principal := &Principal{Subject: subject, Plan: plan}
+		if roles != "" {
+			principal.Roles = strings.Split(roles, "+")
+		}
+		principals[token] = principal
+	}
+	return principals, nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Denylist holds ids of sessions whose access tokens must be rejected before
+// they expire.
+type Denylist interface {
+	Deny(ctx context.Context, id string, ttl time.Duration) error
+	Denied(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryDenylist is a Denylist local to the process. Entries are dropped once
+// their ttl has passed.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	now     func() time.Time
+}
+
+func NewMemoryDenylist() *MemoryDenylist {
+	return &MemoryDenylist{
+		entries: map[string]time.Time{},
+		now:     time.Now,
+	}
+}
+
+func (denylist *MemoryDenylist) Deny(_ context.Context, id string, ttl time.Duration) error {
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+
+	now := denylist.now()
+	for candidate, expiresAt := range denylist.entries {
+		if !expiresAt.After(now) {
+			delete(denylist.entries, candidate)
+		}
+	}
+	denylist.entries[id] = now.Add(ttl)
+	return nil
+}
+
+func (denylist *MemoryDenylist) Denied(_ context.Context, id string) (bool, error) {
+	denylist.mu.Lock()
+	defer denylist.mu.Unlock()
+
+	expiresAt, ok := denylist.entries[id]
+	return ok && expiresAt.After(denylist.now()), nil
+}
+
+// RedisDenylist is a Denylist in Redis or a Redis compatible server, shared by
+// a fleet of instances. Entries expire with their ttl.
+type RedisDenylist struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewRedisDenylist(client redis.Cmdable, prefix string) *RedisDenylist {
+	return &RedisDenylist{client: client, prefix: prefix}
+}
+
+func (denylist *RedisDenylist) Deny(ctx context.Context, id string, ttl time.Duration) error {
+	return denylist.client.Set(ctx, denylist.prefix+id, 1, ttl).Err()
+}
+
+func (denylist *RedisDenylist) Denied(ctx context.Context, id string) (bool, error) {
+	err := denylist.client.Get(ctx, denylist.prefix+id).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
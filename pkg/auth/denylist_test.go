@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisDenylist(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	ctx := context.Background()
+
+	denylist := NewRedisDenylist(client, "denylist:")
+	other := NewRedisDenylist(client, "denylist:")
+
+	err := denylist.Deny(ctx, "def456", time.Minute)
+	assert.NoError(t, err, "Should deny session")
+
+	denied, err := other.Denied(ctx, "def456")
+	assert.NoError(t, err, "Should check denylist")
+	assert.True(t, denied, "Should share denied sessions between instances")
+
+	denied, _ = other.Denied(ctx, "def457")
+	assert.False(t, denied, "Should not deny other sessions")
+
+	server.FastForward(time.Minute)
+	denied, _ = other.Denied(ctx, "def456")
+	assert.False(t, denied, "Should expire entries after ttl")
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+)
+
+const (
+	tokenTypeAccess = "access"
+	tokenTypeMFA    = "mfa"
+	mfaTokenTTL     = 5 * time.Minute
+)
+
+type tokenClaims struct {
+	jwt.RegisteredClaims
+	Type      string   `json:"typ"`
+	SessionId string   `json:"sid,omitempty"`
+	TenantId  string   `json:"tid,omitempty"`
+	Roles     []string `json:"roles,omitempty"`
+	Plan      string   `json:"plan,omitempty"`
+}
+
+type TokenIssuer struct {
+	secret    []byte
+	issuer    string
+	accessTTL time.Duration
+	denylist  Denylist
+	now       func() time.Time
+}
+
+func NewTokenIssuer(secret []byte, issuer string, accessTTL time.Duration) *TokenIssuer {
+	return &TokenIssuer{
+		secret:    secret,
+		issuer:    issuer,
+		accessTTL: accessTTL,
+		now:       time.Now,
+	}
+}
+
+// WithDenylist rejects access tokens of sessions on the denylist.
+func (issuer *TokenIssuer) WithDenylist(denylist Denylist) *TokenIssuer {
+	issuer.denylist = denylist
+	return issuer
+}
+
+func (issuer *TokenIssuer) AccessTTL() time.Duration {
+	return issuer.accessTTL
+}
+
+func (issuer *TokenIssuer) IssueAccessToken(
+	subject string,
+	sessionId string,
+	tenantId string,
+	roles []string,
+	plan string,
+) (string, error) {
+	return issuer.sign(
+		subject,
+		sessionId,
+		tenantId,
+		roles,
+		plan,
+		tokenTypeAccess,
+		issuer.accessTTL,
+	)
+}
+
+// IssueMFAToken issues a short lived token proving that the password of the
+// subject was verified, to be exchanged for tokens with a second factor.
+func (issuer *TokenIssuer) IssueMFAToken(
+	subject string,
+	tenantId string,
+) (string, time.Duration, error) {
+	id, _, err := securetoken.Generate()
+	if err != nil {
+		return "", 0, err
+	}
+	token, err := issuer.sign(subject, id, tenantId, nil, "", tokenTypeMFA, mfaTokenTTL)
+	return token, mfaTokenTTL, err
+}
+
+// ParseMFAToken returns the principal of an MFA token with the id of the
+// token as its session id, and rejects tokens revoked with RevokeMFAToken.
+func (issuer *TokenIssuer) ParseMFAToken(ctx context.Context, token string) (*Principal, error) {
+	claims, err := issuer.parse(token, tokenTypeMFA)
+	if err != nil {
+		return nil, err
+	}
+	err = issuer.checkDenylist(ctx, claims.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		Subject:   claims.Subject,
+		SessionId: claims.SessionId,
+		TenantId:  claims.TenantId,
+	}, nil
+}
+
+// RevokeMFAToken rejects the MFA token of principal until it expires. Without
+// a denylist it does nothing.
+func (issuer *TokenIssuer) RevokeMFAToken(ctx context.Context, principal *Principal) error {
+	if issuer.denylist == nil || principal.SessionId == "" {
+		return nil
+	}
+	return issuer.denylist.Deny(ctx, principal.SessionId, mfaTokenTTL)
+}
+
+func (issuer *TokenIssuer) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	claims, err := issuer.parse(token, tokenTypeAccess)
+	if err != nil {
+		return nil, err
+	}
+	err = issuer.checkDenylist(ctx, claims.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return &Principal{
+		Subject:   claims.Subject,
+		Roles:     claims.Roles,
+		SessionId: claims.SessionId,
+		TenantId:  claims.TenantId,
+		Plan:      claims.Plan,
+	}, nil
+}
+
+func (issuer *TokenIssuer) checkDenylist(ctx context.Context, id string) error {
+	if issuer.denylist == nil || id == "" {
+		return nil
+	}
+	denied, err := issuer.denylist.Denied(ctx, id)
+	if err != nil {
+		return fmt.Errorf("error checking denylist: %v", err)
+	}
+	if denied {
+		return fmt.Errorf("%w: session was revoked", ErrUnauthenticated)
+	}
+	return nil
+}
+
+func (issuer *TokenIssuer) sign(
+	subject string,
+	sessionId string,
+	tenantId string,
+	roles []string,
+	plan string,
+	tokenType string,
+	ttl time.Duration,
+) (string, error) {
+	now := issuer.now()
+	claims := tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer.issuer,
+			Subject:   subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+		Type:      tokenType,
+		SessionId: sessionId,
+		TenantId:  tenantId,
+		Roles:     roles,
+		Plan:      plan,
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(issuer.secret)
+	if err != nil {
+		return "", fmt.Errorf("error signing token: %v", err)
+	}
+	return token, nil
+}
+
+func (issuer *TokenIssuer) parse(token string, tokenType string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := parser.ParseWithClaims(token, claims, func(*jwt.Token) (any, error) {
+		return issuer.secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	if claims.Type != tokenType || !claims.VerifyIssuer(issuer.issuer, true) {
+		return nil, fmt.Errorf("%w: expecting %s token", ErrUnauthenticated, tokenType)
+	}
+	return claims, nil
+}
+
+type ChainAuthenticator []Authenticator
+
+func (authenticators ChainAuthenticator) Authenticate(
+	ctx context.Context,
+	token string,
+) (*Principal, error) {
+	for _, authenticator := range authenticators {
+		principal, err := authenticator.Authenticate(ctx, token)
+		if err == nil {
+			return principal, nil
+		}
+		if !errors.Is(err, ErrUnauthenticated) {
+			return nil, err
+		}
+	}
+	return nil, ErrUnauthenticated
+}
@@ -0,0 +1,64 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	now := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+	lru := NewLRU(2)
+	lru.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_ = lru.Set(ctx, "a", []byte("1"), time.Minute)
+	_ = lru.Set(ctx, "b", []byte("2"), time.Second)
+	_, _, _ = lru.Get(ctx, "a")
+	_ = lru.Set(ctx, "c", []byte("3"), time.Minute)
+
+	_, ok, _ := lru.Get(ctx, "b")
+	assert.False(t, ok, "Should evict least recently used value")
+	value, ok, _ := lru.Get(ctx, "a")
+	assert.True(t, ok, "Should keep recently used value")
+	assert.Equal(t, []byte("1"), value, "Should return value")
+
+	now = now.Add(time.Minute)
+	_, ok, _ = lru.Get(ctx, "c")
+	assert.False(t, ok, "Should expire value after ttl")
+
+	_ = lru.Delete(ctx, "a")
+	_, ok, _ = lru.Get(ctx, "a")
+	assert.False(t, ok, "Should delete value")
+}
+
+func TestRedis(t *testing.T) {
+	server := miniredis.RunT(t)
+	cache := NewRedis(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	ctx := context.Background()
+
+	_, ok, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.False(t, ok, "Should miss unknown key")
+
+	err = cache.Set(ctx, "a", []byte("1"), time.Minute)
+	assert.NoError(t, err)
+	value, ok, _ := cache.Get(ctx, "a")
+	assert.True(t, ok, "Should hit key")
+	assert.Equal(t, []byte("1"), value, "Should return value")
+	assert.True(t, server.Exists("test:a"), "Should prefix keys")
+
+	server.FastForward(time.Minute)
+	_, ok, _ = cache.Get(ctx, "a")
+	assert.False(t, ok, "Should expire value after ttl")
+
+	_ = cache.Set(ctx, "b", []byte("2"), time.Minute)
+	err = cache.Delete(ctx, "a", "b")
+	assert.NoError(t, err)
+	_, ok, _ = cache.Get(ctx, "b")
+	assert.False(t, ok, "Should delete value")
+}
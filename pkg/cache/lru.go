@@ -0,0 +1,87 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+type entry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// LRU is an in-process Cache that evicts the least recently used value once
+// it holds capacity values.
+type LRU struct {
+	capacity int
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	order    *list.List
+	now      func() time.Time
+}
+
+func NewLRU(capacity int) *LRU {
+	return &LRU{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+func (lru *LRU) Get(_ context.Context, key string) ([]byte, bool, error) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	element, ok := lru.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	current := element.Value.(*entry)
+	if !lru.now().Before(current.expiresAt) {
+		lru.remove(element)
+		return nil, false, nil
+	}
+	lru.order.MoveToFront(element)
+	return current.value, true, nil
+}
+
+func (lru *LRU) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	expiresAt := lru.now().Add(ttl)
+	if element, ok := lru.entries[key]; ok {
+		current := element.Value.(*entry)
+		current.value = value
+		current.expiresAt = expiresAt
+		lru.order.MoveToFront(element)
+		return nil
+	}
+
+	lru.entries[key] = lru.order.PushFront(&entry{key: key, value: value, expiresAt: expiresAt})
+	if lru.order.Len() > lru.capacity {
+		lru.remove(lru.order.Back())
+	}
+	return nil
+}
+
+func (lru *LRU) Delete(_ context.Context, keys ...string) error {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	for _, key := range keys {
+		if element, ok := lru.entries[key]; ok {
+			lru.remove(element)
+		}
+	}
+	return nil
+}
+
+func (lru *LRU) remove(element *list.Element) {
+	lru.order.Remove(element)
+	delete(lru.entries, element.Value.(*entry).key)
+}
@@ -0,0 +1,46 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// Redis is a Cache in Redis or a Redis compatible server, shared by a fleet of
+// instances.
+type Redis struct {
+	client redis.Cmdable
+	prefix string
+}
+
+func NewRedis(client redis.Cmdable, prefix string) *Redis {
+	return &Redis{client: client, prefix: prefix}
+}
+
+func (cache *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, err := cache.client.Get(ctx, cache.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (cache *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return cache.client.Set(ctx, cache.prefix+key, value, ttl).Err()
+}
+
+func (cache *Redis) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, key := range keys {
+		prefixed[i] = cache.prefix + key
+	}
+	return cache.client.Del(ctx, prefixed...).Err()
+}
@@ -0,0 +1,195 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type Client struct {
+	baseURL     *url.URL
+	httpClient  *http.Client
+	timeout     time.Duration
+	retryPolicy RetryPolicy
+	auth        AuthFunc
+}
+
+func NewClient(baseURL string, options ...Option) (*Client, error) {
+	parsedURL, err := url.Parse(strings.TrimSuffix(baseURL, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing base url: %v", err)
+	}
+
+	client := &Client{
+		baseURL:     parsedURL,
+		httpClient:  http.DefaultClient,
+		timeout:     defaultTimeout,
+		retryPolicy: DefaultRetryPolicy,
+	}
+	for _, option := range options {
+		option(client)
+	}
+	return client, nil
+}
+
+func (client *Client) do(
+	ctx context.Context,
+	method string,
+	path string,
+	query url.Values,
+	requestBody any,
+	responseBody any,
+) (int, error) {
+	var payload []byte
+	if requestBody != nil {
+		var err error
+		payload, err = json.Marshal(requestBody)
+		if err != nil {
+			return 0, fmt.Errorf("error marshaling request body: %v", err)
+		}
+	}
+
+	endpoint := *client.baseURL
+	endpoint.Path += path
+	endpoint.RawQuery = query.Encode()
+
+	maxAttempts := client.retryPolicy.MaxAttempts
+	if maxAttempts < 1 || !isIdempotent(method) {
+		maxAttempts = 1
+	}
+
+	var statusCode int
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retryAfter time.Duration
+		statusCode, retryAfter, err = client.attempt(
+			ctx,
+			method,
+			endpoint.String(),
+			payload,
+			responseBody,
+		)
+		if attempt >= maxAttempts || !isRetryable(ctx, statusCode, err) {
+			return statusCode, err
+		}
+
+		backoff := client.backoff(attempt)
+		if retryAfter > backoff {
+			backoff = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return statusCode, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+func (client *Client) attempt(
+	ctx context.Context,
+	method string,
+	endpoint string,
+	payload []byte,
+	responseBody any,
+) (int, time.Duration, error) {
+	if client.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.timeout)
+		defer cancel()
+	}
+
+	var body io.Reader
+	if payload != nil {
+		body = bytes.NewReader(payload)
+	}
+	request, err := http.NewRequestWithContext(ctx, method, endpoint, body)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error creating request: %v", err)
+	}
+	request.Header.Set("Accept", "application/json")
+	if payload != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	if client.auth != nil {
+		if err := client.auth(request); err != nil {
+			return 0, 0, fmt.Errorf("error authenticating request: %v", err)
+		}
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error sending request: %w", err)
+	}
+	defer response.Body.Close()
+
+	retryAfter := parseRetryAfter(response.Header.Get("Retry-After"))
+	if response.StatusCode >= http.StatusBadRequest {
+		var errorBody models.ValidationErrorMessage
+		_ = json.NewDecoder(response.Body).Decode(&errorBody)
+		return response.StatusCode, retryAfter, newAPIError(response.StatusCode, errorBody)
+	}
+
+	if responseBody != nil && response.StatusCode != http.StatusNoContent {
+		if err := json.NewDecoder(response.Body).Decode(responseBody); err != nil {
+			return response.StatusCode, 0, fmt.Errorf("error decoding response body: %v", err)
+		}
+	}
+	return response.StatusCode, 0, nil
+}
+
+func (client *Client) backoff(attempt int) time.Duration {
+	backoff := client.retryPolicy.MinBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > client.retryPolicy.MaxBackoff {
+		backoff = client.retryPolicy.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(backoff)))
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryable(ctx context.Context, statusCode int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if statusCode == 0 {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+	return statusCode == http.StatusTooManyRequests || statusCode >= http.StatusInternalServerError
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return time.Until(date)
+	}
+	return 0
+}
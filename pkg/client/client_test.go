@@ -0,0 +1,243 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/johannaojeling/go-rest-api/pkg/api"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+type Suite struct {
+	suite.Suite
+	server *httptest.Server
+	client *Client
+}
+
+func (s *Suite) SetupTest() {
+	app := api.NewApp(
+		repositories.NewMemoryUserRepository(),
+		api.WithResponseValidation(func(_ *gin.Context, err *openapi.ResponseValidationError) {
+			s.T().Errorf("response does not match specification: %v", err)
+		}),
+	)
+	s.server = httptest.NewServer(app)
+
+	client, err := NewClient(s.server.URL)
+	if err != nil {
+		s.T().Fatalf("error creating client: %v", err)
+	}
+	s.client = client
+}
+
+func (s *Suite) TearDownTest() {
+	s.server.Close()
+}
+
+func TestClient(t *testing.T) {
+	suite.Run(t, &Suite{})
+}
+
+func (s *Suite) TestClient_UserLifecycle() {
+	ctx := context.Background()
+	request := schemas.UserRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane.doe@mail.com",
+	}
+
+	created, err := s.client.CreateUser(ctx, request)
+	s.Require().NoError(err, "Should create user")
+	s.NotEmpty(created.Id, "Should assign id")
+	s.Equal(request.Email, created.Email, "Should match email")
+
+	fetched, err := s.client.GetUser(ctx, created.Id)
+	s.Require().NoError(err, "Should get user")
+	s.Equal(created, fetched, "Should match created user")
+
+	request.Email = "jane@mail.com"
+	updated, err := s.client.UpdateUser(ctx, created.Id, request)
+	s.Require().NoError(err, "Should update user")
+	s.Equal("jane@mail.com", updated.Email, "Should match updated email")
+
+	err = s.client.DeleteUser(ctx, created.Id)
+	s.Require().NoError(err, "Should delete user")
+
+	_, err = s.client.GetUser(ctx, created.Id)
+	s.True(errors.Is(err, ErrUserNotFound), "Should map 404 to ErrUserNotFound")
+
+	err = s.client.DeleteUser(ctx, created.Id)
+	s.True(errors.Is(err, ErrUserNotFound), "Should map 404 to ErrUserNotFound")
+}
+
+func (s *Suite) TestClient_CreateUserInvalid() {
+	_, err := s.client.CreateUser(context.Background(), schemas.UserRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane",
+	})
+
+	var apiErr *APIError
+	s.Require().True(errors.As(err, &apiErr), "Should return APIError")
+	s.True(errors.Is(err, ErrBadRequest), "Should map 400 to ErrBadRequest")
+	s.Equal("email", apiErr.Errors[0].Field, "Should include field errors")
+}
+
+func (s *Suite) TestClient_ListUsers() {
+	ctx := context.Background()
+	expected := map[string]bool{}
+	for i := 0; i < 5; i++ {
+		user, err := s.client.CreateUser(ctx, schemas.UserRequest{
+			FirstName: "Jane",
+			LastName:  fmt.Sprintf("Doe %d", i),
+			Email:     fmt.Sprintf("jane.doe.%d@mail.com", i),
+		})
+		s.Require().NoError(err, "Should create user")
+		expected[user.Id] = true
+	}
+
+	actual := map[string]bool{}
+	iterator := s.client.ListUsers(ctx, 2)
+	for iterator.Next() {
+		actual[iterator.User().Id] = true
+	}
+
+	s.NoError(iterator.Err(), "Should iterate without error")
+	s.Equal(expected, actual, "Should iterate over all users across pages")
+
+	iterator = s.client.ListUsers(ctx, 500)
+	s.True(iterator.Next(), "Should clamp page size to maximum")
+	s.NoError(iterator.Err(), "Should iterate without error")
+}
+
+func TestClient_Retry(t *testing.T) {
+	testCases := []struct {
+		failures         int32
+		failureStatus    int
+		expectedAttempts int32
+		expectedErr      error
+		reason           string
+	}{
+		{
+			failures:         2,
+			failureStatus:    http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+			expectedErr:      nil,
+			reason:           "Should retry until the request succeeds",
+		},
+		{
+			failures:         5,
+			failureStatus:    http.StatusServiceUnavailable,
+			expectedAttempts: 3,
+			expectedErr:      ErrServer,
+			reason:           "Should give up after max attempts",
+		},
+		{
+			failures:         5,
+			failureStatus:    http.StatusInternalServerError,
+			expectedAttempts: 3,
+			expectedErr:      ErrServer,
+			reason:           "Should retry any server error",
+		},
+		{
+			failures:         5,
+			failureStatus:    http.StatusNotFound,
+			expectedAttempts: 1,
+			expectedErr:      ErrUserNotFound,
+			reason:           "Should not retry client errors",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			var attempts int32
+			server := httptest.NewServer(
+				http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+					assert.Equal(
+						t,
+						"Bearer secret",
+						request.Header.Get("Authorization"),
+						"Should inject auth header",
+					)
+					if atomic.AddInt32(&attempts, 1) <= tc.failures {
+						writer.WriteHeader(tc.failureStatus)
+						return
+					}
+					writer.Header().Set("Content-Type", "application/json")
+					_, _ = writer.Write([]byte(`{"id":"abc123"}`))
+				}),
+			)
+			defer server.Close()
+
+			client, err := NewClient(
+				server.URL,
+				WithBearerToken("secret"),
+				WithRetryPolicy(RetryPolicy{
+					MaxAttempts: 3,
+					MinBackoff:  time.Millisecond,
+					MaxBackoff:  5 * time.Millisecond,
+				}),
+			)
+			if err != nil {
+				t.Fatalf("error creating client: %v", err)
+			}
+
+			_, err = client.GetUser(context.Background(), "abc123")
+
+			assert.Equal(
+				t,
+				tc.expectedAttempts,
+				atomic.LoadInt32(&attempts),
+				"Should match attempts",
+			)
+			if tc.expectedErr == nil {
+				assert.NoError(t, err, "Should succeed")
+			} else {
+				assert.ErrorIs(t, err, tc.expectedErr, "Should match error")
+			}
+		})
+	}
+}
+
+func TestClient_RetryNetworkErrors(t *testing.T) {
+	var attempts int32
+	client, err := NewClient(
+		"http://localhost:1",
+		WithAuth(func(*http.Request) error {
+			if atomic.AddInt32(&attempts, 1) == 1 {
+				return nil
+			}
+			return errors.New("token expired")
+		}),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  time.Millisecond,
+			MaxBackoff:  5 * time.Millisecond,
+		}),
+	)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+
+	_, err = client.GetUser(context.Background(), "abc123")
+
+	assert.ErrorContains(t, err, "error authenticating request", "Should match error")
+	assert.Equal(
+		t,
+		int32(2),
+		atomic.LoadInt32(&attempts),
+		"Should retry network error but not authentication error",
+	)
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var (
+	ErrBadRequest      = errors.New("bad request")
+	ErrUnauthorized    = errors.New("unauthorized")
+	ErrForbidden       = errors.New("forbidden")
+	ErrUserNotFound    = errors.New("user not found")
+	ErrTooManyRequests = errors.New("too many requests")
+	ErrServer          = errors.New("server error")
+)
+
+type APIError struct {
+	StatusCode int
+	Details    string
+	Errors     []models.FieldError
+	sentinel   error
+}
+
+func (err *APIError) Error() string {
+	if err.Details == "" {
+		return fmt.Sprintf("%v: status %d", err.sentinel, err.StatusCode)
+	}
+	return fmt.Sprintf("%v: status %d: %s", err.sentinel, err.StatusCode, err.Details)
+}
+
+func (err *APIError) Unwrap() error {
+	return err.sentinel
+}
+
+func newAPIError(statusCode int, body models.ValidationErrorMessage) *APIError {
+	return &APIError{
+		StatusCode: statusCode,
+		Details:    body.Details,
+		Errors:     body.Errors,
+		sentinel:   sentinelForStatus(statusCode),
+	}
+}
+
+func sentinelForStatus(statusCode int) error {
+	switch {
+	case statusCode == http.StatusBadRequest:
+		return ErrBadRequest
+	case statusCode == http.StatusUnauthorized:
+		return ErrUnauthorized
+	case statusCode == http.StatusForbidden:
+		return ErrForbidden
+	case statusCode == http.StatusNotFound:
+		return ErrUserNotFound
+	case statusCode == http.StatusTooManyRequests:
+		return ErrTooManyRequests
+	case statusCode >= http.StatusInternalServerError:
+		return ErrServer
+	}
+	return fmt.Errorf("unexpected status %d", statusCode)
+}
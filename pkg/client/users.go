@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const (
+	defaultPageSize = 50
+	maxPageSize     = 100
+)
+
+func (client *Client) CreateUser(
+	ctx context.Context,
+	request schemas.UserRequest,
+) (*schemas.UserResponse, error) {
+	var user schemas.UserResponse
+	_, err := client.do(ctx, http.MethodPost, "/users/", nil, request, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (client *Client) GetUser(ctx context.Context, id string) (*schemas.UserResponse, error) {
+	var user schemas.UserResponse
+	_, err := client.do(ctx, http.MethodGet, "/users/"+url.PathEscape(id), nil, nil, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (client *Client) ListUsersPage(
+	ctx context.Context,
+	limit int,
+	offset int,
+) ([]schemas.UserResponse, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	if offset > 0 {
+		query.Set("offset", strconv.Itoa(offset))
+	}
+
+	var users []schemas.UserResponse
+	_, err := client.do(ctx, http.MethodGet, "/users/", query, nil, &users)
+	return users, err
+}
+
+func (client *Client) ListUsers(ctx context.Context, pageSize int) *UserIterator {
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	return &UserIterator{
+		ctx:      ctx,
+		client:   client,
+		pageSize: pageSize,
+	}
+}
+
+func (client *Client) UpdateUser(
+	ctx context.Context,
+	id string,
+	request schemas.UserRequest,
+) (*schemas.UserResponse, error) {
+	var user schemas.UserResponse
+	_, err := client.do(ctx, http.MethodPut, "/users/"+url.PathEscape(id), nil, request, &user)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (client *Client) DeleteUser(ctx context.Context, id string) error {
+	_, err := client.do(ctx, http.MethodDelete, "/users/"+url.PathEscape(id), nil, nil, nil)
+	return err
+}
+
+type UserIterator struct {
+	ctx      context.Context
+	client   *Client
+	pageSize int
+	offset   int
+	page     []schemas.UserResponse
+	index    int
+	current  schemas.UserResponse
+	done     bool
+	err      error
+}
+
+func (iterator *UserIterator) Next() bool {
+	if iterator.err != nil {
+		return false
+	}
+
+	if iterator.index >= len(iterator.page) {
+		if iterator.done {
+			return false
+		}
+		page, err := iterator.client.ListUsersPage(
+			iterator.ctx,
+			iterator.pageSize,
+			iterator.offset,
+		)
+		if err != nil {
+			iterator.err = err
+			return false
+		}
+		iterator.page = page
+		iterator.index = 0
+		iterator.offset += len(page)
+		iterator.done = len(page) < iterator.pageSize
+		if len(page) == 0 {
+			return false
+		}
+	}
+
+	iterator.current = iterator.page[iterator.index]
+	iterator.index++
+	return true
+}
+
+func (iterator *UserIterator) User() schemas.UserResponse {
+	return iterator.current
+}
+
+func (iterator *UserIterator) Err() error {
+	return iterator.err
+}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+type Mode string
+
+const (
+	// TCP connects to the host and port of the DSN with the driver.
+	TCP Mode = "tcp"
+	// UnixSocket connects to the Unix socket of the DSN host, as a Cloud SQL
+	// instance connection name, in the socket directory.
+	UnixSocket Mode = "unix"
+	// Connector connects to the DSN host through the dialer of the config.
+	Connector Mode = "connector"
+)
+
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case TCP, UnixSocket, Connector:
+		return mode, nil
+	}
+	return "", fmt.Errorf("expecting mode %s, %s or %s", TCP, UnixSocket, Connector)
+}
+
+// Config describes how to connect to the database. Unix socket and connector
+// modes always use the postgres driver.
+type Config struct {
+	Driver         string
+	DSN            string
+	Mode           Mode
+	SocketDir      string
+	Dialer         Dialer
+	Pool           Pool
+	ConnectRetries int
+}
+
+// Pool configures the connection pool of a database. Zero values keep the
+// defaults of database/sql.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+func (pool Pool) apply(db *sql.DB) {
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+}
+
+// dialer returns the dialer of the mode, which is nil in TCP mode.
+func (config Config) dialer() (Dialer, error) {
+	switch config.Mode {
+	case UnixSocket:
+		return unixDialer{dir: config.SocketDir}, nil
+	case Connector:
+		if config.Dialer == nil {
+			return nil, errors.New("connector mode requires a dialer")
+		}
+		return config.Dialer, nil
+	}
+	return nil, nil
+}
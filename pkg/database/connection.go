@@ -2,19 +2,28 @@ package database
 
 import (
 	"fmt"
+	"log"
+	"time"
 
 	"database/sql"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
 )
 
-func GetConnection(driver string, dsn string) (*gorm.DB, error) {
-	sqlDB, err := sql.Open(driver, dsn)
+func GetConnection(config Config) (*gorm.DB, error) {
+	sqlDB, err := openDB(config, config.DSN)
 	if err != nil {
 		return nil, fmt.Errorf("error opening SQL DB: %v", err)
 	}
 
+	err = connect(sqlDB, config.ConnectRetries)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to SQL DB: %v", err)
+	}
+
 	dialector := postgres.New(postgres.Config{
 		Conn: sqlDB,
 	})
@@ -22,5 +31,73 @@ func GetConnection(driver string, dsn string) (*gorm.DB, error) {
 	if err != nil {
 		return nil, fmt.Errorf("error opening Gorm DB: %v", err)
 	}
+
+	err = gormDB.Use(tenancy.Plugin{})
+	if err != nil {
+		return nil, fmt.Errorf("error registering tenancy plugin: %v", err)
+	}
 	return gormDB, nil
 }
+
+// UseReplicas sends the reads of gormDB to read replicas opened from dsns,
+// with the mode and pool of config.
+func UseReplicas(
+	gormDB *gorm.DB,
+	config Config,
+	dsns []string,
+	policy Policy,
+) (*Replicas, error) {
+	dbs := make([]*sql.DB, 0, len(dsns))
+	for _, dsn := range dsns {
+		sqlDB, err := openDB(config, dsn)
+		if err != nil {
+			return nil, fmt.Errorf("error opening replica SQL DB: %v", err)
+		}
+		dbs = append(dbs, sqlDB)
+	}
+
+	replicas := NewReplicas(dbs, policy)
+	err := gormDB.Use(replicas)
+	if err != nil {
+		return nil, fmt.Errorf("error registering replicas plugin: %v", err)
+	}
+	return replicas, nil
+}
+
+func openDB(config Config, dsn string) (*sql.DB, error) {
+	dialer, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlDB *sql.DB
+	if dialer == nil {
+		sqlDB, err = sql.Open(config.Driver, dsn)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		sqlDB = sql.OpenDB(dialConnector{dialer: dialer, dsn: dsn})
+	}
+	config.Pool.apply(sqlDB)
+	return sqlDB, nil
+}
+
+// connect pings db, retrying up to retries times with exponential backoff
+// while the database is starting up or unreachable.
+func connect(db *sql.DB, retries int) error {
+	backoff := minReconnectInterval
+	for attempt := 0; ; attempt++ {
+		err := db.Ping()
+		if err == nil || attempt >= retries {
+			return err
+		}
+
+		log.Printf("error connecting to database, retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxReconnectInterval {
+			backoff = maxReconnectInterval
+		}
+	}
+}
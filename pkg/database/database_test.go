@@ -0,0 +1,257 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type record struct {
+	Id   string
+	Name string
+}
+
+const selectRecords = `SELECT * FROM "records"`
+
+func newMock(t *testing.T, monitorPings bool) (*sql.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(monitorPings))
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	return conn, mock
+}
+
+func newReplicatedDB(
+	t *testing.T,
+	policy Policy,
+	replicaCount int,
+) (*gorm.DB, *Replicas, sqlmock.Sqlmock, []sqlmock.Sqlmock) {
+	conn, primary := newMock(t, false)
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	var conns []*sql.DB
+	var mocks []sqlmock.Sqlmock
+	for i := 0; i < replicaCount; i++ {
+		conn, mock := newMock(t, true)
+		conns = append(conns, conn)
+		mocks = append(mocks, mock)
+	}
+	replicas := NewReplicas(conns, policy)
+	err = db.Use(replicas)
+	if err != nil {
+		t.Fatalf("error registering replicas: %v", err)
+	}
+	return db, replicas, primary, mocks
+}
+
+func expectSelect(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery(regexp.QuoteMeta(selectRecords)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("unix")
+	assert.NoError(t, err, "Should parse mode")
+	assert.Equal(t, UnixSocket, mode, "Should match mode")
+
+	_, err = ParseMode("proxy")
+	assert.Error(t, err, "Should reject unknown mode")
+}
+
+func TestOpenDB(t *testing.T) {
+	config := Config{
+		Driver: "postgres",
+		Pool:   Pool{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: time.Hour},
+	}
+	sqlDB, err := openDB(config, "host=localhost")
+	assert.NoError(t, err, "Should open database")
+	assert.Equal(t, 10, sqlDB.Stats().MaxOpenConnections, "Should limit open connections")
+
+	_, err = openDB(Config{Mode: Connector}, "host=localhost")
+	assert.Error(t, err, "Should require dialer in connector mode")
+}
+
+func TestUnixDialer(t *testing.T) {
+	dir := t.TempDir()
+	err := os.Mkdir(filepath.Join(dir, "project:region:instance"), 0o700)
+	if err != nil {
+		t.Fatalf("error creating socket directory: %v", err)
+	}
+	listener, err := net.Listen(
+		"unix",
+		filepath.Join(dir, "project:region:instance", ".s.PGSQL.5432"),
+	)
+	if err != nil {
+		t.Fatalf("error listening on socket: %v", err)
+	}
+	defer listener.Close()
+
+	dialer, err := Config{Mode: UnixSocket, SocketDir: dir}.dialer()
+	assert.NoError(t, err, "Should create dialer")
+
+	conn, err := pqDialer{dialer: dialer, ctx: context.Background()}.DialTimeout(
+		"tcp",
+		"[project:region:instance]:5432",
+		time.Second,
+	)
+	assert.NoError(t, err, "Should dial socket of instance")
+	if conn != nil {
+		conn.Close()
+	}
+}
+
+type blockingDialer struct{}
+
+func (blockingDialer) DialContext(ctx context.Context, _ string, _ string) (net.Conn, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestDialConnector_Connect(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := dialConnector{dialer: blockingDialer{}, dsn: "host=localhost"}.Connect(ctx)
+	assert.ErrorIs(t, err, context.Canceled, "Should dial with context of connection")
+}
+
+func TestConnect(t *testing.T) {
+	conn, mock := newMock(t, true)
+	mock.ExpectPing().WillReturnError(errors.New("the database system is starting up"))
+	mock.ExpectPing()
+
+	err := connect(conn, 1)
+	assert.NoError(t, err, "Should connect after retry")
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+	err = connect(conn, 0)
+	assert.Error(t, err, "Should fail without retries left")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should ping database")
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy("least_connections")
+	assert.NoError(t, err, "Should parse policy")
+	assert.Equal(t, LeastConnections, policy, "Should match policy")
+
+	_, err = ParsePolicy("fastest")
+	assert.Error(t, err, "Should reject unknown policy")
+}
+
+func TestReplicas_Route(t *testing.T) {
+	db, _, primary, replicas := newReplicatedDB(t, RoundRobin, 2)
+	ctx := context.Background()
+
+	expectSelect(replicas[0])
+	expectSelect(replicas[1])
+	expectSelect(replicas[0])
+	for i := 0; i < 3; i++ {
+		err := db.WithContext(ctx).Find(&[]record{}).Error
+		assert.NoError(t, err, "Should read from replica")
+	}
+
+	primary.ExpectBegin()
+	primary.ExpectExec(regexp.QuoteMeta(`INSERT INTO "records"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	primary.ExpectCommit()
+	err := db.WithContext(ctx).Create(&record{Id: "abc123", Name: "Jane"}).Error
+	assert.NoError(t, err, "Should write to primary")
+
+	expectSelect(primary)
+	err = db.WithContext(WithPrimary(ctx)).Find(&[]record{}).Error
+	assert.NoError(t, err, "Should read from primary when forced")
+
+	primary.ExpectQuery(regexp.QuoteMeta(selectRecords + ` FOR UPDATE`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	err = db.WithContext(ctx).
+		Clauses(clause.Locking{Strength: "UPDATE"}).
+		Find(&[]record{}).
+		Error
+	assert.NoError(t, err, "Should lock rows on primary")
+
+	primary.ExpectBegin()
+	expectSelect(primary)
+	primary.ExpectCommit()
+	err = db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return tx.Find(&[]record{}).Error
+	})
+	assert.NoError(t, err, "Should read from primary in transaction")
+
+	primary.ExpectQuery(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_notify"}))
+	err = db.WithContext(ctx).Raw("SELECT pg_notify(?, ?)", "users", "{}").Scan(&[]string{}).Error
+	assert.NoError(t, err, "Should send raw SQL to primary")
+
+	assert.NoError(t, primary.ExpectationsWereMet(), "Should match primary queries")
+	for _, replica := range replicas {
+		assert.NoError(t, replica.ExpectationsWereMet(), "Should match replica queries")
+	}
+}
+
+func TestReplicas_CheckHealth(t *testing.T) {
+	db, replicas, primary, mocks := newReplicatedDB(t, Random, 2)
+	ctx := context.Background()
+
+	mocks[0].ExpectPing().WillReturnError(errors.New("connection refused"))
+	mocks[1].ExpectPing()
+	replicas.checkHealth(ctx, time.Second)
+
+	expectSelect(mocks[1])
+	expectSelect(mocks[1])
+	for i := 0; i < 2; i++ {
+		err := db.WithContext(ctx).Find(&[]record{}).Error
+		assert.NoError(t, err, "Should read from healthy replica")
+	}
+
+	mocks[0].ExpectPing().WillReturnError(errors.New("connection refused"))
+	mocks[1].ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.checkHealth(ctx, time.Second)
+
+	expectSelect(primary)
+	err := db.WithContext(ctx).Find(&[]record{}).Error
+	assert.NoError(t, err, "Should read from primary without healthy replicas")
+
+	mocks[0].ExpectPing()
+	mocks[1].ExpectPing().WillReturnError(errors.New("connection refused"))
+	replicas.checkHealth(ctx, time.Second)
+
+	expectSelect(mocks[0])
+	err = db.WithContext(ctx).Find(&[]record{}).Error
+	assert.NoError(t, err, "Should read from recovered replica")
+
+	assert.NoError(t, primary.ExpectationsWereMet(), "Should match primary queries")
+	for _, mock := range mocks {
+		assert.NoError(t, mock.ExpectationsWereMet(), "Should match replica queries")
+	}
+}
+
+func TestStickiness(t *testing.T) {
+	now := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+	stickiness := NewStickiness(5 * time.Second)
+	stickiness.now = func() time.Time { return now }
+
+	stickiness.Wrote("user:abc123")
+	assert.True(t, stickiness.Sticky("user:abc123"), "Should stick after write")
+	assert.False(t, stickiness.Sticky("user:abc124"), "Should not stick other clients")
+
+	now = now.Add(5 * time.Second)
+	assert.False(t, stickiness.Sticky("user:abc123"), "Should not stick after window")
+
+	stickiness.Wrote("user:abc124")
+	assert.NotContains(t, stickiness.writes, "user:abc123", "Should sweep expired writes")
+}
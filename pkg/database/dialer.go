@@ -0,0 +1,103 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"path/filepath"
+	"time"
+
+	"cloud.google.com/go/cloudsqlconn"
+	"github.com/lib/pq"
+)
+
+// Dialer connects to the database server at address, the host and port of the
+// DSN, so that connections can go through a proxy or connector.
+type Dialer interface {
+	DialContext(ctx context.Context, network string, address string) (net.Conn, error)
+}
+
+type unixDialer struct {
+	dir string
+}
+
+func (dialer unixDialer) DialContext(
+	ctx context.Context,
+	_ string,
+	address string,
+) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	var netDialer net.Dialer
+	return netDialer.DialContext(ctx, "unix", filepath.Join(dialer.dir, host, ".s.PGSQL."+port))
+}
+
+// CloudSQLDialer connects to the Cloud SQL instance named by the DSN host, as
+// project:region:instance, with the Cloud SQL Go connector.
+type CloudSQLDialer struct {
+	dialer *cloudsqlconn.Dialer
+}
+
+// NewCloudSQLDialer creates a dialer with the default credentials. With
+// iamAuth, the database user is an IAM principal logging in without password.
+func NewCloudSQLDialer(ctx context.Context, iamAuth bool) (*CloudSQLDialer, error) {
+	var options []cloudsqlconn.Option
+	if iamAuth {
+		options = append(options, cloudsqlconn.WithIAMAuthN())
+	}
+	dialer, err := cloudsqlconn.NewDialer(ctx, options...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating Cloud SQL dialer: %v", err)
+	}
+	return &CloudSQLDialer{dialer: dialer}, nil
+}
+
+func (dialer *CloudSQLDialer) DialContext(
+	ctx context.Context,
+	_ string,
+	address string,
+) (net.Conn, error) {
+	instance, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+	return dialer.dialer.Dial(ctx, instance)
+}
+
+// pqDialer adapts a Dialer to the pq driver, dialing with the context of the
+// connection attempt.
+type pqDialer struct {
+	dialer Dialer
+	ctx    context.Context
+}
+
+func (dialer pqDialer) Dial(network string, address string) (net.Conn, error) {
+	return dialer.dialer.DialContext(dialer.ctx, network, address)
+}
+
+func (dialer pqDialer) DialTimeout(
+	network string,
+	address string,
+	timeout time.Duration,
+) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(dialer.ctx, timeout)
+	defer cancel()
+	return dialer.dialer.DialContext(ctx, network, address)
+}
+
+// dialConnector opens pq connections through a dialer.
+type dialConnector struct {
+	dialer Dialer
+	dsn    string
+}
+
+func (connector dialConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return pq.DialOpen(pqDialer{dialer: connector.dialer, ctx: ctx}, connector.dsn)
+}
+
+func (connector dialConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	minReconnectInterval = time.Second
+	maxReconnectInterval = time.Minute
+)
+
+func NewListener(config Config) (*pq.Listener, error) {
+	onEvent := func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("listener connection event %d: %v", event, err)
+		}
+	}
+
+	dialer, err := config.dialer()
+	if err != nil {
+		return nil, err
+	}
+	if dialer != nil {
+		return pq.NewDialListener(
+			pqDialer{dialer: dialer, ctx: context.Background()},
+			config.DSN,
+			minReconnectInterval,
+			maxReconnectInterval,
+			onEvent,
+		), nil
+	}
+	return pq.NewListener(config.DSN, minReconnectInterval, maxReconnectInterval, onEvent), nil
+}
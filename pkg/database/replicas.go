@@ -0,0 +1,190 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type Policy string
+
+const (
+	RoundRobin       Policy = "round_robin"
+	Random           Policy = "random"
+	LeastConnections Policy = "least_connections"
+)
+
+func ParsePolicy(value string) (Policy, error) {
+	switch policy := Policy(value); policy {
+	case RoundRobin, Random, LeastConnections:
+		return policy, nil
+	}
+	return "", fmt.Errorf(
+		"expecting policy %s, %s or %s",
+		RoundRobin,
+		Random,
+		LeastConnections,
+	)
+}
+
+type primaryKey struct{}
+
+// WithPrimary makes queries with ctx read from the primary.
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey{}, true)
+}
+
+// UsesPrimary reports whether queries with ctx read from the primary.
+func UsesPrimary(ctx context.Context) bool {
+	primary, _ := ctx.Value(primaryKey{}).(bool)
+	return primary
+}
+
+// Replicas is a plugin that sends reads to healthy read replicas, chosen by a
+// load balancing policy. Writes, transactions, locking reads and raw SQL go to
+// the primary, as do reads while no replica is healthy.
+type Replicas struct {
+	primary  gorm.ConnPool
+	replicas []*replica
+	policy   Policy
+	next     uint32
+}
+
+type replica struct {
+	db      *sql.DB
+	healthy int32
+}
+
+func NewReplicas(dbs []*sql.DB, policy Policy) *Replicas {
+	replicas := &Replicas{policy: policy}
+	for _, db := range dbs {
+		replicas.replicas = append(replicas.replicas, &replica{db: db, healthy: 1})
+	}
+	return replicas
+}
+
+func (replicas *Replicas) Name() string {
+	return "database:replicas"
+}
+
+func (replicas *Replicas) Initialize(db *gorm.DB) error {
+	replicas.primary = db.ConnPool
+
+	callbacks := db.Callback()
+	err := callbacks.Query().Before("gorm:query").Register("database:query", replicas.routeRead)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Row().Before("gorm:row").Register("database:row", replicas.routeRead)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Create().Before("gorm:create").Register("database:create", replicas.routeWrite)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Update().Before("gorm:update").Register("database:update", replicas.routeWrite)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Delete().Before("gorm:delete").Register("database:delete", replicas.routeWrite)
+	if err != nil {
+		return err
+	}
+	return callbacks.Raw().Before("gorm:raw").Register("database:raw", replicas.routeWrite)
+}
+
+// Run checks the health of the replicas every interval until ctx is done.
+// Replicas that cannot be pinged within timeout are ejected until they
+// respond again.
+func (replicas *Replicas) Run(ctx context.Context, interval time.Duration, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		replicas.checkHealth(ctx, timeout)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (replicas *Replicas) checkHealth(ctx context.Context, timeout time.Duration) {
+	for i, replica := range replicas.replicas {
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := replica.db.PingContext(pingCtx)
+		cancel()
+
+		if err != nil {
+			if atomic.SwapInt32(&replica.healthy, 0) == 1 {
+				log.Printf("ejecting read replica %d: %v", i, err)
+			}
+		} else if atomic.SwapInt32(&replica.healthy, 1) == 0 {
+			log.Printf("read replica %d is healthy again", i)
+		}
+	}
+}
+
+func (replicas *Replicas) routeRead(db *gorm.DB) {
+	_, locking := db.Statement.Clauses["FOR"]
+	if isTransaction(db.Statement.ConnPool) || locking || db.Statement.SQL.Len() > 0 ||
+		UsesPrimary(db.Statement.Context) {
+		replicas.routeWrite(db)
+		return
+	}
+
+	replica := replicas.pick()
+	if replica == nil {
+		replicas.routeWrite(db)
+		return
+	}
+	db.Statement.ConnPool = replica.db
+}
+
+func (replicas *Replicas) routeWrite(db *gorm.DB) {
+	if !isTransaction(db.Statement.ConnPool) {
+		db.Statement.ConnPool = replicas.primary
+	}
+}
+
+func (replicas *Replicas) pick() *replica {
+	healthy := make([]*replica, 0, len(replicas.replicas))
+	for _, replica := range replicas.replicas {
+		if atomic.LoadInt32(&replica.healthy) == 1 {
+			healthy = append(healthy, replica)
+		}
+	}
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	switch replicas.policy {
+	case Random:
+		return healthy[rand.Intn(len(healthy))]
+	case LeastConnections:
+		least := healthy[0]
+		for _, replica := range healthy[1:] {
+			if replica.db.Stats().InUse < least.db.Stats().InUse {
+				least = replica
+			}
+		}
+		return least
+	default:
+		next := atomic.AddUint32(&replicas.next, 1)
+		return healthy[(next-1)%uint32(len(healthy))]
+	}
+}
+
+func isTransaction(connPool gorm.ConnPool) bool {
+	_, ok := connPool.(gorm.TxCommitter)
+	return ok
+}
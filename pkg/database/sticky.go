@@ -0,0 +1,50 @@
+package database
+
+import (
+	"sync"
+	"time"
+)
+
+// Stickiness remembers clients that wrote within a window, so that their
+// reads can go to the primary until the replicas have caught up.
+type Stickiness struct {
+	window    time.Duration
+	mu        sync.Mutex
+	writes    map[string]time.Time
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewStickiness(window time.Duration) *Stickiness {
+	return &Stickiness{
+		window: window,
+		writes: map[string]time.Time{},
+		now:    time.Now,
+	}
+}
+
+func (stickiness *Stickiness) Wrote(key string) {
+	stickiness.mu.Lock()
+	defer stickiness.mu.Unlock()
+
+	now := stickiness.now()
+	stickiness.writes[key] = now
+	if now.Sub(stickiness.lastSweep) < stickiness.window {
+		return
+	}
+	for key, wrote := range stickiness.writes {
+		if now.Sub(wrote) >= stickiness.window {
+			delete(stickiness.writes, key)
+		}
+	}
+	stickiness.lastSweep = now
+}
+
+// Sticky reports whether the client of key wrote within the window.
+func (stickiness *Stickiness) Sticky(key string) bool {
+	stickiness.mu.Lock()
+	defer stickiness.mu.Unlock()
+
+	wrote, ok := stickiness.writes[key]
+	return ok && stickiness.now().Sub(wrote) < stickiness.window
+}
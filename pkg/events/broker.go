@@ -0,0 +1,98 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+)
+
+type Broker struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscription]struct{}
+	bufferSize  int
+	recent      []*Event
+	replaySize  int
+}
+
+type Subscription struct {
+	broker *Broker
+	events chan *Event
+	once   sync.Once
+}
+
+func NewBroker(bufferSize int, replaySize int) *Broker {
+	return &Broker{
+		subscribers: map[*Subscription]struct{}{},
+		bufferSize:  bufferSize,
+		replaySize:  replaySize,
+	}
+}
+
+func (broker *Broker) Subscribe() *Subscription {
+	subscription := &Subscription{
+		broker: broker,
+		events: make(chan *Event, broker.bufferSize),
+	}
+
+	broker.mu.Lock()
+	broker.subscribers[subscription] = struct{}{}
+	broker.mu.Unlock()
+	return subscription
+}
+
+func (broker *Broker) Publish(_ context.Context, event *Event) error {
+	broker.mu.Lock()
+	if broker.replaySize > 0 {
+		if len(broker.recent) == broker.replaySize {
+			broker.recent = broker.recent[1:]
+		}
+		broker.recent = append(broker.recent, event)
+	}
+
+	var slow []*Subscription
+	for subscription := range broker.subscribers {
+		select {
+		case subscription.events <- event:
+		default:
+			slow = append(slow, subscription)
+		}
+	}
+	broker.mu.Unlock()
+
+	for _, subscription := range slow {
+		log.Printf("dropping slow subscriber after event %d", event.Id)
+		subscription.Close()
+	}
+	return nil
+}
+
+func (broker *Broker) Replay(afterId int64) ([]*Event, bool) {
+	broker.mu.RLock()
+	defer broker.mu.RUnlock()
+
+	if len(broker.recent) == 0 || broker.recent[0].Id > afterId {
+		return nil, false
+	}
+
+	var replay []*Event
+	for _, event := range broker.recent {
+		if event.Id > afterId {
+			replay = append(replay, event)
+		}
+	}
+	return replay, true
+}
+
+func (subscription *Subscription) Events() <-chan *Event {
+	return subscription.events
+}
+
+func (subscription *Subscription) Close() {
+	subscription.once.Do(func() {
+		broker := subscription.broker
+		broker.mu.Lock()
+		delete(broker.subscribers, subscription)
+		broker.mu.Unlock()
+		close(subscription.events)
+	})
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+const (
+	UserCreated = "user.created"
+	UserUpdated = "user.updated"
+	UserDeleted = "user.deleted"
+
+	userAggregate = "user"
+)
+
+type Event struct {
+	Id          int64           `json:"id"`
+	TenantId    string          `json:"tenant_id"`
+	Type        string          `json:"type"`
+	AggregateId string          `json:"aggregate_id"`
+	OccurredAt  time.Time       `json:"occurred_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+type UserSnapshot struct {
+	Id        string `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+}
+
+type FieldChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+type UserCreatedPayload struct {
+	User UserSnapshot `json:"user"`
+}
+
+type UserUpdatedPayload struct {
+	User    UserSnapshot           `json:"user"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+type UserDeletedPayload struct {
+	User UserSnapshot `json:"user"`
+}
+
+func NewUserSnapshot(user *models.User) UserSnapshot {
+	return UserSnapshot{
+		Id:        user.Id,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+}
+
+func DiffUsers(before *models.User, after *models.User) map[string]FieldChange {
+	changes := map[string]FieldChange{}
+	addChange := func(field string, old string, new string) {
+		if old != new {
+			changes[field] = FieldChange{Old: old, New: new}
+		}
+	}
+	addChange("first_name", before.FirstName, after.FirstName)
+	addChange("last_name", before.LastName, after.LastName)
+	addChange("email", before.Email, after.Email)
+	return changes
+}
+
+func eventFromMessage(message *models.OutboxMessage) *Event {
+	return &Event{
+		Id:          message.Id,
+		TenantId:    message.TenantId,
+		Type:        message.EventType,
+		AggregateId: message.AggregateId,
+		OccurredAt:  message.CreatedAt,
+		Payload:     message.Payload,
+	}
+}
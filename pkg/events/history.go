@@ -0,0 +1,46 @@
+package events
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type History interface {
+	EventsAfter(ctx context.Context, afterId int64, limit int) ([]*Event, error)
+}
+
+type OutboxHistory struct {
+	gormDB *gorm.DB
+}
+
+func NewOutboxHistory(DB *gorm.DB) *OutboxHistory {
+	return &OutboxHistory{gormDB: DB}
+}
+
+// EventsAfter returns the published events after afterId. Reads through a DB
+// with tenancy.Plugin only return the events of the tenant in ctx.
+func (history *OutboxHistory) EventsAfter(
+	ctx context.Context,
+	afterId int64,
+	limit int,
+) ([]*Event, error) {
+	var messages []*models.OutboxMessage
+	err := history.gormDB.WithContext(ctx).
+		Where("id > ? AND published_at IS NOT NULL", afterId).
+		Order("id").
+		Limit(limit).
+		Find(&messages).
+		Error
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, len(messages))
+	for i, message := range messages {
+		events[i] = eventFromMessage(message)
+	}
+	return events, nil
+}
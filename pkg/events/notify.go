@@ -0,0 +1,93 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+)
+
+const (
+	NotifyChannel = "user_events"
+
+	listenerPingInterval = 90 * time.Second
+)
+
+type NotifyPublisher struct {
+	gormDB *gorm.DB
+}
+
+func NewNotifyPublisher(DB *gorm.DB) *NotifyPublisher {
+	return &NotifyPublisher{gormDB: DB}
+}
+
+func (publisher *NotifyPublisher) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+
+	err = publisher.gormDB.WithContext(ctx).
+		Exec("SELECT pg_notify(?, ?)", NotifyChannel, string(data)).
+		Error
+	if err != nil {
+		return fmt.Errorf("error notifying listeners: %v", err)
+	}
+	return nil
+}
+
+type Listener struct {
+	listener  *pq.Listener
+	publisher Publisher
+}
+
+func NewListener(listener *pq.Listener, publisher Publisher) *Listener {
+	return &Listener{
+		listener:  listener,
+		publisher: publisher,
+	}
+}
+
+func (listener *Listener) Run(ctx context.Context) error {
+	err := listener.listener.Listen(NotifyChannel)
+	if err != nil {
+		return fmt.Errorf("error listening on channel %q: %v", NotifyChannel, err)
+	}
+	defer listener.listener.Close()
+
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			go func() {
+				if err := listener.listener.Ping(); err != nil {
+					log.Printf("error pinging listener connection: %v", err)
+				}
+			}()
+		case notification := <-listener.listener.Notify:
+			if notification == nil {
+				log.Printf("listener connection re-established, notifications may have been missed")
+				continue
+			}
+
+			event := &Event{}
+			err := json.Unmarshal([]byte(notification.Extra), event)
+			if err != nil {
+				log.Printf("error unmarshaling notification: %v", err)
+				continue
+			}
+			err = listener.publisher.Publish(ctx, event)
+			if err != nil {
+				log.Printf("error publishing event %d: %v", event.Id, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+type OutboxWriter struct{}
+
+func NewOutboxWriter() *OutboxWriter {
+	return &OutboxWriter{}
+}
+
+func (writer *OutboxWriter) OnUserChange(tx *gorm.DB, change *repositories.UserChange) error {
+	var eventType string
+	var payload any
+	switch change.Operation {
+	case repositories.OperationCreate:
+		eventType = UserCreated
+		payload = UserCreatedPayload{User: NewUserSnapshot(change.After)}
+	case repositories.OperationUpdate:
+		eventType = UserUpdated
+		payload = UserUpdatedPayload{
+			User:    NewUserSnapshot(change.After),
+			Changes: DiffUsers(change.Before, change.After),
+		}
+	case repositories.OperationDelete:
+		eventType = UserDeleted
+		payload = UserDeletedPayload{User: NewUserSnapshot(change.Before)}
+	default:
+		return fmt.Errorf("unknown operation %q", change.Operation)
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshaling event payload: %v", err)
+	}
+
+	message := &models.OutboxMessage{
+		TenantId:      change.TenantId(),
+		AggregateType: userAggregate,
+		AggregateId:   change.UserId(),
+		EventType:     eventType,
+		Payload:       data,
+	}
+	err = tx.Create(message).Error
+	if err != nil {
+		return fmt.Errorf("error writing outbox message: %v", err)
+	}
+	return nil
+}
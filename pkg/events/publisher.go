@@ -0,0 +1,21 @@
+package events
+
+import (
+	"context"
+)
+
+type Publisher interface {
+	Publish(ctx context.Context, event *Event) error
+}
+
+type MultiPublisher []Publisher
+
+func (publishers MultiPublisher) Publish(ctx context.Context, event *Event) error {
+	for _, publisher := range publishers {
+		err := publisher.Publish(ctx, event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"golang.org/x/oauth2/google"
+)
+
+const (
+	pubSubScope           = "https://www.googleapis.com/auth/pubsub"
+	defaultPubSubEndpoint = "https://pubsub.googleapis.com"
+)
+
+type PubSubPublisher struct {
+	httpClient *http.Client
+	publishURL string
+}
+
+type pubSubMessage struct {
+	Data        string            `json:"data"`
+	Attributes  map[string]string `json:"attributes"`
+	OrderingKey string            `json:"orderingKey"`
+}
+
+type pubSubRequest struct {
+	Messages []pubSubMessage `json:"messages"`
+}
+
+func NewPubSubPublisher(
+	ctx context.Context,
+	endpoint string,
+	project string,
+	topic string,
+) (*PubSubPublisher, error) {
+	httpClient, err := google.DefaultClient(ctx, pubSubScope)
+	if err != nil {
+		return nil, fmt.Errorf("error creating pub/sub client: %v", err)
+	}
+	if endpoint == "" {
+		endpoint = defaultPubSubEndpoint
+	}
+
+	return &PubSubPublisher{
+		httpClient: httpClient,
+		publishURL: fmt.Sprintf("%s/v1/projects/%s/topics/%s:publish", endpoint, project, topic),
+	}, nil
+}
+
+func (publisher *PubSubPublisher) Publish(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+
+	body, err := json.Marshal(pubSubRequest{
+		Messages: []pubSubMessage{
+			{
+				Data: base64.StdEncoding.EncodeToString(data),
+				Attributes: map[string]string{
+					"event_id":   strconv.FormatInt(event.Id, 10),
+					"event_type": event.Type,
+					"tenant_id":  event.TenantId,
+				},
+				OrderingKey: event.AggregateId,
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("error marshaling pub/sub request: %v", err)
+	}
+
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		publisher.publishURL,
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return fmt.Errorf("error creating pub/sub request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := publisher.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("error publishing to pub/sub: %v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		details, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+		return fmt.Errorf(
+			"error publishing to pub/sub: status %d: %s",
+			response.StatusCode,
+			details,
+		)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+const (
+	relayLockKey     = 7_310_451_962
+	maxRelayAttempts = 10
+)
+
+type Relay struct {
+	gormDB    *gorm.DB
+	publisher Publisher
+	interval  time.Duration
+	batchSize int
+}
+
+func NewRelay(DB *gorm.DB, publisher Publisher, interval time.Duration, batchSize int) *Relay {
+	return &Relay{
+		gormDB:    DB,
+		publisher: publisher,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+func (relay *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relay.interval)
+	defer ticker.Stop()
+
+	for {
+		published, err := relay.ProcessBatch(ctx)
+		if err != nil {
+			log.Printf("error relaying outbox messages: %v", err)
+		}
+		if published == relay.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (relay *Relay) ProcessBatch(ctx context.Context) (int, error) {
+	published := 0
+	err := relay.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var locked bool
+		err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", relayLockKey).Scan(&locked).Error
+		if err != nil {
+			return fmt.Errorf("error acquiring relay lock: %v", err)
+		}
+		if !locked {
+			return nil
+		}
+
+		var messages []*models.OutboxMessage
+		err = tx.Where("published_at IS NULL AND dead_at IS NULL").
+			Order("id").
+			Limit(relay.batchSize).
+			Find(&messages).
+			Error
+		if err != nil {
+			return fmt.Errorf("error reading outbox: %v", err)
+		}
+
+		blocked := map[string]bool{}
+		for _, message := range messages {
+			if blocked[message.AggregateId] {
+				continue
+			}
+
+			err = relay.publisher.Publish(ctx, eventFromMessage(message))
+			if err != nil {
+				log.Printf("error publishing outbox message %d: %v", message.Id, err)
+				blocked[message.AggregateId] = true
+				updates := map[string]any{
+					"attempts":   gorm.Expr("attempts + 1"),
+					"last_error": err.Error(),
+				}
+				// Dead messages are no longer relayed until an admin retries
+				// them.
+				if message.Attempts+1 >= maxRelayAttempts {
+					log.Printf(
+						"dead-lettering outbox message %d after %d attempts",
+						message.Id,
+						maxRelayAttempts,
+					)
+					updates["dead_at"] = time.Now()
+				}
+				err = tx.Model(message).Updates(updates).Error
+				if err != nil {
+					return fmt.Errorf("error recording failed attempt: %v", err)
+				}
+				continue
+			}
+
+			err = tx.Model(message).Update("published_at", time.Now()).Error
+			if err != nil {
+				return fmt.Errorf("error marking message as published: %v", err)
+			}
+			published++
+		}
+		return nil
+	})
+	return published, err
+}
@@ -0,0 +1,226 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+var outboxColumns = []string{"id", "aggregate_type", "aggregate_id", "event_type", "payload"}
+
+type recordingPublisher struct {
+	failures map[int64]bool
+	events   []*Event
+}
+
+func (publisher *recordingPublisher) Publish(_ context.Context, event *Event) error {
+	if publisher.failures[event.Id] {
+		return errors.New("unavailable")
+	}
+	publisher.events = append(publisher.events, event)
+	return nil
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	return db, mock
+}
+
+func TestOutboxWriter_SameTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	userRepository := repositories.NewSQLUserRepository(db, NewOutboxWriter())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "users"`)).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("abc123"))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "outbox"`)).
+		WithArgs(
+			"default",
+			"user",
+			"abc123",
+			UserCreated,
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+			sqlmock.AnyArg(),
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := userRepository.CreateUser(context.Background(), &models.User{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane.doe@mail.com",
+	})
+
+	assert.NoError(t, err, "Should create user")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should write outbox in user transaction")
+}
+
+func TestDiffUsers(t *testing.T) {
+	before := &models.User{Id: "abc123", FirstName: "Jane", LastName: "Doe", Email: "jane@mail.com"}
+	after := &models.User{Id: "abc123", FirstName: "Jane", LastName: "Roe", Email: "jane@mail.com"}
+
+	assert.Equal(
+		t,
+		map[string]FieldChange{"last_name": {Old: "Doe", New: "Roe"}},
+		DiffUsers(before, after),
+		"Should only contain changed fields",
+	)
+}
+
+func TestRelay_ProcessBatch(t *testing.T) {
+	db, mock := newMockDB(t)
+	publisher := &recordingPublisher{failures: map[int64]bool{1: true}}
+	relay := NewRelay(db, publisher, time.Second, 100)
+
+	payload, _ := json.Marshal(UserCreatedPayload{})
+	rows := sqlmock.NewRows(outboxColumns).
+		AddRow(1, "user", "abc123", UserCreated, payload).
+		AddRow(2, "user", "abc124", UserCreated, payload).
+		AddRow(3, "user", "abc123", UserUpdated, payload)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "outbox" WHERE published_at IS NULL AND dead_at IS NULL ORDER BY id LIMIT 100`,
+	)).WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "attempts"=attempts + 1,"last_error"=$1 WHERE "id" = $2`)).
+		WithArgs("unavailable", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "outbox" SET "published_at"=$1 WHERE "id" = $2`)).
+		WithArgs(sqlmock.AnyArg(), 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err, "Should process batch")
+	assert.Equal(t, 1, published, "Should publish one message")
+	assert.Len(t, publisher.events, 1, "Should publish one event")
+	assert.Equal(
+		t,
+		int64(2),
+		publisher.events[0].Id,
+		"Should hold back events after a failure for the same user",
+	)
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+}
+
+func TestRelay_DeadLetter(t *testing.T) {
+	db, mock := newMockDB(t)
+	publisher := &recordingPublisher{failures: map[int64]bool{1: true}}
+	relay := NewRelay(db, publisher, time.Second, 100)
+
+	payload, _ := json.Marshal(UserCreatedPayload{})
+	rows := sqlmock.NewRows(append(outboxColumns, "attempts")).
+		AddRow(1, "user", "abc123", UserCreated, payload, maxRelayAttempts-1)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT pg_try_advisory_xact_lock($1)`)).
+		WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_xact_lock"}).AddRow(true))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "outbox" WHERE published_at IS NULL AND dead_at IS NULL ORDER BY id LIMIT 100`,
+	)).WillReturnRows(rows)
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "outbox" SET "attempts"=attempts + 1,"dead_at"=$1,"last_error"=$2 WHERE "id" = $3`,
+	)).
+		WithArgs(sqlmock.AnyArg(), "unavailable", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	published, err := relay.ProcessBatch(context.Background())
+
+	assert.NoError(t, err, "Should process batch")
+	assert.Equal(t, 0, published, "Should publish no messages")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should mark message dead on last attempt")
+}
+
+func TestOutboxHistory_EventsAfter(t *testing.T) {
+	db, mock := newMockDB(t)
+	err := db.Use(tenancy.Plugin{})
+	if err != nil {
+		t.Fatalf("error registering tenancy plugin: %v", err)
+	}
+	history := NewOutboxHistory(db)
+
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "outbox" WHERE (id > $1 AND published_at IS NOT NULL) AND "outbox"."tenant_id" = $2 ORDER BY id LIMIT 10`,
+	)).
+		WithArgs(5, "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "tenant_id"}).AddRow(6, "acme"))
+
+	events, err := history.EventsAfter(tenancy.WithTenant(context.Background(), "acme"), 5, 10)
+
+	assert.NoError(t, err, "Should get events")
+	assert.Len(t, events, 1, "Should return events of tenant")
+	assert.Equal(t, "acme", events[0].TenantId, "Should set tenant of event")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should restrict events to tenant")
+}
+
+func TestBroker_Publish(t *testing.T) {
+	broker := NewBroker(1, 0)
+	fast := broker.Subscribe()
+	slow := broker.Subscribe()
+
+	_ = broker.Publish(context.Background(), &Event{Id: 1})
+	<-fast.Events()
+	_ = broker.Publish(context.Background(), &Event{Id: 2})
+
+	assert.Equal(t, int64(2), (<-fast.Events()).Id, "Should deliver events to subscriber")
+	assert.Equal(t, int64(1), (<-slow.Events()).Id, "Should deliver buffered events")
+
+	_, open := <-slow.Events()
+	assert.False(t, open, "Should close subscriber whose buffer is full")
+}
+
+func TestBroker_Replay(t *testing.T) {
+	broker := NewBroker(8, 2)
+	for id := int64(1); id <= 3; id++ {
+		_ = broker.Publish(context.Background(), &Event{Id: id})
+	}
+
+	replay, ok := broker.Replay(2)
+	assert.True(t, ok, "Should replay from buffer")
+	assert.Len(t, replay, 1, "Should replay events after id")
+
+	_, ok = broker.Replay(1)
+	assert.False(t, ok, "Should not replay events older than buffer")
+}
+
+func TestNotifyPublisher_Publish(t *testing.T) {
+	db, mock := newMockDB(t)
+	publisher := NewNotifyPublisher(db)
+
+	mock.ExpectExec(regexp.QuoteMeta(`SELECT pg_notify($1, $2)`)).
+		WithArgs(NotifyChannel, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := publisher.Publish(context.Background(), &Event{Id: 1, Type: UserCreated})
+
+	assert.NoError(t, err, "Should notify listeners")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+}
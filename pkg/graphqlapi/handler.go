@@ -0,0 +1,165 @@
+package graphqlapi
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/source"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+const graphqlTag = "graphql"
+
+type GraphQLRequest struct {
+	Query         string                 `json:"query"         binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+type GraphQLQuery struct {
+	Query         string `form:"query"         binding:"required"`
+	OperationName string `form:"operationName"`
+	Variables     string `form:"variables"`
+}
+
+type GraphQLResponse struct {
+	Data   map[string]interface{}     `json:"data"`
+	Errors []gqlerrors.FormattedError `json:"errors,omitempty"`
+}
+
+type Handler struct {
+	userRepository repositories.UserRepository
+	schema         graphql.Schema
+	limits         Limits
+}
+
+func NewHandler(userRepository repositories.UserRepository, limits Limits) *Handler {
+	return &Handler{
+		userRepository: userRepository,
+		schema:         newSchema(userRepository),
+		limits:         limits,
+	}
+}
+
+func (handler *Handler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *Handler) Routes() []openapi.Route {
+	responses := map[int]any{
+		http.StatusOK:         GraphQLResponse{},
+		http.StatusBadRequest: models.ValidationErrorMessage{},
+	}
+	return []openapi.Route{
+		{
+			Method:      http.MethodPost,
+			Path:        "",
+			Handler:     handler.Post,
+			OperationId: "postGraphQL",
+			Summary:     "Execute a GraphQL operation",
+			Tags:        []string{graphqlTag},
+			Body:        GraphQLRequest{},
+			Responses:   responses,
+		},
+		{
+			Method:      http.MethodGet,
+			Path:        "",
+			Handler:     handler.Get,
+			OperationId: "getGraphQL",
+			Summary:     "Execute a GraphQL query",
+			Tags:        []string{graphqlTag},
+			Query:       GraphQLQuery{},
+			Responses:   responses,
+		},
+	}
+}
+
+func (handler *Handler) Post(ctx *gin.Context) {
+	var request GraphQLRequest
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		log.Printf("invalid request body: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid request body"),
+		)
+		return
+	}
+	handler.execute(ctx, request)
+}
+
+func (handler *Handler) Get(ctx *gin.Context) {
+	var query GraphQLQuery
+	err := ctx.ShouldBindQuery(&query)
+	if err != nil {
+		log.Printf("invalid query: %v", err)
+		ctx.AbortWithStatusJSON(
+			http.StatusBadRequest,
+			models.NewErrorMessage("invalid query, expecting query"),
+		)
+		return
+	}
+
+	request := GraphQLRequest{
+		Query:         query.Query,
+		OperationName: query.OperationName,
+	}
+	if query.Variables != "" {
+		err = json.Unmarshal([]byte(query.Variables), &request.Variables)
+		if err != nil {
+			log.Printf("invalid variables: %v", err)
+			ctx.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				models.NewErrorMessage("invalid variables, expecting JSON object"),
+			)
+			return
+		}
+	}
+	handler.execute(ctx, request)
+}
+
+func (handler *Handler) execute(ctx *gin.Context, request GraphQLRequest) {
+	document, err := parser.Parse(parser.ParseParams{
+		Source: source.NewSource(&source.Source{Body: []byte(request.Query)}),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusOK, GraphQLResponse{
+			Errors: gqlerrors.FormatErrors(err),
+		})
+		return
+	}
+
+	err = handler.limits.check(document, request.Variables)
+	if err != nil {
+		log.Printf("query rejected: %v", err)
+		ctx.JSON(http.StatusOK, GraphQLResponse{
+			Errors: gqlerrors.FormatErrors(err),
+		})
+		return
+	}
+
+	loader := NewUserLoader(ctx.Request.Context(), handler.userRepository)
+	result := graphql.Do(graphql.Params{
+		Schema:         handler.schema,
+		RequestString:  request.Query,
+		VariableValues: request.Variables,
+		OperationName:  request.OperationName,
+		Context:        withUserLoader(ctx.Request.Context(), loader),
+	})
+
+	data, _ := result.Data.(map[string]interface{})
+	ctx.JSON(http.StatusOK, GraphQLResponse{
+		Data:   data,
+		Errors: result.Errors,
+	})
+}
@@ -0,0 +1,188 @@
+package graphqlapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func newRouter(userRepository repositories.UserRepository, limits Limits) *gin.Engine {
+	router := gin.New()
+	NewHandler(userRepository, limits).Register(router.Group("/graphql"))
+	return router
+}
+
+func execute(t *testing.T, router *gin.Engine, request GraphQLRequest) map[string]interface{} {
+	jsonBody, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("error marshaling request body to json %v", err)
+	}
+
+	httpRequest, err := http.NewRequest("POST", "/graphql", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		t.Fatalf("error creating request %v", err)
+	}
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httpRequest)
+	assert.Equal(t, 200, recorder.Code, "Should match response code")
+
+	var actualBody map[string]interface{}
+	err = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+	return actualBody
+}
+
+func TestHandler_BatchUserLookups(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	query := `SELECT * FROM "users" WHERE id IN ($1,$2)`
+	rows := sqlmock.NewRows([]string{"id", "first_name", "last_name", "email"}).
+		AddRow("abc123", "Jane", "Doe", "jane.doe@mail.com").
+		AddRow("abc124", "John", "Doe", "john.doe@mail.com")
+	mock.ExpectQuery(regexp.QuoteMeta(query)).
+		WithArgs("abc123", "abc124").
+		WillReturnRows(rows)
+
+	router := newRouter(repositories.NewSQLUserRepository(db), DefaultLimits)
+	actualBody := execute(t, router, GraphQLRequest{
+		Query: `{
+			jane: user(id: "abc123") { email }
+			john: user(id: "abc124") { email }
+			again: user(id: "abc123") { firstName }
+		}`,
+	})
+
+	assert.Equal(
+		t,
+		map[string]interface{}{
+			"jane":  map[string]interface{}{"email": "jane.doe@mail.com"},
+			"john":  map[string]interface{}{"email": "john.doe@mail.com"},
+			"again": map[string]interface{}{"firstName": "Jane"},
+		},
+		actualBody["data"],
+		"Should match response data",
+	)
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should load all users in one query")
+}
+
+func TestHandler_UsersConnection(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	for i := 0; i < 3; i++ {
+		err := userRepository.CreateUser(context.Background(), &models.User{
+			Id:        fmt.Sprintf("abc12%d", i),
+			FirstName: "Jane",
+			LastName:  "Doe",
+			Email:     fmt.Sprintf("jane.doe.%d@mail.com", i),
+		})
+		if err != nil {
+			t.Fatalf("error creating user: %v", err)
+		}
+	}
+	router := newRouter(userRepository, DefaultLimits)
+
+	query := `query($after: String) {
+		users(first: 2, after: $after) {
+			edges { node { id } }
+			pageInfo { hasNextPage endCursor }
+		}
+	}`
+
+	first := execute(t, router, GraphQLRequest{Query: query})
+	firstPage := first["data"].(map[string]interface{})["users"].(map[string]interface{})
+	pageInfo := firstPage["pageInfo"].(map[string]interface{})
+
+	assert.Len(t, firstPage["edges"], 2, "Should return first page")
+	assert.Equal(t, true, pageInfo["hasNextPage"], "Should have next page")
+
+	second := execute(t, router, GraphQLRequest{
+		Query:     query,
+		Variables: map[string]interface{}{"after": pageInfo["endCursor"]},
+	})
+	secondPage := second["data"].(map[string]interface{})["users"].(map[string]interface{})
+
+	assert.Equal(
+		t,
+		[]interface{}{
+			map[string]interface{}{"node": map[string]interface{}{"id": "abc122"}},
+		},
+		secondPage["edges"],
+		"Should return remaining users",
+	)
+	assert.Equal(
+		t,
+		false,
+		secondPage["pageInfo"].(map[string]interface{})["hasNextPage"],
+		"Should not have next page",
+	)
+}
+
+func TestHandler_Limits(t *testing.T) {
+	testCases := []struct {
+		query         string
+		expectedError string
+		reason        string
+	}{
+		{
+			query:         `{ user(id: "abc123") { id } }`,
+			expectedError: "",
+			reason:        "Should execute queries within limits",
+		},
+		{
+			query:         `{ users { edges { node { id } } } }`,
+			expectedError: "query depth 4 exceeds maximum of 3",
+			reason:        "Should reject queries that are too deep",
+		},
+		{
+			query:         `{ users(first: 100) { pageInfo { hasNextPage endCursor } } }`,
+			expectedError: "query complexity 301 exceeds maximum of 50",
+			reason:        "Should reject queries that are too complex",
+		},
+	}
+
+	router := newRouter(repositories.NewMemoryUserRepository(), Limits{
+		MaxDepth:      3,
+		MaxComplexity: 50,
+	})
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			actualBody := execute(t, router, GraphQLRequest{Query: tc.query})
+
+			if tc.expectedError == "" {
+				assert.Nil(t, actualBody["errors"], "Should not return errors")
+				return
+			}
+			errors := actualBody["errors"].([]interface{})
+			assert.Equal(
+				t,
+				tc.expectedError,
+				errors[0].(map[string]interface{})["message"],
+				"Should match error message",
+			)
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package graphqlapi
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+}
+
+var DefaultLimits = Limits{
+	MaxDepth:      8,
+	MaxComplexity: 500,
+}
+
+var connectionFields = map[string]bool{
+	"users": true,
+}
+
+type analyzer struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	visiting  map[string]bool
+}
+
+func (limits Limits) check(document *ast.Document, variables map[string]any) error {
+	analyzer := &analyzer{
+		fragments: map[string]*ast.FragmentDefinition{},
+		variables: variables,
+		visiting:  map[string]bool{},
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			analyzer.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		depth, complexity := analyzer.measure(operation.SelectionSet)
+		if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds maximum of %d", depth, limits.MaxDepth)
+		}
+		if limits.MaxComplexity > 0 && complexity > limits.MaxComplexity {
+			return fmt.Errorf(
+				"query complexity %d exceeds maximum of %d",
+				complexity,
+				limits.MaxComplexity,
+			)
+		}
+	}
+	return nil
+}
+
+func (analyzer *analyzer) measure(selectionSet *ast.SelectionSet) (int, int) {
+	if selectionSet == nil {
+		return 0, 0
+	}
+
+	maxDepth, complexity := 0, 0
+	for _, selection := range selectionSet.Selections {
+		var depth, cost int
+		switch selection := selection.(type) {
+		case *ast.Field:
+			childDepth, childComplexity := analyzer.measure(selection.SelectionSet)
+			depth = childDepth + 1
+			cost = 1 + childComplexity*analyzer.multiplier(selection)
+		case *ast.InlineFragment:
+			depth, cost = analyzer.measure(selection.SelectionSet)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := analyzer.fragments[name]
+			if !ok || analyzer.visiting[name] {
+				continue
+			}
+			analyzer.visiting[name] = true
+			depth, cost = analyzer.measure(fragment.SelectionSet)
+			analyzer.visiting[name] = false
+		}
+
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+		complexity += cost
+	}
+	return maxDepth, complexity
+}
+
+func (analyzer *analyzer) multiplier(field *ast.Field) int {
+	for _, argument := range field.Arguments {
+		if argument.Name.Value != "first" {
+			continue
+		}
+
+		switch value := argument.Value.(type) {
+		case *ast.IntValue:
+			if first, err := strconv.Atoi(value.Value); err == nil && first > 0 {
+				return first
+			}
+		case *ast.Variable:
+			if first, ok := toInt(analyzer.variables[value.Name.Value]); ok && first > 0 {
+				return first
+			}
+		}
+		return defaultPageSize
+	}
+	if connectionFields[field.Name.Value] {
+		return defaultPageSize
+	}
+	return 1
+}
+
+func toInt(value any) (int, bool) {
+	switch value := value.(type) {
+	case int:
+		return value, true
+	case float64:
+		return int(value), true
+	}
+	return 0, false
+}
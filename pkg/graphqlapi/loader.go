@@ -0,0 +1,94 @@
+package graphqlapi
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+type UserLoader struct {
+	ctx            context.Context
+	userRepository repositories.UserRepository
+	mu             sync.Mutex
+	batch          *userBatch
+}
+
+type userBatch struct {
+	once  sync.Once
+	ids   []string
+	seen  map[string]bool
+	users map[string]*models.User
+	err   error
+}
+
+func NewUserLoader(
+	ctx context.Context,
+	userRepository repositories.UserRepository,
+) *UserLoader {
+	return &UserLoader{
+		ctx:            ctx,
+		userRepository: userRepository,
+	}
+}
+
+func (loader *UserLoader) Load(id string) func() (*models.User, error) {
+	loader.mu.Lock()
+	defer loader.mu.Unlock()
+
+	if loader.batch == nil {
+		loader.batch = &userBatch{seen: map[string]bool{}}
+	}
+	batch := loader.batch
+	if !batch.seen[id] {
+		batch.seen[id] = true
+		batch.ids = append(batch.ids, id)
+	}
+
+	return func() (*models.User, error) {
+		batch.once.Do(func() {
+			loader.dispatch(batch)
+		})
+		if batch.err != nil {
+			return nil, batch.err
+		}
+		user, ok := batch.users[id]
+		if !ok {
+			return nil, repositories.ErrUserNotFound
+		}
+		return user, nil
+	}
+}
+
+func (loader *UserLoader) dispatch(batch *userBatch) {
+	loader.mu.Lock()
+	if loader.batch == batch {
+		loader.batch = nil
+	}
+	loader.mu.Unlock()
+
+	sort.Strings(batch.ids)
+	users, err := loader.userRepository.GetUsersByIds(loader.ctx, batch.ids)
+	if err != nil {
+		batch.err = err
+		return
+	}
+
+	batch.users = make(map[string]*models.User, len(users))
+	for _, user := range users {
+		batch.users[user.Id] = user
+	}
+}
+
+type loaderKey struct{}
+
+func withUserLoader(ctx context.Context, loader *UserLoader) context.Context {
+	return context.WithValue(ctx, loaderKey{}, loader)
+}
+
+func userLoaderFromContext(ctx context.Context) (*UserLoader, bool) {
+	loader, ok := ctx.Value(loaderKey{}).(*UserLoader)
+	return loader, ok
+}
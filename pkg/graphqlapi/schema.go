@@ -0,0 +1,313 @@
+package graphqlapi
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/graphql-go/graphql"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	cursorPrefix    = "offset:"
+)
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+		"firstName": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"lastName":  &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"email":     &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+var userEdgeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserEdge",
+	Fields: graphql.Fields{
+		"cursor": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"node":   &graphql.Field{Type: graphql.NewNonNull(userType)},
+	},
+})
+
+var pageInfoType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "PageInfo",
+	Fields: graphql.Fields{
+		"hasNextPage": &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+		"endCursor":   &graphql.Field{Type: graphql.String},
+	},
+})
+
+var userConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "UserConnection",
+	Fields: graphql.Fields{
+		"edges":    &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(userEdgeType))},
+		"pageInfo": &graphql.Field{Type: graphql.NewNonNull(pageInfoType)},
+	},
+})
+
+var userInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UserInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"firstName": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"lastName":  &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"email":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+	},
+})
+
+type resolver struct {
+	userRepository repositories.UserRepository
+}
+
+func newSchema(userRepository repositories.UserRepository) graphql.Schema {
+	resolver := &resolver{userRepository: userRepository}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: userType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolver.user,
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(userConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"first": &graphql.ArgumentConfig{Type: graphql.Int},
+					"after": &graphql.ArgumentConfig{Type: graphql.String},
+				},
+				Resolve: resolver.users,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(userInputType)},
+				},
+				Resolve: resolver.createUser,
+			},
+			"updateUser": &graphql.Field{
+				Type: graphql.NewNonNull(userType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(userInputType)},
+				},
+				Resolve: resolver.updateUser,
+			},
+			"deleteUser": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: resolver.deleteUser,
+			},
+		},
+	})
+
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+	if err != nil {
+		panic(fmt.Sprintf("invalid graphql schema: %v", err))
+	}
+	return schema
+}
+
+func (resolver *resolver) user(params graphql.ResolveParams) (interface{}, error) {
+	id, _ := params.Args["id"].(string)
+
+	var load func() (*models.User, error)
+	if loader, ok := userLoaderFromContext(params.Context); ok {
+		load = loader.Load(id)
+	} else {
+		load = func() (*models.User, error) {
+			return resolver.userRepository.GetUserById(params.Context, id)
+		}
+	}
+
+	return func() (interface{}, error) {
+		user, err := load()
+		if errors.Is(err, repositories.ErrUserNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			log.Printf("error getting user: %v", err)
+			return nil, errors.New("error retrieving user")
+		}
+		return userModelToMap(user), nil
+	}, nil
+}
+
+func (resolver *resolver) users(params graphql.ResolveParams) (interface{}, error) {
+	first := defaultPageSize
+	if value, ok := params.Args["first"].(int); ok {
+		first = value
+	}
+	if first < 1 || first > maxPageSize {
+		return nil, fmt.Errorf("first must be between 1 and %d", maxPageSize)
+	}
+
+	offset := 0
+	if after, ok := params.Args["after"].(string); ok {
+		var err error
+		offset, err = decodeCursor(after)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	users, err := resolver.userRepository.GetAllUsers(params.Context, repositories.ListOptions{
+		Limit:  first + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		log.Printf("error getting users: %v", err)
+		return nil, errors.New("error retrieving users")
+	}
+
+	hasNextPage := len(users) > first
+	if hasNextPage {
+		users = users[:first]
+	}
+
+	edges := make([]map[string]interface{}, len(users))
+	var endCursor interface{}
+	for i, user := range users {
+		cursor := encodeCursor(offset + i + 1)
+		edges[i] = map[string]interface{}{
+			"cursor": cursor,
+			"node":   userModelToMap(user),
+		}
+		endCursor = cursor
+	}
+
+	return map[string]interface{}{
+		"edges": edges,
+		"pageInfo": map[string]interface{}{
+			"hasNextPage": hasNextPage,
+			"endCursor":   endCursor,
+		},
+	}, nil
+}
+
+func (resolver *resolver) createUser(params graphql.ResolveParams) (interface{}, error) {
+	userRequest, err := userInputToRequest(params.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	user := userRequestToUserModel(userRequest)
+	err = resolver.userRepository.CreateUser(params.Context, user)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return nil, fmt.Errorf("a user with email %q already exists", user.Email)
+	}
+	if err != nil {
+		log.Printf("error creating user: %v", err)
+		return nil, errors.New("error creating user")
+	}
+	return userModelToMap(user), nil
+}
+
+func (resolver *resolver) updateUser(params graphql.ResolveParams) (interface{}, error) {
+	id, _ := params.Args["id"].(string)
+	userRequest, err := userInputToRequest(params.Args["input"])
+	if err != nil {
+		return nil, err
+	}
+
+	updates := userRequestToUserModel(userRequest)
+	updatedUser, err := resolver.userRepository.UpdateUserById(params.Context, id, updates)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return nil, fmt.Errorf("no user with id %q exists", id)
+	}
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return nil, fmt.Errorf("a user with email %q already exists", updates.Email)
+	}
+	if err != nil {
+		log.Printf("error updating user: %v", err)
+		return nil, errors.New("error updating user")
+	}
+	return userModelToMap(updatedUser), nil
+}
+
+func (resolver *resolver) deleteUser(params graphql.ResolveParams) (interface{}, error) {
+	id, _ := params.Args["id"].(string)
+
+	err := resolver.userRepository.DeleteUserById(params.Context, id)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		log.Printf("error deleting user: %v", err)
+		return nil, errors.New("error deleting user")
+	}
+	return true, nil
+}
+
+func userInputToRequest(input interface{}) (schemas.UserRequest, error) {
+	fields, _ := input.(map[string]interface{})
+	firstName, _ := fields["firstName"].(string)
+	lastName, _ := fields["lastName"].(string)
+	email, _ := fields["email"].(string)
+
+	userRequest := schemas.UserRequest{
+		FirstName: firstName,
+		LastName:  lastName,
+		Email:     email,
+	}
+	if err := binding.Validator.ValidateStruct(&userRequest); err != nil {
+		return userRequest, fmt.Errorf("invalid input: %v", err)
+	}
+	return userRequest, nil
+}
+
+func userRequestToUserModel(userRequest schemas.UserRequest) *models.User {
+	return &models.User{
+		FirstName: userRequest.FirstName,
+		LastName:  userRequest.LastName,
+		Email:     userRequest.Email,
+	}
+}
+
+func userModelToMap(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"id":        user.Id,
+		"firstName": user.FirstName,
+		"lastName":  user.LastName,
+		"email":     user.Email,
+	}
+}
+
+func encodeCursor(offset int) string {
+	return base64.StdEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(offset)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	decoded, err := base64.StdEncoding.DecodeString(cursor)
+	if err != nil || !strings.HasPrefix(string(decoded), cursorPrefix) {
+		return 0, errors.New("invalid cursor")
+	}
+	offset, err := strconv.Atoi(strings.TrimPrefix(string(decoded), cursorPrefix))
+	if err != nil || offset < 0 {
+		return 0, errors.New("invalid cursor")
+	}
+	return offset, nil
+}
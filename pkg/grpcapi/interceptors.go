@@ -0,0 +1,124 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/johannaojeling/go-rest-api/pkg/audit"
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func LoggingInterceptor(
+	ctx context.Context,
+	request any,
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	start := time.Now()
+	response, err := handler(ctx, request)
+	log.Printf(
+		"[GRPC] %s | %s | %v",
+		info.FullMethod,
+		status.Code(err),
+		time.Since(start),
+	)
+	return response, err
+}
+
+func AuthInterceptor(authenticator auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if authenticator == nil {
+			return nil, status.Error(codes.Unauthenticated, "authentication is not configured")
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "missing authorization metadata")
+		}
+
+		token, ok := auth.BearerToken(values[0])
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "expecting bearer token")
+		}
+
+		principal, err := authenticator.Authenticate(ctx, token)
+		if err != nil {
+			log.Printf("error authenticating request: %v", err)
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(auth.WithPrincipal(ctx, principal), request)
+	}
+}
+
+func TenantInterceptor(resolver *tenancy.Resolver) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		request any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		var requested, authority string
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(strings.ToLower(tenancy.Header)); len(values) > 0 {
+			requested = values[0]
+		}
+		if values := md.Get(":authority"); len(values) > 0 {
+			authority = values[0]
+		}
+
+		principal, _ := auth.PrincipalFromContext(ctx)
+		tenantId, err := resolver.Resolve(ctx, principal, requested, authority)
+		if errors.Is(err, tenancy.ErrTenantMismatch) {
+			return nil, status.Error(
+				codes.PermissionDenied,
+				"token is not valid for the requested tenant",
+			)
+		}
+		if errors.Is(err, tenancy.ErrUnknownTenant) {
+			return nil, status.Error(codes.NotFound, "unknown tenant")
+		}
+		if err != nil {
+			log.Printf("error resolving tenant: %v", err)
+			return nil, status.Error(codes.Internal, "error resolving tenant")
+		}
+		return handler(tenancy.WithTenant(ctx, tenantId), request)
+	}
+}
+
+func AuditInterceptor(
+	ctx context.Context,
+	request any,
+	_ *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (any, error) {
+	var requestMetadata audit.Metadata
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(strings.ToLower(audit.RequestIdHeader)); len(values) > 0 {
+		requestMetadata.RequestId = values[0]
+	} else {
+		requestMetadata.RequestId = audit.NewRequestId()
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			requestMetadata.IP = host
+		}
+	}
+	return handler(audit.WithMetadata(ctx, requestMetadata), request)
+}
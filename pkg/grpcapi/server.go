@@ -0,0 +1,46 @@
+package grpcapi
+
+import (
+	"net/http"
+	"strings"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	usersv1 "github.com/johannaojeling/go-rest-api/pkg/pb/users/v1"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func NewServer(
+	userRepository repositories.UserRepository,
+	authenticator auth.Authenticator,
+	tenantResolver *tenancy.Resolver,
+) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{
+		LoggingInterceptor,
+		AuditInterceptor,
+		AuthInterceptor(authenticator),
+	}
+	if tenantResolver != nil {
+		interceptors = append(interceptors, TenantInterceptor(tenantResolver))
+	}
+
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...))
+	usersv1.RegisterUserServiceServer(server, NewUserServer(userRepository))
+	return server
+}
+
+func Multiplex(grpcServer *grpc.Server, httpHandler http.Handler) http.Handler {
+	handler := http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		contentType := request.Header.Get("Content-Type")
+		if request.ProtoMajor == 2 && strings.HasPrefix(contentType, "application/grpc") {
+			grpcServer.ServeHTTP(writer, request)
+			return
+		}
+		httpHandler.ServeHTTP(writer, request)
+	})
+	return h2c.NewHandler(handler, &http2.Server{})
+}
@@ -0,0 +1,224 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	usersv1 "github.com/johannaojeling/go-rest-api/pkg/pb/users/v1"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+const (
+	bufferSize = 1024 * 1024
+	token      = "secret"
+)
+
+type Suite struct {
+	suite.Suite
+	server *grpc.Server
+	conn   *grpc.ClientConn
+	client usersv1.UserServiceClient
+}
+
+func (s *Suite) SetupTest() {
+	listener := bufconn.Listen(bufferSize)
+	authenticator := auth.NewStaticTokenAuthenticator(map[string]*auth.Principal{
+		token: {Subject: "tester"},
+	})
+	s.server = NewServer(repositories.NewMemoryUserRepository(), authenticator, nil)
+	go func() {
+		_ = s.server.Serve(listener)
+	}()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		s.T().Fatalf("error dialing bufnet: %v", err)
+	}
+	s.conn = conn
+	s.client = usersv1.NewUserServiceClient(conn)
+}
+
+func (s *Suite) TearDownTest() {
+	_ = s.conn.Close()
+	s.server.Stop()
+}
+
+func TestUserServer(t *testing.T) {
+	suite.Run(t, &Suite{})
+}
+
+func (s *Suite) authorized() context.Context {
+	return metadata.AppendToOutgoingContext(
+		context.Background(),
+		"authorization",
+		"Bearer "+token,
+	)
+}
+
+func (s *Suite) TestUserServer_Auth() {
+	testCases := []struct {
+		ctx          context.Context
+		expectedCode codes.Code
+		reason       string
+	}{
+		{
+			ctx:          context.Background(),
+			expectedCode: codes.Unauthenticated,
+			reason:       "Should reject calls without authorization metadata",
+		},
+		{
+			ctx: metadata.AppendToOutgoingContext(
+				context.Background(),
+				"authorization",
+				"Bearer wrong",
+			),
+			expectedCode: codes.Unauthenticated,
+			reason:       "Should reject calls with invalid token",
+		},
+		{
+			ctx:          s.authorized(),
+			expectedCode: codes.OK,
+			reason:       "Should accept calls with valid token",
+		},
+	}
+
+	for i, tc := range testCases {
+		s.T().Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			_, err := s.client.ListUsers(tc.ctx, &usersv1.ListUsersRequest{})
+			assert.Equal(t, tc.expectedCode, status.Code(err), "Should match status code")
+		})
+	}
+}
+
+func (s *Suite) TestUserServer_UserLifecycle() {
+	ctx := s.authorized()
+
+	created, err := s.client.CreateUser(ctx, &usersv1.CreateUserRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane.doe@mail.com",
+	})
+	s.Require().NoError(err, "Should create user")
+	s.NotEmpty(created.GetId(), "Should assign id")
+
+	fetched, err := s.client.GetUser(ctx, &usersv1.GetUserRequest{Id: created.GetId()})
+	s.Require().NoError(err, "Should get user")
+	s.Equal(created.GetEmail(), fetched.GetEmail(), "Should match email")
+
+	updated, err := s.client.UpdateUser(ctx, &usersv1.UpdateUserRequest{
+		Id:        created.GetId(),
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane@mail.com",
+	})
+	s.Require().NoError(err, "Should update user")
+	s.False(updated.GetCreated(), "Should update existing user")
+	s.Equal("jane@mail.com", updated.GetUser().GetEmail(), "Should match updated email")
+
+	listed, err := s.client.ListUsers(ctx, &usersv1.ListUsersRequest{})
+	s.Require().NoError(err, "Should list users")
+	s.Len(listed.GetUsers(), 1, "Should list created user")
+
+	_, err = s.client.DeleteUser(ctx, &usersv1.DeleteUserRequest{Id: created.GetId()})
+	s.Require().NoError(err, "Should delete user")
+
+	_, err = s.client.GetUser(ctx, &usersv1.GetUserRequest{Id: created.GetId()})
+	s.Equal(codes.NotFound, status.Code(err), "Should map missing user to NotFound")
+}
+
+func (s *Suite) TestUserServer_InvalidArgument() {
+	_, err := s.client.CreateUser(s.authorized(), &usersv1.CreateUserRequest{
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane",
+	})
+	s.Equal(
+		codes.InvalidArgument,
+		status.Code(err),
+		"Should map invalid request to InvalidArgument",
+	)
+}
+
+func TestNewServer_WithoutAuthenticator(t *testing.T) {
+	listener := bufconn.Listen(bufferSize)
+	server := NewServer(repositories.NewMemoryUserRepository(), nil, nil)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("error dialing bufnet: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	_, err = usersv1.NewUserServiceClient(conn).ListUsers(ctx, &usersv1.ListUsersRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err), "Should reject every call")
+}
+
+func TestMultiplex(t *testing.T) {
+	authenticator := auth.NewStaticTokenAuthenticator(map[string]*auth.Principal{
+		token: {Subject: "tester"},
+	})
+	grpcServer := NewServer(repositories.NewMemoryUserRepository(), authenticator, nil)
+	defer grpcServer.Stop()
+	httpHandler := http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_, _ = writer.Write([]byte("ok"))
+	})
+	server := httptest.NewServer(Multiplex(grpcServer, httpHandler))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("error sending request: %v", err)
+	}
+	defer response.Body.Close()
+	body, _ := io.ReadAll(response.Body)
+	assert.Equal(t, "ok", string(body), "Should serve HTTP/1 requests with HTTP handler")
+
+	conn, err := grpc.DialContext(
+		context.Background(),
+		strings.TrimPrefix(server.URL, "http://"),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("error dialing server: %v", err)
+	}
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+	_, err = usersv1.NewUserServiceClient(conn).ListUsers(ctx, &usersv1.ListUsersRequest{})
+	assert.NoError(t, err, "Should serve gRPC calls over h2c")
+}
@@ -0,0 +1,192 @@
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/gin-gonic/gin/binding"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	usersv1 "github.com/johannaojeling/go-rest-api/pkg/pb/users/v1"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/schemas"
+)
+
+type UserServer struct {
+	usersv1.UnimplementedUserServiceServer
+	userRepository repositories.UserRepository
+}
+
+func NewUserServer(userRepository repositories.UserRepository) *UserServer {
+	return &UserServer{
+		userRepository: userRepository,
+	}
+}
+
+func (server *UserServer) CreateUser(
+	ctx context.Context,
+	request *usersv1.CreateUserRequest,
+) (*usersv1.User, error) {
+	userRequest := schemas.UserRequest{
+		FirstName: request.GetFirstName(),
+		LastName:  request.GetLastName(),
+		Email:     request.GetEmail(),
+	}
+	if err := binding.Validator.ValidateStruct(&userRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+
+	user := userRequestToUserModel(userRequest)
+	err := server.userRepository.CreateUser(ctx, user)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return nil, status.Errorf(
+			codes.AlreadyExists,
+			"a user with email %q already exists",
+			user.Email,
+		)
+	}
+	if err != nil {
+		log.Printf("error creating user: %v", err)
+		return nil, status.Error(codes.Internal, "error creating user")
+	}
+	return userModelToProto(user), nil
+}
+
+func (server *UserServer) GetUser(
+	ctx context.Context,
+	request *usersv1.GetUserRequest,
+) (*usersv1.User, error) {
+	id := request.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid request, expecting id")
+	}
+
+	user, err := server.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, toStatus(err, id, "error retrieving user")
+	}
+	return userModelToProto(user), nil
+}
+
+func (server *UserServer) ListUsers(
+	ctx context.Context,
+	request *usersv1.ListUsersRequest,
+) (*usersv1.ListUsersResponse, error) {
+	listQuery := schemas.UserListQuery{
+		Limit:  int(request.GetLimit()),
+		Offset: int(request.GetOffset()),
+	}
+	if err := binding.Validator.ValidateStruct(&listQuery); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+
+	users, err := server.userRepository.GetAllUsers(ctx, repositories.ListOptions{
+		Limit:  listQuery.Limit,
+		Offset: listQuery.Offset,
+	})
+	if err != nil {
+		log.Printf("error getting users: %v", err)
+		return nil, status.Error(codes.Internal, "error retrieving users")
+	}
+
+	response := &usersv1.ListUsersResponse{
+		Users: make([]*usersv1.User, len(users)),
+	}
+	for i, user := range users {
+		response.Users[i] = userModelToProto(user)
+	}
+	return response, nil
+}
+
+func (server *UserServer) UpdateUser(
+	ctx context.Context,
+	request *usersv1.UpdateUserRequest,
+) (*usersv1.UpdateUserResponse, error) {
+	id := request.GetId()
+	userRequest := schemas.UserRequest{
+		FirstName: request.GetFirstName(),
+		LastName:  request.GetLastName(),
+		Email:     request.GetEmail(),
+	}
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid request, expecting id")
+	}
+	if err := binding.Validator.ValidateStruct(&userRequest); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid request: %v", err)
+	}
+
+	updates := userRequestToUserModel(userRequest)
+	updatedUser, err := server.userRepository.UpdateUserById(ctx, id, updates)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		updates.Id = id
+		err = server.userRepository.CreateUser(ctx, updates)
+		if errors.Is(err, repositories.ErrEmailTaken) {
+			return nil, status.Errorf(
+				codes.AlreadyExists,
+				"a user with email %q already exists",
+				updates.Email,
+			)
+		}
+		if err != nil {
+			log.Printf("error creating user: %v", err)
+			return nil, status.Error(codes.Internal, "error creating user")
+		}
+		return &usersv1.UpdateUserResponse{User: userModelToProto(updates), Created: true}, nil
+	}
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		return nil, status.Errorf(
+			codes.AlreadyExists,
+			"a user with email %q already exists",
+			updates.Email,
+		)
+	}
+	if err != nil {
+		log.Printf("error updating user: %v", err)
+		return nil, status.Error(codes.Internal, "error updating user")
+	}
+	return &usersv1.UpdateUserResponse{User: userModelToProto(updatedUser)}, nil
+}
+
+func (server *UserServer) DeleteUser(
+	ctx context.Context,
+	request *usersv1.DeleteUserRequest,
+) (*usersv1.DeleteUserResponse, error) {
+	id := request.GetId()
+	if id == "" {
+		return nil, status.Error(codes.InvalidArgument, "invalid request, expecting id")
+	}
+
+	err := server.userRepository.DeleteUserById(ctx, id)
+	if err != nil {
+		return nil, toStatus(err, id, "error deleting user")
+	}
+	return &usersv1.DeleteUserResponse{}, nil
+}
+
+func toStatus(err error, id string, message string) error {
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		return status.Errorf(codes.NotFound, "no user with id %q exists", id)
+	}
+	log.Printf("%s: %v", message, err)
+	return status.Error(codes.Internal, message)
+}
+
+func userRequestToUserModel(userRequest schemas.UserRequest) *models.User {
+	return &models.User{
+		FirstName: userRequest.FirstName,
+		LastName:  userRequest.LastName,
+		Email:     userRequest.Email,
+	}
+}
+
+func userModelToProto(user *models.User) *usersv1.User {
+	return &usersv1.User{
+		Id:        user.Id,
+		FirstName: user.FirstName,
+		LastName:  user.LastName,
+		Email:     user.Email,
+	}
+}
@@ -0,0 +1,58 @@
+package history
+
+import (
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type field struct {
+	name string
+	get  func(user *models.User) string
+	set  func(user *models.User, value string)
+}
+
+var fields = []field{
+	{
+		name: "first_name",
+		get:  func(user *models.User) string { return user.FirstName },
+		set:  func(user *models.User, value string) { user.FirstName = value },
+	},
+	{
+		name: "last_name",
+		get:  func(user *models.User) string { return user.LastName },
+		set:  func(user *models.User, value string) { user.LastName = value },
+	},
+	{
+		name: "email",
+		get:  func(user *models.User) string { return user.Email },
+		set:  func(user *models.User, value string) { user.Email = value },
+	},
+}
+
+func Diff(before *models.User, after *models.User, changedAt time.Time) []*models.UserFieldChange {
+	var changes []*models.UserFieldChange
+	for _, field := range fields {
+		oldValue, newValue := field.get(before), field.get(after)
+		if oldValue == newValue {
+			continue
+		}
+		changes = append(changes, &models.UserFieldChange{
+			UserId:    after.Id,
+			Field:     field.name,
+			OldValue:  oldValue,
+			NewValue:  newValue,
+			ChangedAt: changedAt,
+		})
+	}
+	return changes
+}
+
+func revert(user *models.User, change *models.UserFieldChange) {
+	for _, field := range fields {
+		if field.name == change.Field {
+			field.set(user, change.OldValue)
+			return
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package history
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	return db, mock
+}
+
+func TestWriter_ChangedFields(t *testing.T) {
+	db, mock := newMockDB(t)
+	userRepository := repositories.NewSQLUserRepository(db, NewWriter())
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{Subject: "support"})
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "first_name", "last_name", "email"}).
+			AddRow("abc123", "Jane", "Doe", "jane.doe@mail.com"))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "user_history"`)).
+		WithArgs(
+			"abc123",
+			"email",
+			"jane.doe@mail.com",
+			"jane@mail.com",
+			"support",
+			sqlmock.AnyArg(),
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	_, err := userRepository.UpdateUserById(
+		ctx,
+		"abc123",
+		&models.User{FirstName: "Jane", LastName: "Doe", Email: "jane@mail.com"},
+	)
+
+	assert.NoError(t, err, "Should update user")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should only record the changed field")
+}
+
+func TestReader_GetUserAsOf(t *testing.T) {
+	db, mock := newMockDB(t)
+	userRepository := repositories.NewMemoryUserRepository()
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Email: "jane@mail.com"}
+	if err := userRepository.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	reader := NewReader(userRepository, repositories.NewSQLHistoryRepository(db), 24*time.Hour)
+
+	asOf := time.Now().Add(time.Minute)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "field", "old_value", "new_value"}).
+		AddRow(3, user.Id, "email", "j.doe@mail.com", "jane@mail.com").
+		AddRow(2, user.Id, "email", "jane.doe@mail.com", "j.doe@mail.com")
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "user_history" WHERE user_id = $1 AND changed_at > $2 ORDER BY id DESC`,
+	)).
+		WithArgs(user.Id, asOf).
+		WillReturnRows(rows)
+
+	actual, err := reader.GetUserAsOf(context.Background(), user.Id, asOf)
+
+	assert.NoError(t, err, "Should reconstruct user")
+	assert.Equal(t, "jane.doe@mail.com", actual.Email, "Should revert later changes")
+	assert.Equal(t, "Jane", actual.FirstName, "Should keep unchanged fields")
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = reader.GetUserAsOf(context.Background(), user.Id, user.CreatedAt.Add(-time.Second))
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound, "Should not exist before creation")
+
+	_, err = reader.GetUserAsOf(context.Background(), user.Id, time.Now().Add(-48*time.Hour))
+	assert.ErrorIs(t, err, ErrOutsideRetention, "Should reject times outside retention")
+}
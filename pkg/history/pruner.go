@@ -0,0 +1,50 @@
+package history
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+type Pruner struct {
+	historyRepository repositories.HistoryRepository
+	retention         time.Duration
+	interval          time.Duration
+}
+
+func NewPruner(
+	historyRepository repositories.HistoryRepository,
+	retention time.Duration,
+	interval time.Duration,
+) *Pruner {
+	return &Pruner{
+		historyRepository: historyRepository,
+		retention:         retention,
+		interval:          interval,
+	}
+}
+
+func (pruner *Pruner) Run(ctx context.Context) {
+	ticker := time.NewTicker(pruner.interval)
+	defer ticker.Stop()
+
+	for {
+		deleted, err := pruner.historyRepository.DeleteChangesBefore(
+			ctx,
+			time.Now().Add(-pruner.retention),
+		)
+		if err != nil {
+			log.Printf("error pruning user history: %v", err)
+		} else if deleted > 0 {
+			log.Printf("pruned %d user history entries", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
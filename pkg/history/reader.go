@@ -0,0 +1,78 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+var ErrOutsideRetention = errors.New("requested time is outside the history retention window")
+
+type Reader struct {
+	userRepository    repositories.UserRepository
+	historyRepository repositories.HistoryRepository
+	retention         time.Duration
+	now               func() time.Time
+}
+
+func NewReader(
+	userRepository repositories.UserRepository,
+	historyRepository repositories.HistoryRepository,
+	retention time.Duration,
+) *Reader {
+	return &Reader{
+		userRepository:    userRepository,
+		historyRepository: historyRepository,
+		retention:         retention,
+		now:               time.Now,
+	}
+}
+
+func (reader *Reader) GetUserAsOf(
+	ctx context.Context,
+	id string,
+	asOf time.Time,
+) (*models.User, error) {
+	if reader.retention > 0 && asOf.Before(reader.now().Add(-reader.retention)) {
+		return nil, ErrOutsideRetention
+	}
+
+	// The user is read before its changes, so an update committed in between is
+	// reverted to the value it replaced instead of being missed.
+	user, err := reader.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if asOf.Before(user.CreatedAt) {
+		return nil, repositories.ErrUserNotFound
+	}
+
+	changes, err := reader.historyRepository.GetUserChangesAfter(ctx, id, asOf)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		revert(user, change)
+	}
+	if len(changes) > 0 {
+		user.UpdatedAt = asOf
+	}
+	return user, nil
+}
+
+// GetUserHistory returns the field changes of a user, newest first, or
+// repositories.ErrUserNotFound if no such user exists in the tenant of ctx.
+func (reader *Reader) GetUserHistory(
+	ctx context.Context,
+	id string,
+	options repositories.ListOptions,
+) ([]*models.UserFieldChange, error) {
+	_, err := reader.userRepository.GetUserById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return reader.historyRepository.GetUserHistory(ctx, id, options)
+}
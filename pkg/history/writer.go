@@ -0,0 +1,42 @@
+package history
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/audit"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+type Writer struct{}
+
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+func (writer *Writer) OnUserChange(tx *gorm.DB, change *repositories.UserChange) error {
+	if change.Operation != repositories.OperationUpdate {
+		return nil
+	}
+
+	changedAt := change.After.UpdatedAt
+	if changedAt.IsZero() {
+		changedAt = time.Now()
+	}
+	changes := Diff(change.Before, change.After, changedAt)
+	if len(changes) == 0 {
+		return nil
+	}
+
+	actor := audit.ActorFromContext(tx.Statement.Context)
+	for _, fieldChange := range changes {
+		fieldChange.Actor = actor
+	}
+	err := tx.Create(&changes).Error
+	if err != nil {
+		return fmt.Errorf("error writing user history: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package httpcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ParsePolicies parses semicolon separated route=directives entries, as
+// "GET /users/=public, max-age=60", into Cache-Control values by route.
+func ParsePolicies(value string) (map[string]string, error) {
+	policies := map[string]string{}
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, directives, ok := strings.Cut(entry, "=")
+		method, path, isRoute := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok || !isRoute || !strings.HasPrefix(path, "/") || strings.TrimSpace(directives) == "" {
+			return nil, fmt.Errorf("invalid policy %q, expecting METHOD /path=directives", entry)
+		}
+		policies[strings.ToUpper(method)+" "+path] = strings.TrimSpace(directives)
+	}
+	return policies, nil
+}
+
+// ETag returns a weak entity tag for a representation derived from parts.
+func ETag(parts ...any) string {
+	hash := sha256.Sum256([]byte(fmt.Sprint(parts...)))
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// NotModified reports whether a GET or HEAD request can be answered with 304
+// Not Modified. If-Modified-Since is only evaluated without If-None-Match.
+func NotModified(request *http.Request, etag string, lastModified time.Time) bool {
+	if request.Method != http.MethodGet && request.Method != http.MethodHead {
+		return false
+	}
+
+	if ifNoneMatch := request.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakMatch(candidate, etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	ifModifiedSince := request.Header.Get("If-Modified-Since")
+	if ifModifiedSince == "" || lastModified.IsZero() {
+		return false
+	}
+	since, err := http.ParseTime(ifModifiedSince)
+	if err != nil {
+		return false
+	}
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+func weakMatch(a string, b string) bool {
+	return strings.TrimPrefix(a, "W/") == strings.TrimPrefix(b, "W/")
+}
@@ -0,0 +1,101 @@
+package httpcache
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(
+		"GET /users/=public, max-age=60; GET /users/:id=private, no-cache",
+	)
+
+	assert.NoError(t, err, "Should parse policies")
+	assert.Equal(
+		t,
+		map[string]string{
+			"GET /users/":    "public, max-age=60",
+			"GET /users/:id": "private, no-cache",
+		},
+		policies,
+		"Should map routes to directives",
+	)
+
+	_, err = ParsePolicies("/users/=public")
+	assert.Error(t, err, "Should require method")
+
+	_, err = ParsePolicies("GET /users/=")
+	assert.Error(t, err, "Should require directives")
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2022, 3, 3, 12, 0, 0, 500, time.UTC)
+	etag := ETag(2, lastModified.UnixNano())
+
+	testCases := []struct {
+		method   string
+		headers  map[string]string
+		expected bool
+		reason   string
+	}{
+		{
+			method:   "GET",
+			headers:  map[string]string{"If-None-Match": `"other", ` + etag},
+			expected: true,
+			reason:   "Should match any listed ETag",
+		},
+		{
+			method:   "GET",
+			headers:  map[string]string{"If-None-Match": etag[2:]},
+			expected: true,
+			reason:   "Should compare ETags weakly",
+		},
+		{
+			method:   "HEAD",
+			headers:  map[string]string{"If-None-Match": "*"},
+			expected: true,
+			reason:   "Should match wildcard",
+		},
+		{
+			method: "GET",
+			headers: map[string]string{
+				"If-None-Match":     `"other"`,
+				"If-Modified-Since": "Thu, 03 Mar 2022 12:00:00 GMT",
+			},
+			expected: false,
+			reason:   "Should ignore If-Modified-Since with If-None-Match",
+		},
+		{
+			method:   "GET",
+			headers:  map[string]string{"If-Modified-Since": "Thu, 03 Mar 2022 12:00:00 GMT"},
+			expected: true,
+			reason:   "Should compare modification time in seconds",
+		},
+		{
+			method:   "GET",
+			headers:  map[string]string{"If-Modified-Since": "Thu, 03 Mar 2022 11:59:59 GMT"},
+			expected: false,
+			reason:   "Should be modified after time",
+		},
+		{
+			method:   "PUT",
+			headers:  map[string]string{"If-None-Match": etag},
+			expected: false,
+			reason:   "Should only apply to GET and HEAD",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.reason, func(t *testing.T) {
+			request, _ := http.NewRequest(tc.method, "/users/", nil)
+			for key, value := range tc.headers {
+				request.Header.Set(key, value)
+			}
+
+			assert.Equal(t, tc.expected, NotModified(request, etag, lastModified), tc.reason)
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/totp"
+)
+
+const (
+	skew               = 1
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	qrCodeSize         = 256
+	// A code has about a one in 10^6/3 chance to match, so an attacker with the
+	// password gets maxFailures tries per lockout.
+	maxFailures = 5
+	lockout     = 15 * time.Minute
+)
+
+var (
+	ErrAlreadyEnrolled = errors.New("mfa is already enabled")
+	ErrInvalidCode     = errors.New("invalid or already used code")
+	ErrLocked          = errors.New("too many invalid codes")
+)
+
+type Enrollment struct {
+	Secret string
+	URI    string
+}
+
+type Service struct {
+	mfaRepository repositories.MFARepository
+	issuer        string
+	now           func() time.Time
+}
+
+func NewService(mfaRepository repositories.MFARepository, issuer string) *Service {
+	return &Service{
+		mfaRepository: mfaRepository,
+		issuer:        issuer,
+		now:           time.Now,
+	}
+}
+
+// Enroll starts a new enrollment, replacing any unconfirmed one. MFA is only
+// enabled once the enrollment is confirmed with a code.
+func (service *Service) Enroll(ctx context.Context, user *models.User) (*Enrollment, error) {
+	factor, err := service.mfaRepository.GetTOTPFactor(ctx, user.Id)
+	if err != nil && !errors.Is(err, repositories.ErrMFANotEnrolled) {
+		return nil, err
+	}
+	if factor != nil && factor.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	err = service.mfaRepository.SaveTOTPFactor(
+		ctx,
+		&models.TOTPFactor{UserId: user.Id, Secret: secret},
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Enrollment{Secret: secret, URI: totp.URI(service.issuer, user.Email, secret)}, nil
+}
+
+func (service *Service) QRCode(ctx context.Context, user *models.User) ([]byte, error) {
+	factor, err := service.mfaRepository.GetTOTPFactor(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnrolled
+	}
+	uri := totp.URI(service.issuer, user.Email, factor.Secret)
+	return qrcode.Encode(uri, qrcode.Medium, qrCodeSize)
+}
+
+// Confirm enables MFA with the first code from the authenticator and returns
+// recovery codes, which are only stored hashed and cannot be shown again.
+func (service *Service) Confirm(ctx context.Context, userId string, code string) ([]string, error) {
+	factor, err := service.mfaRepository.GetTOTPFactor(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+	if factor.ConfirmedAt != nil {
+		return nil, ErrAlreadyEnrolled
+	}
+	step, ok := totp.Validate(factor.Secret, code, service.now(), skew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = securetoken.Hash(codes[i])
+	}
+
+	err = service.mfaRepository.ConfirmTOTPFactor(ctx, userId, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (service *Service) Enabled(ctx context.Context, userId string) (bool, error) {
+	factor, err := service.mfaRepository.GetTOTPFactor(ctx, userId)
+	if errors.Is(err, repositories.ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return factor.ConfirmedAt != nil, nil
+}
+
+// Verify accepts either a code from the authenticator or an unused recovery
+// code. After maxFailures invalid codes in a row the user is locked out for
+// the lockout period, over every MFA token they obtain, and ErrLocked is
+// returned.
+func (service *Service) Verify(ctx context.Context, userId string, code string) error {
+	factor, err := service.mfaRepository.GetTOTPFactor(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if factor.ConfirmedAt == nil {
+		return repositories.ErrMFANotEnrolled
+	}
+	now := service.now()
+	if factor.LockedUntil != nil && now.Before(*factor.LockedUntil) {
+		return ErrLocked
+	}
+
+	err = service.verify(ctx, factor, code, now)
+	if errors.Is(err, ErrInvalidCode) {
+		locked, err := service.mfaRepository.RecordTOTPFailure(
+			ctx,
+			userId,
+			maxFailures,
+			now.Add(lockout),
+		)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrLocked
+		}
+		return ErrInvalidCode
+	}
+	if err != nil {
+		return err
+	}
+	if factor.FailedAttempts > 0 {
+		return service.mfaRepository.ResetTOTPFailures(ctx, userId)
+	}
+	return nil
+}
+
+func (service *Service) verify(
+	ctx context.Context,
+	factor *models.TOTPFactor,
+	code string,
+	now time.Time,
+) error {
+	userId := factor.UserId
+	if step, ok := totp.Validate(factor.Secret, code, now, skew); ok {
+		used, err := service.mfaRepository.UseTOTPStep(ctx, userId, step)
+		if err != nil {
+			return err
+		}
+		if !used {
+			return ErrInvalidCode
+		}
+		return nil
+	}
+
+	used, err := service.mfaRepository.UseRecoveryCode(
+		ctx,
+		userId,
+		securetoken.Hash(normalizeRecoveryCode(code)),
+	)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidCode
+	}
+	return nil
+}
+
+func (service *Service) Reset(ctx context.Context, userId string) error {
+	return service.mfaRepository.DeleteMFA(ctx, userId)
+}
+
+func newRecoveryCode() (string, error) {
+	data := make([]byte, recoveryCodeLength)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", fmt.Errorf("error generating recovery code: %v", err)
+	}
+	code := base32.StdEncoding.EncodeToString(data)[:recoveryCodeLength]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != recoveryCodeLength {
+		return code
+	}
+	return code[:5] + "-" + code[5:]
+}
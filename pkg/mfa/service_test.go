@@ -0,0 +1,232 @@
+package mfa
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/totp"
+)
+
+const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+var factorColumns = []string{
+	"user_id",
+	"secret",
+	"confirmed_at",
+	"last_used_step",
+	"failed_attempts",
+	"locked_until",
+}
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	return db, mock
+}
+
+func newTestService(db *gorm.DB, now time.Time) *Service {
+	service := NewService(repositories.NewSQLMFARepository(db), "go-rest-api")
+	service.now = func() time.Time { return now }
+	return service
+}
+
+func expectFailure(mock sqlmock.Sqlmock, now time.Time, failedAttempts int) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(
+			sqlmock.NewRows(factorColumns).
+				AddRow("abc123", secret, now, 0, failedAttempts, nil),
+		)
+	if failedAttempts+1 < maxFailures {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "failed_attempts"=$1`)).
+			WithArgs(failedAttempts+1, sqlmock.AnyArg(), "abc123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	} else {
+		mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "failed_attempts"=$1,"locked_until"=$2`)).
+			WithArgs(0, now.Add(lockout), sqlmock.AnyArg(), "abc123").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectRecoveryCode(mock sqlmock.Sqlmock, code string, used int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_recovery_codes" SET "used_at"=$1`)).
+		WithArgs(sqlmock.AnyArg(), "abc123", securetoken.Hash(code)).
+		WillReturnResult(sqlmock.NewResult(0, used))
+	mock.ExpectCommit()
+}
+
+func TestService_Verify(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	step := totp.Step(now)
+	code, err := totp.Code(secret, step)
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	testCases := []struct {
+		reason   string
+		code     string
+		used     int64
+		expected error
+	}{
+		{
+			reason:   "Should accept current code",
+			code:     code,
+			used:     1,
+			expected: nil,
+		},
+		{
+			reason:   "Should reject replayed code",
+			code:     code,
+			used:     0,
+			expected: ErrInvalidCode,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.reason, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			service := newTestService(db, now)
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+				WithArgs("abc123").
+				WillReturnRows(
+					sqlmock.NewRows(factorColumns).AddRow("abc123", secret, now, 0, 0, nil),
+				)
+			mock.ExpectBegin()
+			mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "last_used_step"=$1`)).
+				WithArgs(step, sqlmock.AnyArg(), "abc123", step).
+				WillReturnResult(sqlmock.NewResult(0, tc.used))
+			mock.ExpectCommit()
+			if tc.used == 0 {
+				expectFailure(mock, now, 0)
+			}
+
+			err := service.Verify(context.Background(), "abc123", tc.code)
+
+			assert.ErrorIs(t, err, tc.expected, tc.reason)
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
+
+func TestService_VerifyRecoveryCode(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	db, mock := newMockDB(t)
+	service := newTestService(db, now)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(sqlmock.NewRows(factorColumns).AddRow("abc123", secret, now, 0, 0, nil))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_recovery_codes" SET "used_at"=$1`)).
+		WithArgs(sqlmock.AnyArg(), "abc123", securetoken.Hash("ABCDE-FGHIJ")).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := service.Verify(context.Background(), "abc123", "abcde-fghij")
+
+	assert.NoError(t, err, "Should accept recovery code")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+}
+
+func TestService_VerifyLockout(t *testing.T) {
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	code, err := totp.Code(secret, totp.Step(now))
+	if err != nil {
+		t.Fatalf("error generating code: %v", err)
+	}
+
+	testCases := []struct {
+		reason         string
+		code           string
+		failedAttempts int
+		lockedUntil    any
+		expect         func(mock sqlmock.Sqlmock)
+		expected       error
+	}{
+		{
+			reason:         "Should count invalid code",
+			code:           "000000",
+			failedAttempts: 1,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRecoveryCode(mock, "000000", 0)
+				expectFailure(mock, now, 1)
+			},
+			expected: ErrInvalidCode,
+		},
+		{
+			reason:         "Should lock after too many invalid codes",
+			code:           "000000",
+			failedAttempts: maxFailures - 1,
+			expect: func(mock sqlmock.Sqlmock) {
+				expectRecoveryCode(mock, "000000", 0)
+				expectFailure(mock, now, maxFailures-1)
+			},
+			expected: ErrLocked,
+		},
+		{
+			reason:      "Should reject valid code while locked",
+			code:        code,
+			lockedUntil: now.Add(time.Minute),
+			expect:      func(mock sqlmock.Sqlmock) {},
+			expected:    ErrLocked,
+		},
+		{
+			reason:         "Should reset failures after valid code",
+			code:           code,
+			failedAttempts: 2,
+			lockedUntil:    now.Add(-time.Minute),
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "last_used_step"=$1`)).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				mock.ExpectBegin()
+				mock.ExpectExec(regexp.QuoteMeta(`UPDATE "mfa_totp" SET "failed_attempts"=$1`)).
+					WithArgs(0, sqlmock.AnyArg(), "abc123").
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.reason, func(t *testing.T) {
+			db, mock := newMockDB(t)
+			service := newTestService(db, now)
+
+			mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "mfa_totp" WHERE user_id = $1`)).
+				WithArgs("abc123").
+				WillReturnRows(
+					sqlmock.NewRows(factorColumns).
+						AddRow("abc123", secret, now, 0, tc.failedAttempts, tc.lockedUntil),
+				)
+			tc.expect(mock)
+
+			err := service.Verify(context.Background(), "abc123", tc.code)
+
+			assert.ErrorIs(t, err, tc.expected, tc.reason)
+			assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+		})
+	}
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+type AuditEntry struct {
+	Id        int64  `gorm:"primaryKey;autoIncrement"`
+	Action    string `gorm:"not null;index"`
+	UserId    string `gorm:"not null;index"`
+	Actor     string `gorm:"not null;index"`
+	RequestId string
+	IP        string
+	Before    NullJSON  `gorm:"type:jsonb"`
+	After     NullJSON  `gorm:"type:jsonb"`
+	CreatedAt time.Time `gorm:"index"`
+}
+
+func (AuditEntry) TableName() string {
+	return "audit_log"
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"time"
+)
+
+type Credential struct {
+	UserId            string `gorm:"primaryKey"`
+	PasswordHash      string `gorm:"not null"`
+	PasswordChangedAt time.Time
+	CreatedAt         time.Time
+	UpdatedAt         time.Time
+	User              *User `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+)
+
+type Group struct {
+	Id          string `gorm:"primary_key;default:gen_random_uuid()"`
+	TenantId    string `gorm:"not null;default:'default';index"`
+	Name        string `gorm:"not null"`
+	Description string `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	Tenant      *Tenant `gorm:"constraint:OnDelete:RESTRICT"`
+}
+
+type GroupMember struct {
+	GroupId   string `gorm:"primaryKey"`
+	UserId    string `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+	Group     *Group `gorm:"constraint:OnDelete:CASCADE"`
+	User      *User  `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// GroupNesting makes the members of the child group members of the parent
+// group.
+type GroupNesting struct {
+	ParentId  string `gorm:"primaryKey"`
+	ChildId   string `gorm:"primaryKey;index"`
+	CreatedAt time.Time
+	Parent    *Group `gorm:"foreignKey:ParentId;constraint:OnDelete:CASCADE"`
+	Child     *Group `gorm:"foreignKey:ChildId;constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+type UserFieldChange struct {
+	Id        int64  `gorm:"primaryKey;autoIncrement"`
+	UserId    string `gorm:"not null;index:idx_user_history_user_changed_at,priority:1"`
+	Field     string `gorm:"not null"`
+	OldValue  string
+	NewValue  string
+	Actor     string    `gorm:"not null"`
+	ChangedAt time.Time `gorm:"not null;index;index:idx_user_history_user_changed_at,priority:2"`
+}
+
+func (UserFieldChange) TableName() string {
+	return "user_history"
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+type NullJSON []byte
+
+func (value NullJSON) Value() (driver.Value, error) {
+	if len(value) == 0 {
+		return nil, nil
+	}
+	return string(value), nil
+}
+
+func (value *NullJSON) Scan(src any) error {
+	switch src := src.(type) {
+	case nil:
+		*value = nil
+	case []byte:
+		*value = append(NullJSON{}, src...)
+	case string:
+		*value = NullJSON(src)
+	default:
+		return fmt.Errorf("cannot scan %T into NullJSON", src)
+	}
+	return nil
+}
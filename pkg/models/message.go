@@ -11,3 +11,21 @@ func NewErrorMessage(message string, a ...any) ErrorMessage {
 		Details: fmt.Sprintf(message, a...),
 	}
 }
+
+type FieldError struct {
+	In      string `json:"in"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+type ValidationErrorMessage struct {
+	Details string       `json:"details"`
+	Errors  []FieldError `json:"errors"`
+}
+
+func NewValidationErrorMessage(errors []FieldError) ValidationErrorMessage {
+	return ValidationErrorMessage{
+		Details: "request validation failed",
+		Errors:  errors,
+	}
+}
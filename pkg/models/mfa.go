@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+)
+
+type TOTPFactor struct {
+	UserId         string `gorm:"primaryKey"`
+	Secret         string `gorm:"not null"`
+	ConfirmedAt    *time.Time
+	LastUsedStep   int64 `gorm:"not null;default:0"`
+	FailedAttempts int   `gorm:"not null;default:0"`
+	LockedUntil    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	User           *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (TOTPFactor) TableName() string {
+	return "mfa_totp"
+}
+
+type RecoveryCode struct {
+	Id        int64  `gorm:"primaryKey;autoIncrement"`
+	UserId    string `gorm:"not null;index"`
+	CodeHash  string `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (RecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
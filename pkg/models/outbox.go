@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+)
+
+type OutboxMessage struct {
+	Id            int64  `gorm:"primaryKey;autoIncrement"`
+	TenantId      string `gorm:"not null;default:'default';index"`
+	AggregateType string `gorm:"not null"`
+	AggregateId   string `gorm:"not null;index"`
+	EventType     string `gorm:"not null"`
+	Payload       []byte `gorm:"type:jsonb;not null"`
+	CreatedAt     time.Time
+	PublishedAt   *time.Time `gorm:"index"`
+	DeadAt        *time.Time `gorm:"index"`
+	Attempts      int
+	LastError     string
+}
+
+func (OutboxMessage) TableName() string {
+	return "outbox"
+}
@@ -0,0 +1,16 @@
+package models
+
+import (
+	"time"
+)
+
+type PasswordResetToken struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	UserId    string    `gorm:"not null;index"`
+	TenantId  string    `gorm:"not null;default:'default'"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      *User `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+)
+
+type Session struct {
+	Id         string `gorm:"primaryKey;default:gen_random_uuid()"`
+	UserId     string `gorm:"not null;index"`
+	TenantId   string `gorm:"not null;default:'default'"`
+	UserAgent  string `gorm:"not null"`
+	IPAddress  string `gorm:"not null"`
+	CreatedAt  time.Time
+	LastUsedAt time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+	User       *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+// RefreshToken is one token of the rotation chain of a session. Used tokens
+// are kept until the session is deleted to detect their reuse.
+type RefreshToken struct {
+	Id        int64  `gorm:"primaryKey;autoIncrement"`
+	SessionId string `gorm:"not null;index"`
+	TokenHash string `gorm:"not null;uniqueIndex"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	Session   *Session `gorm:"constraint:OnDelete:CASCADE"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import (
+	"time"
+)
+
+type Tenant struct {
+	Id        string `gorm:"primaryKey"`
+	Name      string `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
@@ -5,10 +5,13 @@ import (
 )
 
 type User struct {
-	Id        string `gorm:"primary_key;default:gen_random_uuid()"`
-	FirstName string
-	LastName  string
-	Email     string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	Id            string    `json:"id"         gorm:"primary_key;default:gen_random_uuid()"`
+	TenantId      string    `json:"tenant_id"  gorm:"not null;default:'default';uniqueIndex:idx_users_tenant_email,priority:1"`
+	FirstName     string    `json:"first_name"`
+	LastName      string    `json:"last_name"`
+	Email         string    `json:"email"      gorm:"uniqueIndex:idx_users_tenant_email,priority:2,expression:lower(email)"`
+	EmailVerified bool      `json:"email_verified" gorm:"not null;default:false"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Tenant        *Tenant   `json:"-"          gorm:"constraint:OnDelete:RESTRICT"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+)
+
+type VerificationToken struct {
+	Id        int64     `gorm:"primaryKey;autoIncrement"`
+	UserId    string    `gorm:"not null;index"`
+	TenantId  string    `gorm:"not null;default:'default'"`
+	Email     string    `gorm:"not null"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      *User `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+func (VerificationToken) TableName() string {
+	return "email_verification_tokens"
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryDead      = "dead"
+)
+
+type Webhook struct {
+	Id        string   `gorm:"primary_key;default:gen_random_uuid()"`
+	TenantId  string   `gorm:"not null;default:'default';index"`
+	URL       string   `gorm:"not null"`
+	Events    []string `gorm:"serializer:json;type:jsonb;not null"`
+	Secret    string   `gorm:"not null"`
+	Active    bool     `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type WebhookDelivery struct {
+	Id             int64  `gorm:"primaryKey;autoIncrement"`
+	WebhookId      string `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event"`
+	EventId        int64  `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event"`
+	EventType      string `gorm:"not null"`
+	Payload        []byte `gorm:"type:jsonb;not null"`
+	Status         string `gorm:"not null;index"`
+	Attempts       int
+	NextAttemptAt  time.Time `gorm:"index"`
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	AttemptLog     []*WebhookAttempt `gorm:"foreignKey:DeliveryId"`
+}
+
+type WebhookAttempt struct {
+	Id          int64 `gorm:"primaryKey;autoIncrement"`
+	DeliveryId  int64 `gorm:"not null;index"`
+	Attempt     int
+	StatusCode  int
+	Error       string
+	DurationMs  int64
+	AttemptedAt time.Time
+}
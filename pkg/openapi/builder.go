@@ -0,0 +1,189 @@
+package openapi
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const contentTypeJSON = "application/json"
+
+type Route struct {
+	Method      string
+	Path        string
+	Handler     gin.HandlerFunc
+	OperationId string
+	Summary     string
+	Tags        []string
+	URI         any
+	Query       any
+	Header      any
+	Body        any
+	ContentType string
+	Responses   map[int]any
+}
+
+type Builder struct {
+	document *Document
+}
+
+func NewBuilder(title string, version string) *Builder {
+	return &Builder{
+		document: &Document{
+			OpenAPI: Version,
+			Info: Info{
+				Title:   title,
+				Version: version,
+			},
+			Paths: map[string]*PathItem{},
+			Components: Components{
+				Schemas: map[string]*Schema{},
+			},
+		},
+	}
+}
+
+func (builder *Builder) AddRoutes(basePath string, routes []Route) {
+	for _, route := range routes {
+		builder.AddRoute(basePath, route)
+	}
+}
+
+func (builder *Builder) AddRoute(basePath string, route Route) {
+	specPath := ToSpecPath(JoinPaths(basePath, route.Path))
+	item, ok := builder.document.Paths[specPath]
+	if !ok {
+		item = &PathItem{}
+		builder.document.Paths[specPath] = item
+	}
+
+	operation := item.operation(route.Method)
+	if operation == nil {
+		return
+	}
+	*operation = builder.operation(specPath, route)
+}
+
+func (builder *Builder) Document() *Document {
+	return builder.document
+}
+
+func (builder *Builder) operation(specPath string, route Route) *Operation {
+	operation := &Operation{
+		OperationId: route.OperationId,
+		Summary:     route.Summary,
+		Tags:        route.Tags,
+		Responses:   map[string]*Response{},
+	}
+
+	parameters := builder.pathParameters(specPath, route.URI)
+	if route.Query != nil {
+		parameters = append(parameters, builder.parameters(route.Query, "form", "query")...)
+	}
+	if route.Header != nil {
+		parameters = append(parameters, builder.parameters(route.Header, "header", "header")...)
+	}
+	operation.Parameters = parameters
+
+	if route.Body != nil {
+		operation.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]*MediaType{
+				contentTypeJSON: {Schema: builder.schemaFor(reflect.TypeOf(route.Body))},
+			},
+		}
+	}
+
+	for status, body := range route.Responses {
+		response := &Response{Description: http.StatusText(status)}
+		if body != nil {
+			contentType := contentTypeJSON
+			if route.ContentType != "" && status < http.StatusMultipleChoices {
+				contentType = route.ContentType
+			}
+			response.Content = map[string]*MediaType{
+				contentType: {Schema: builder.schemaFor(reflect.TypeOf(body))},
+			}
+		}
+		operation.Responses[strconv.Itoa(status)] = response
+	}
+	return operation
+}
+
+func (builder *Builder) pathParameters(specPath string, uri any) []*Parameter {
+	schemas := map[string]*Schema{}
+	if uri != nil {
+		for _, field := range builder.fields(reflect.TypeOf(uri), "uri") {
+			schemas[field.name] = field.schema
+		}
+	}
+
+	var parameters []*Parameter
+	for _, name := range PathParameterNames(specPath) {
+		schema, ok := schemas[name]
+		if !ok {
+			schema = &Schema{Type: "string"}
+		}
+		parameters = append(parameters, &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   schema,
+		})
+	}
+	return parameters
+}
+
+func (builder *Builder) parameters(value any, tagKey string, in string) []*Parameter {
+	fields := builder.fields(reflect.TypeOf(value), tagKey)
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].name < fields[j].name
+	})
+
+	parameters := make([]*Parameter, len(fields))
+	for i, field := range fields {
+		parameters[i] = &Parameter{
+			Name:     field.name,
+			In:       in,
+			Required: field.required,
+			Schema:   field.schema,
+		}
+	}
+	return parameters
+}
+
+func JoinPaths(basePath string, relativePath string) string {
+	if relativePath == "" {
+		return basePath
+	}
+	joined := path.Join(basePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(joined, "/") {
+		return joined + "/"
+	}
+	return joined
+}
+
+func ToSpecPath(ginPath string) string {
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if strings.HasPrefix(segment, ":") || strings.HasPrefix(segment, "*") {
+			segments[i] = "{" + segment[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+func PathParameterNames(specPath string) []string {
+	var names []string
+	for _, segment := range strings.Split(specPath, "/") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			names = append(names, segment[1:len(segment)-1])
+		}
+	}
+	return names
+}
@@ -0,0 +1,44 @@
+package openapi
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed static/docs.html
+var docsPage []byte
+
+// Swagger UI 5.18.2 from swagger-ui-dist, licensed under Apache-2.0; see LICENSE and NOTICE in
+// static/swagger-ui.
+//
+//go:embed static/swagger-ui
+var swaggerUI embed.FS
+
+type DocsHandler struct {
+	document *Document
+}
+
+func NewDocsHandler(document *Document) *DocsHandler {
+	return &DocsHandler{
+		document: document,
+	}
+}
+
+func (handler *DocsHandler) Register(router gin.IRoutes) {
+	router.GET("/openapi.json", handler.GetSpec)
+	router.GET("/docs", handler.GetDocs)
+
+	assets, _ := fs.Sub(swaggerUI, "static/swagger-ui")
+	router.StaticFS("/swagger-ui", http.FS(assets))
+}
+
+func (handler *DocsHandler) GetSpec(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, handler.document)
+}
+
+func (handler *DocsHandler) GetDocs(ctx *gin.Context) {
+	ctx.Data(http.StatusOK, "text/html; charset=utf-8", docsPage)
+}
@@ -0,0 +1,201 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	bytesType      = reflect.TypeOf([]byte{})
+)
+
+type field struct {
+	name     string
+	schema   *Schema
+	required bool
+}
+
+func (builder *Builder) schemaFor(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case t == bytesType:
+		return &Schema{Type: "string", Format: "binary"}
+	case t.Kind() == reflect.Struct && t.Name() != "":
+		name := t.Name()
+		if _, ok := builder.document.Components.Schemas[name]; !ok {
+			builder.document.Components.Schemas[name] = nil
+			builder.document.Components.Schemas[name] = builder.objectSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	return builder.inlineSchema(t)
+}
+
+func (builder *Builder) inlineSchema(t reflect.Type) *Schema {
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: builder.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: builder.schemaFor(t.Elem())}
+	case reflect.Struct:
+		return builder.objectSchema(t)
+	}
+	return &Schema{}
+}
+
+func (builder *Builder) objectSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	for _, field := range builder.fields(t, "json") {
+		schema.Properties[field.name] = field.schema
+		if field.required {
+			schema.Required = append(schema.Required, field.name)
+		}
+	}
+	return schema
+}
+
+func (builder *Builder) fields(t reflect.Type, tagKey string) []field {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+		if structField.Anonymous && structField.Tag.Get(tagKey) == "" {
+			fields = append(fields, builder.fields(structField.Type, tagKey)...)
+			continue
+		}
+
+		name := structField.Name
+		if tag, ok := structField.Tag.Lookup(tagKey); ok {
+			tagName := strings.Split(tag, ",")[0]
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		} else if tagKey != "json" {
+			continue
+		}
+
+		var schema *Schema
+		if isScalar(structField.Type) {
+			schema = builder.inlineSchema(structField.Type)
+		} else {
+			schema = builder.schemaFor(structField.Type)
+		}
+		if kind := structField.Type.Kind(); kind == reflect.Slice || kind == reflect.Map {
+			schema.Nullable = true
+		}
+		required := applyBinding(schema, structField.Tag.Get("binding"))
+		fields = append(fields, field{name: name, schema: schema, required: required})
+	}
+	return fields
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Slice, reflect.Array, reflect.Map, reflect.Pointer:
+		return false
+	}
+	return true
+}
+
+func applyBinding(schema *Schema, binding string) bool {
+	required := false
+	if binding == "" {
+		return required
+	}
+
+	rules := strings.Split(binding, ",")
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "dive":
+			if schema.Items != nil {
+				applyBinding(schema.Items, strings.Join(rules[i+1:], ","))
+			}
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "uuid":
+			schema.Format = "uuid"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				schema.Enum = append(schema.Enum, value)
+			}
+		case "min", "gte":
+			setLowerBound(schema, param)
+		case "max", "lte":
+			setUpperBound(schema, param)
+		case "len":
+			setLowerBound(schema, param)
+			setUpperBound(schema, param)
+		}
+	}
+	return required
+}
+
+func setLowerBound(schema *Schema, param string) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		length := int(value)
+		schema.MinLength = &length
+	case "array":
+		length := int(value)
+		schema.MinItems = &length
+	default:
+		schema.Minimum = &value
+	}
+}
+
+func setUpperBound(schema *Schema, param string) {
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+	switch schema.Type {
+	case "string":
+		length := int(value)
+		schema.MaxLength = &length
+	case "array":
+		length := int(value)
+		schema.MaxItems = &length
+	default:
+		schema.Maximum = &value
+	}
+}
@@ -0,0 +1,207 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var uuidPattern = regexp.MustCompile(
+	`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`,
+)
+
+type violation struct {
+	field   string
+	message string
+}
+
+func (document *Document) resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		name := strings.TrimPrefix(schema.Ref, "#/components/schemas/")
+		schema = document.Components.Schemas[name]
+	}
+	return schema
+}
+
+func (document *Document) validateValue(schema *Schema, value any, field string) []violation {
+	schema = document.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	if value == nil {
+		if schema.Type == "" || schema.Nullable {
+			return nil
+		}
+		return []violation{{field, fmt.Sprintf("must be of type %s", schema.Type)}}
+	}
+
+	switch schema.Type {
+	case "object":
+		return document.validateObject(schema, value, field)
+	case "array":
+		return document.validateArray(schema, value, field)
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return []violation{{field, "must be of type string"}}
+		}
+		return validateString(schema, s, field)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return []violation{{field, fmt.Sprintf("must be of type %s", schema.Type)}}
+		}
+		return validateNumber(schema, number, field)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []violation{{field, "must be of type boolean"}}
+		}
+	}
+	return nil
+}
+
+func (document *Document) validateObject(schema *Schema, value any, field string) []violation {
+	object, ok := value.(map[string]any)
+	if !ok {
+		return []violation{{field, "must be of type object"}}
+	}
+
+	var violations []violation
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			violations = append(violations, violation{joinField(field, name), "is required"})
+		}
+	}
+	for name, propertyValue := range object {
+		propertySchema, ok := schema.Properties[name]
+		if !ok {
+			propertySchema = schema.AdditionalProperties
+		}
+		violations = append(
+			violations,
+			document.validateValue(propertySchema, propertyValue, joinField(field, name))...,
+		)
+	}
+	return violations
+}
+
+func (document *Document) validateArray(schema *Schema, value any, field string) []violation {
+	array, ok := value.([]any)
+	if !ok {
+		return []violation{{field, "must be of type array"}}
+	}
+
+	var violations []violation
+	if schema.MinItems != nil && len(array) < *schema.MinItems {
+		message := fmt.Sprintf("must contain at least %d items", *schema.MinItems)
+		violations = append(violations, violation{field, message})
+	}
+	if schema.MaxItems != nil && len(array) > *schema.MaxItems {
+		message := fmt.Sprintf("must contain at most %d items", *schema.MaxItems)
+		violations = append(violations, violation{field, message})
+	}
+	for i, item := range array {
+		itemField := fmt.Sprintf("%s[%d]", field, i)
+		violations = append(violations, document.validateValue(schema.Items, item, itemField)...)
+	}
+	return violations
+}
+
+func validateString(schema *Schema, value string, field string) []violation {
+	var violations []violation
+	length := utf8.RuneCountInString(value)
+	if schema.MinLength != nil && length < *schema.MinLength {
+		message := fmt.Sprintf("must be at least %d characters", *schema.MinLength)
+		violations = append(violations, violation{field, message})
+	}
+	if schema.MaxLength != nil && length > *schema.MaxLength {
+		message := fmt.Sprintf("must be at most %d characters", *schema.MaxLength)
+		violations = append(violations, violation{field, message})
+	}
+	if len(schema.Enum) > 0 && !contains(schema.Enum, value) {
+		message := fmt.Sprintf("must be one of %v", schema.Enum)
+		violations = append(violations, violation{field, message})
+	}
+	if !validFormat(schema.Format, value) {
+		message := fmt.Sprintf("must be a valid %s", schema.Format)
+		violations = append(violations, violation{field, message})
+	}
+	return violations
+}
+
+func validateNumber(schema *Schema, value json.Number, field string) []violation {
+	number, err := value.Float64()
+	if err != nil {
+		return []violation{{field, fmt.Sprintf("must be of type %s", schema.Type)}}
+	}
+	if schema.Type == "integer" {
+		if _, err := value.Int64(); err != nil {
+			return []violation{{field, "must be of type integer"}}
+		}
+	}
+
+	var violations []violation
+	if schema.Minimum != nil && number < *schema.Minimum {
+		message := fmt.Sprintf("must be greater than or equal to %v", *schema.Minimum)
+		violations = append(violations, violation{field, message})
+	}
+	if schema.Maximum != nil && number > *schema.Maximum {
+		message := fmt.Sprintf("must be less than or equal to %v", *schema.Maximum)
+		violations = append(violations, violation{field, message})
+	}
+	return violations
+}
+
+func validFormat(format string, value string) bool {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "uuid":
+		return uuidPattern.MatchString(value)
+	case "uri":
+		parsed, err := url.ParseRequestURI(value)
+		return err == nil && parsed.Scheme != ""
+	case "date-time":
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}
+	return true
+}
+
+func parseParameter(schema *Schema, raw string) any {
+	switch schema.Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(raw, 64); err == nil {
+			return json.Number(raw)
+		}
+	case "boolean":
+		if parsed, err := strconv.ParseBool(raw); err == nil {
+			return parsed
+		}
+	}
+	return raw
+}
+
+func joinField(parent string, name string) string {
+	if parent == "" {
+		return name
+	}
+	return parent + "." + name
+}
+
+func contains(values []any, value any) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
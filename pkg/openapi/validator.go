@@ -0,0 +1,303 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+const maxRequestBodySize = 1 << 20
+
+type ResponseErrorHandler func(ctx *gin.Context, err *ResponseValidationError)
+
+type ResponseValidationError struct {
+	Method string
+	Path   string
+	Status int
+	Errors []models.FieldError
+}
+
+func (err *ResponseValidationError) Error() string {
+	messages := make([]string, len(err.Errors))
+	for i, fieldError := range err.Errors {
+		messages[i] = fmt.Sprintf("%s: %s", fieldError.Field, fieldError.Message)
+	}
+	return fmt.Sprintf(
+		"response %d for %s %s does not match specification: %s",
+		err.Status,
+		err.Method,
+		err.Path,
+		strings.Join(messages, "; "),
+	)
+}
+
+func LogResponseError(_ *gin.Context, err *ResponseValidationError) {
+	log.Printf("invalid response: %v", err)
+}
+
+type Validator struct {
+	document        *Document
+	onResponseError ResponseErrorHandler
+}
+
+func NewValidator(document *Document) *Validator {
+	return &Validator{
+		document: document,
+	}
+}
+
+func (validator *Validator) WithResponseValidation(handler ResponseErrorHandler) *Validator {
+	validator.onResponseError = handler
+	return validator
+}
+
+func (validator *Validator) Middleware() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		specPath := ToSpecPath(ctx.FullPath())
+		item, ok := validator.document.Paths[specPath]
+		if !ok {
+			ctx.Next()
+			return
+		}
+		operation := item.Operation(ctx.Request.Method)
+		if operation == nil {
+			ctx.Next()
+			return
+		}
+
+		fieldErrors := validator.validateRequest(ctx, operation)
+		if len(fieldErrors) > 0 {
+			log.Printf("invalid request: %v", fieldErrors)
+			ctx.AbortWithStatusJSON(
+				http.StatusBadRequest,
+				models.NewValidationErrorMessage(fieldErrors),
+			)
+			return
+		}
+
+		if validator.onResponseError == nil || isStreaming(operation) || ctx.IsWebsocket() {
+			ctx.Next()
+			return
+		}
+
+		writer := &bodyRecorder{ResponseWriter: ctx.Writer}
+		ctx.Writer = writer
+		ctx.Next()
+
+		fieldErrors = validator.validateResponse(
+			operation,
+			ctx.Writer.Status(),
+			writer.body.Bytes(),
+		)
+		if len(fieldErrors) > 0 {
+			validator.onResponseError(ctx, &ResponseValidationError{
+				Method: ctx.Request.Method,
+				Path:   specPath,
+				Status: ctx.Writer.Status(),
+				Errors: fieldErrors,
+			})
+		}
+	}
+}
+
+func (validator *Validator) validateRequest(
+	ctx *gin.Context,
+	operation *Operation,
+) []models.FieldError {
+	var fieldErrors []models.FieldError
+	for _, parameter := range operation.Parameters {
+		var raw string
+		var present bool
+		switch parameter.In {
+		case "path":
+			raw = ctx.Param(parameter.Name)
+			present = raw != ""
+		case "query":
+			raw, present = ctx.GetQuery(parameter.Name)
+		case "header":
+			raw = ctx.GetHeader(parameter.Name)
+			present = raw != ""
+		}
+
+		if !present {
+			if parameter.Required {
+				fieldErrors = append(fieldErrors, models.FieldError{
+					In:      parameter.In,
+					Field:   parameter.Name,
+					Message: "is required",
+				})
+			}
+			continue
+		}
+
+		schema := validator.document.resolve(parameter.Schema)
+		value := parseParameter(schema, raw)
+		violations := validator.document.validateValue(schema, value, parameter.Name)
+		fieldErrors = append(fieldErrors, toFieldErrors(parameter.In, violations)...)
+	}
+
+	if operation.RequestBody != nil {
+		fieldErrors = append(
+			fieldErrors,
+			validator.validateRequestBody(ctx, operation.RequestBody)...)
+	}
+	return fieldErrors
+}
+
+func (validator *Validator) validateRequestBody(
+	ctx *gin.Context,
+	requestBody *RequestBody,
+) []models.FieldError {
+	mediaType, _, err := mime.ParseMediaType(ctx.GetHeader("Content-Type"))
+	if err != nil {
+		mediaType = contentTypeJSON
+	}
+	content, ok := requestBody.Content[mediaType]
+	if !ok {
+		message := fmt.Sprintf("unsupported content type %q", mediaType)
+		return []models.FieldError{{In: "header", Field: "Content-Type", Message: message}}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxRequestBodySize))
+	if err != nil && len(body) >= maxRequestBodySize {
+		message := fmt.Sprintf("must not exceed %d bytes", maxRequestBodySize)
+		return []models.FieldError{{In: "body", Message: message}}
+	}
+	if err != nil {
+		return []models.FieldError{{In: "body", Message: "could not be read"}}
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	if len(bytes.TrimSpace(body)) == 0 {
+		if requestBody.Required {
+			return []models.FieldError{{In: "body", Message: "is required"}}
+		}
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return []models.FieldError{{In: "body", Message: "must be valid JSON"}}
+	}
+	return toFieldErrors("body", validator.document.validateValue(content.Schema, value, ""))
+}
+
+func (validator *Validator) validateResponse(
+	operation *Operation,
+	status int,
+	body []byte,
+) []models.FieldError {
+	response, ok := operation.Responses[strconv.Itoa(status)]
+	if !ok {
+		return []models.FieldError{{In: "status", Message: "is not documented"}}
+	}
+
+	content, ok := response.Content[contentTypeJSON]
+	if !ok {
+		if len(body) > 0 {
+			return []models.FieldError{{In: "body", Message: "is not documented"}}
+		}
+		return nil
+	}
+
+	value, err := decodeJSON(body)
+	if err != nil {
+		return []models.FieldError{{In: "body", Message: "must be valid JSON"}}
+	}
+	violations := validator.document.validateValue(content.Schema, value, "")
+	violations = append(violations, validator.undocumentedFields(content.Schema, value, "")...)
+	return toFieldErrors("body", violations)
+}
+
+func (validator *Validator) undocumentedFields(
+	schema *Schema,
+	value any,
+	field string,
+) []violation {
+	schema = validator.document.resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	var violations []violation
+	switch value := value.(type) {
+	case map[string]any:
+		if schema.Type != "object" || schema.AdditionalProperties != nil {
+			return nil
+		}
+		for name, propertyValue := range value {
+			propertySchema, ok := schema.Properties[name]
+			if !ok {
+				violations = append(violations, violation{joinField(field, name), "is not documented"})
+				continue
+			}
+			violations = append(
+				violations,
+				validator.undocumentedFields(propertySchema, propertyValue, joinField(field, name))...,
+			)
+		}
+	case []any:
+		for i, item := range value {
+			itemField := fmt.Sprintf("%s[%d]", field, i)
+			violations = append(violations, validator.undocumentedFields(schema.Items, item, itemField)...)
+		}
+	}
+	return violations
+}
+
+func isStreaming(operation *Operation) bool {
+	for _, response := range operation.Responses {
+		for contentType := range response.Content {
+			if contentType != contentTypeJSON {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeJSON(body []byte) (any, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var value any
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+func toFieldErrors(in string, violations []violation) []models.FieldError {
+	fieldErrors := make([]models.FieldError, len(violations))
+	for i, violation := range violations {
+		fieldErrors[i] = models.FieldError{
+			In:      in,
+			Field:   violation.field,
+			Message: violation.message,
+		}
+	}
+	return fieldErrors
+}
+
+type bodyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (recorder *bodyRecorder) Write(data []byte) (int, error) {
+	recorder.body.Write(data)
+	return recorder.ResponseWriter.Write(data)
+}
+
+func (recorder *bodyRecorder) WriteString(s string) (int, error) {
+	recorder.body.WriteString(s)
+	return recorder.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,229 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type itemURI struct {
+	Id string `uri:"id" binding:"required,uuid"`
+}
+
+type itemQuery struct {
+	Limit int `form:"limit" binding:"min=1,max=100"`
+}
+
+type itemRequest struct {
+	Name  string `json:"name"  binding:"required,max=5"`
+	Email string `json:"email" binding:"required,email"`
+}
+
+type itemResponse struct {
+	Name string `json:"name"`
+}
+
+func newTestRouter(responseBody any, onResponseError ResponseErrorHandler) *gin.Engine {
+	handler := func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, responseBody)
+	}
+	routes := []Route{
+		{
+			Method:    http.MethodPut,
+			Path:      "/:id",
+			Handler:   handler,
+			URI:       itemURI{},
+			Query:     itemQuery{},
+			Body:      itemRequest{},
+			Responses: map[int]any{http.StatusOK: itemResponse{}},
+		},
+	}
+
+	builder := NewBuilder("test", "1.0.0")
+	validator := NewValidator(builder.Document())
+	if onResponseError != nil {
+		validator.WithResponseValidation(onResponseError)
+	}
+
+	router := gin.New()
+	router.Use(validator.Middleware())
+	group := router.Group("/items")
+	for _, route := range routes {
+		group.Handle(route.Method, route.Path, route.Handler)
+	}
+	builder.AddRoutes(group.BasePath(), routes)
+	return router
+}
+
+func TestValidator_Request(t *testing.T) {
+	validId := "2f1d4b52-8a1e-4b43-9b5e-0c1f5bd1a0f1"
+
+	testCases := []struct {
+		path         string
+		requestBody  map[string]interface{}
+		expectedCode int
+		expectedErrs []interface{}
+		reason       string
+	}{
+		{
+			path:         "/items/" + validId + "?limit=10",
+			requestBody:  map[string]interface{}{"name": "Jane", "email": "jane.doe@mail.com"},
+			expectedCode: 200,
+			reason:       "Should pass valid requests through to the handler",
+		},
+		{
+			path:         "/items/abc123?limit=0",
+			requestBody:  map[string]interface{}{"name": "Janet", "email": "jane.doe@mail.com"},
+			expectedCode: 400,
+			expectedErrs: []interface{}{
+				map[string]interface{}{
+					"in":      "path",
+					"field":   "id",
+					"message": "must be a valid uuid",
+				},
+				map[string]interface{}{
+					"in":      "query",
+					"field":   "limit",
+					"message": "must be greater than or equal to 1",
+				},
+			},
+			reason: "Should reject invalid path and query parameters",
+		},
+		{
+			path:         "/items/" + validId,
+			requestBody:  map[string]interface{}{"name": "Janette", "email": "jane"},
+			expectedCode: 400,
+			expectedErrs: []interface{}{
+				map[string]interface{}{
+					"in":      "body",
+					"field":   "email",
+					"message": "must be a valid email",
+				},
+				map[string]interface{}{
+					"in":      "body",
+					"field":   "name",
+					"message": "must be at most 5 characters",
+				},
+			},
+			reason: "Should reject body that violates schema constraints",
+		},
+		{
+			path:         "/items/" + validId,
+			requestBody:  map[string]interface{}{"name": "Jane"},
+			expectedCode: 400,
+			expectedErrs: []interface{}{
+				map[string]interface{}{"in": "body", "field": "email", "message": "is required"},
+			},
+			reason: "Should reject body missing required properties",
+		},
+		{
+			path: "/items/" + validId,
+			requestBody: map[string]interface{}{
+				"name":  strings.Repeat("a", maxRequestBodySize),
+				"email": "jane.doe@mail.com",
+			},
+			expectedCode: 400,
+			expectedErrs: []interface{}{
+				map[string]interface{}{
+					"in":      "body",
+					"field":   "",
+					"message": "must not exceed 1048576 bytes",
+				},
+			},
+			reason: "Should reject body larger than limit",
+		},
+	}
+
+	router := newTestRouter(map[string]interface{}{"name": "Jane"}, nil)
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			jsonBody, err := json.Marshal(tc.requestBody)
+			if err != nil {
+				t.Fatalf("error marshaling request body to json %v", err)
+			}
+
+			request, err := http.NewRequest("PUT", tc.path, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+			request.Header.Set("Content-Type", "application/json")
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tc.expectedCode, recorder.Code, "Should match response code")
+
+			if tc.expectedErrs != nil {
+				var actualBody map[string]interface{}
+				err = json.Unmarshal(recorder.Body.Bytes(), &actualBody)
+				if err != nil {
+					t.Fatalf("error unmarshaling response: %v", err)
+				}
+
+				assert.ElementsMatch(
+					t,
+					tc.expectedErrs,
+					actualBody["errors"],
+					"Should match validation errors",
+				)
+			}
+		})
+	}
+}
+
+func TestValidator_Response(t *testing.T) {
+	testCases := []struct {
+		responseBody any
+		expectError  bool
+		reason       string
+	}{
+		{
+			responseBody: map[string]interface{}{"name": "Jane"},
+			expectError:  false,
+			reason:       "Should accept response matching the specification",
+		},
+		{
+			responseBody: map[string]interface{}{"name": "Jane", "nickname": "JD"},
+			expectError:  true,
+			reason:       "Should report undocumented response fields",
+		},
+		{
+			responseBody: map[string]interface{}{"name": 42},
+			expectError:  true,
+			reason:       "Should report response fields with wrong type",
+		},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			var responseErr *ResponseValidationError
+			router := newTestRouter(
+				tc.responseBody,
+				func(_ *gin.Context, err *ResponseValidationError) {
+					responseErr = err
+				},
+			)
+
+			jsonBody := []byte(`{"name":"Jane","email":"jane.doe@mail.com"}`)
+			path := "/items/2f1d4b52-8a1e-4b43-9b5e-0c1f5bd1a0f1"
+			request, err := http.NewRequest("PUT", path, bytes.NewBuffer(jsonBody))
+			if err != nil {
+				t.Fatalf("error creating request %v", err)
+			}
+
+			recorder := httptest.NewRecorder()
+			router.ServeHTTP(recorder, request)
+
+			assert.Equal(t, 200, recorder.Code, "Should match response code")
+			assert.Equal(t, tc.expectError, responseErr != nil, "Should match response validation")
+		})
+	}
+}
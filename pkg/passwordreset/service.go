@@ -0,0 +1,159 @@
+package passwordreset
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+// SessionRevoker signs a user out of all sessions.
+type SessionRevoker interface {
+	RevokeAll(ctx context.Context, userId string) error
+}
+
+type Service struct {
+	userRepository          repositories.UserRepository
+	credentialRepository    repositories.CredentialRepository
+	passwordResetRepository repositories.PasswordResetRepository
+	sessions                SessionRevoker
+	hasher                  *passwords.Hasher
+	policy                  passwords.Policy
+	mailer                  mail.Mailer
+	resetURL                string
+	ttl                     time.Duration
+	now                     func() time.Time
+}
+
+func NewService(
+	userRepository repositories.UserRepository,
+	credentialRepository repositories.CredentialRepository,
+	passwordResetRepository repositories.PasswordResetRepository,
+	sessions SessionRevoker,
+	hasher *passwords.Hasher,
+	policy passwords.Policy,
+	mailer mail.Mailer,
+	resetURL string,
+	ttl time.Duration,
+) *Service {
+	return &Service{
+		userRepository:          userRepository,
+		credentialRepository:    credentialRepository,
+		passwordResetRepository: passwordResetRepository,
+		sessions:                sessions,
+		hasher:                  hasher,
+		policy:                  policy,
+		mailer:                  mailer,
+		resetURL:                resetURL,
+		ttl:                     ttl,
+		now:                     time.Now,
+	}
+}
+
+// Request emails a reset link if a user with a password has the email. Unknown
+// emails are not an error, so callers cannot tell whether an account exists.
+func (service *Service) Request(ctx context.Context, email string) error {
+	credential, err := service.credentialRepository.GetCredentialByEmail(ctx, email)
+	if errors.Is(err, repositories.ErrCredentialNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	user, err := service.userRepository.GetUserById(ctx, credential.UserId)
+	if err != nil {
+		return err
+	}
+
+	token, hash, err := securetoken.Generate()
+	if err != nil {
+		return err
+	}
+	err = service.passwordResetRepository.CreatePasswordResetToken(
+		ctx,
+		&models.PasswordResetToken{
+			UserId:    user.Id,
+			TenantId:  user.TenantId,
+			TokenHash: hash,
+			ExpiresAt: service.now().Add(service.ttl),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("error storing password reset token: %v", err)
+	}
+
+	link := service.resetURL + "?token=" + url.QueryEscape(token)
+	return service.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nA password reset was requested for your account. Open the link below "+
+				"to choose a new password. The link expires in %s.\n\n%s\n\n"+
+				"If you did not request this, you can ignore this email.\n",
+			user.FirstName,
+			service.ttl,
+			link,
+		),
+	})
+}
+
+func (service *Service) Confirm(ctx context.Context, token string, password string) error {
+	hash := securetoken.Hash(token)
+	resetToken, err := service.passwordResetRepository.GetPasswordResetToken(
+		ctx,
+		hash,
+		service.now(),
+	)
+	if err != nil {
+		return err
+	}
+	// Links do not carry the tenant, which is that of the token.
+	ctx = tenancy.WithTenant(ctx, resetToken.TenantId)
+	user, err := service.userRepository.GetUserById(ctx, resetToken.UserId)
+	if err != nil {
+		return err
+	}
+
+	localPart, _, _ := strings.Cut(user.Email, "@")
+	err = service.policy.Check(password, user.FirstName, user.LastName, localPart)
+	if err != nil {
+		return err
+	}
+
+	passwordHash, err := service.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	_, err = service.passwordResetRepository.ResetPassword(ctx, hash, service.now(), passwordHash)
+	if err != nil {
+		return err
+	}
+	err = service.sessions.RevokeAll(ctx, user.Id)
+	if err != nil {
+		return fmt.Errorf("error revoking sessions: %v", err)
+	}
+
+	err = service.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nThe password of your account was reset and you were signed out "+
+				"everywhere. If this was not you, contact support immediately.\n",
+			user.FirstName,
+		),
+	})
+	if err != nil {
+		log.Printf("error sending password changed notification: %v", err)
+	}
+	return nil
+}
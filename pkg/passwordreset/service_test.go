@@ -0,0 +1,153 @@
+package passwordreset
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/passwords"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type recordingRevoker struct {
+	userIds []string
+}
+
+func (revoker *recordingRevoker) RevokeAll(_ context.Context, userId string) error {
+	revoker.userIds = append(revoker.userIds, userId)
+	return nil
+}
+
+func newTestService(
+	t *testing.T,
+	user *models.User,
+) (*Service, sqlmock.Sqlmock, *mail.MemoryMailer, *recordingRevoker) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	if err = db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("error registering tenancy plugin: %v", err)
+	}
+
+	userRepository := repositories.NewMemoryUserRepository()
+	ctx := tenancy.WithTenant(context.Background(), user.TenantId)
+	if err := userRepository.CreateUser(ctx, user); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	mailer := mail.NewMemoryMailer()
+	revoker := &recordingRevoker{}
+	params := passwords.Params{
+		Memory:      1024,
+		Iterations:  1,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+	service := NewService(
+		userRepository,
+		repositories.NewSQLCredentialRepository(db),
+		repositories.NewSQLPasswordResetRepository(db),
+		revoker,
+		passwords.NewHasher(params),
+		passwords.DefaultPolicy,
+		mailer,
+		"https://example.com/reset-password",
+		time.Hour,
+	)
+	return service, mock, mailer, revoker
+}
+
+var (
+	tokenColumns = []string{"id", "user_id", "tenant_id", "token_hash"}
+	selectQuery  = `SELECT * FROM "password_reset_tokens" ` +
+		`WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2`
+)
+
+func expectResetPassword(mock sqlmock.Sqlmock, user *models.User, tokenHash string) {
+	mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
+		WithArgs(tokenHash, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, user.Id, user.TenantId, tokenHash))
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "password_reset_tokens" `+
+			`WHERE (token_hash = $1 AND used_at IS NULL AND expires_at > $2) `+
+			`AND "password_reset_tokens"."tenant_id" = $3`,
+	)).
+		WithArgs(tokenHash, sqlmock.AnyArg(), user.TenantId).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, user.Id, user.TenantId, tokenHash))
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "password_reset_tokens" SET "used_at"=$1 `+
+			`WHERE (user_id = $2 AND used_at IS NULL) AND "password_reset_tokens"."tenant_id" = $3`,
+	)).
+		WithArgs(sqlmock.AnyArg(), user.Id, user.TenantId).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "credentials"`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+func TestService_Confirm(t *testing.T) {
+	user := &models.User{
+		TenantId:  tenancy.DefaultTenant,
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane.doe@mail.com",
+	}
+	service, mock, mailer, revoker := newTestService(t, user)
+	ctx := tenancy.WithTenant(context.Background(), tenancy.DefaultTenant)
+	tokenHash := securetoken.Hash("reset-token")
+	expectResetPassword(mock, user, tokenHash)
+
+	err := service.Confirm(ctx, "reset-token", "Tr0ub4dor&3-horse")
+
+	assert.NoError(t, err, "Should reset password")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should consume all tokens of user")
+	assert.Equal(t, []string{user.Id}, revoker.userIds, "Should revoke all sessions of user")
+	messages := mailer.Messages()
+	if assert.Len(t, messages, 1, "Should notify user") {
+		assert.Equal(t, "Your password was changed", messages[0].Subject)
+	}
+
+	mock.ExpectQuery(regexp.QuoteMeta(selectQuery)).
+		WithArgs(tokenHash, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(tokenColumns))
+
+	err = service.Confirm(ctx, "reset-token", "Tr0ub4dor&3-horse")
+
+	assert.ErrorIs(t, err, repositories.ErrPasswordResetTokenInvalid, "Should be single use")
+}
+
+func TestService_ConfirmOtherTenant(t *testing.T) {
+	user := &models.User{
+		TenantId:  "acme",
+		FirstName: "Jane",
+		LastName:  "Doe",
+		Email:     "jane.doe@mail.com",
+	}
+	service, mock, _, revoker := newTestService(t, user)
+	tokenHash := securetoken.Hash("reset-token")
+	expectResetPassword(mock, user, tokenHash)
+
+	// Anonymous callers of the link are in the default tenant.
+	ctx := tenancy.WithTenant(context.Background(), tenancy.DefaultTenant)
+	err := service.Confirm(ctx, "reset-token", "Tr0ub4dor&3-horse")
+
+	assert.NoError(t, err, "Should reset password in tenant of token")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should consume tokens in tenant of token")
+	assert.Equal(t, []string{user.Id}, revoker.userIds, "Should revoke all sessions of user")
+}
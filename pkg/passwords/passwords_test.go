@@ -0,0 +1,71 @@
+package passwords
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testParams = Params{Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHasher_Verify(t *testing.T) {
+	hasher := NewHasher(testParams)
+	hash, err := hasher.Hash("correct horse battery staple")
+	if err != nil {
+		t.Fatalf("error hashing password: %v", err)
+	}
+
+	match, rehash, err := hasher.Verify("correct horse battery staple", hash)
+	assert.NoError(t, err)
+	assert.True(t, match, "Should match correct password")
+	assert.False(t, rehash, "Should not rehash with same parameters")
+
+	match, _, err = hasher.Verify("wrong horse battery staple", hash)
+	assert.NoError(t, err)
+	assert.False(t, match, "Should not match wrong password")
+
+	stronger := testParams
+	stronger.Iterations = 2
+	match, rehash, err = NewHasher(stronger).Verify("correct horse battery staple", hash)
+	assert.NoError(t, err)
+	assert.True(t, match, "Should verify with parameters of the hash")
+	assert.True(t, rehash, "Should rehash when parameters change")
+
+	_, _, err = hasher.Verify("password", "$2a$10$abcdef")
+	assert.ErrorIs(t, err, ErrInvalidHash, "Should reject other hash formats")
+}
+
+func TestParseParams(t *testing.T) {
+	params, err := ParseParams("m=19456,t=2,p=1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint32(19456), params.Memory)
+	assert.Equal(t, uint32(2), params.Iterations)
+	assert.Equal(t, uint8(1), params.Parallelism)
+
+	_, err = ParseParams("m=0,t=2,p=1")
+	assert.Error(t, err, "Should reject zero parameters")
+}
+
+func TestPolicy_Check(t *testing.T) {
+	testCases := []struct {
+		name     string
+		password string
+		valid    bool
+	}{
+		{name: "too short", password: "Sh0rt!", valid: false},
+		{name: "too few classes", password: "onlylowercaseletters", valid: false},
+		{name: "personal information", password: "JaneDoe-2022-secret", valid: false},
+		{name: "strong", password: "Tr0ub4dor&3-horse", valid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := DefaultPolicy.Check(tc.password, "Jane", "Doe", "jane.doe")
+			assert.Equal(t, tc.valid, err == nil, "Should match validity: %v", err)
+			if err != nil {
+				assert.True(t, errors.Is(err, ErrWeakPassword), "Should wrap ErrWeakPassword")
+			}
+		})
+	}
+}
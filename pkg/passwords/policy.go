@@ -0,0 +1,76 @@
+package passwords
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrWeakPassword = errors.New("password does not meet the strength policy")
+
+type Policy struct {
+	MinLength  int
+	MaxLength  int
+	MinClasses int
+}
+
+var DefaultPolicy = Policy{
+	MinLength:  12,
+	MaxLength:  128,
+	MinClasses: 3,
+}
+
+// Check validates the password and rejects passwords containing any of the
+// given words, such as the user's name or the local part of their email.
+func (policy Policy) Check(password string, words ...string) error {
+	length := utf8.RuneCountInString(password)
+	if length < policy.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, policy.MinLength)
+	}
+	if policy.MaxLength > 0 && length > policy.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrWeakPassword, policy.MaxLength)
+	}
+
+	if classes := characterClasses(password); classes < policy.MinClasses {
+		return fmt.Errorf(
+			"%w: must contain %d of lowercase, uppercase, digits and symbols",
+			ErrWeakPassword,
+			policy.MinClasses,
+		)
+	}
+
+	lower := strings.ToLower(password)
+	for _, word := range words {
+		word = strings.ToLower(word)
+		if len(word) >= 3 && strings.Contains(lower, word) {
+			return fmt.Errorf("%w: must not contain personal information", ErrWeakPassword)
+		}
+	}
+	return nil
+}
+
+func characterClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	return classes
+}
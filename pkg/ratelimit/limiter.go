@@ -0,0 +1,82 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+}
+
+type Limiter interface {
+	Allow(ctx context.Context, key string) (Result, error)
+}
+
+type window struct {
+	start time.Time
+	count int
+}
+
+// MemoryLimiter counts requests per key in fixed windows. Counts are local to
+// the process.
+type MemoryLimiter struct {
+	limit     int
+	period    time.Duration
+	mu        sync.Mutex
+	windows   map[string]*window
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryLimiter(limit int, period time.Duration) *MemoryLimiter {
+	return &MemoryLimiter{
+		limit:   limit,
+		period:  period,
+		windows: map[string]*window{},
+		now:     time.Now,
+	}
+}
+
+func (limiter *MemoryLimiter) Allow(_ context.Context, key string) (Result, error) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	current, ok := limiter.windows[key]
+	if !ok || now.Sub(current.start) >= limiter.period {
+		current = &window{start: now}
+		limiter.windows[key] = current
+	}
+
+	result := Result{
+		Limit:      limiter.limit,
+		ResetAfter: current.start.Add(limiter.period).Sub(now),
+	}
+	if current.count >= limiter.limit {
+		return result, nil
+	}
+
+	current.count++
+	result.Allowed = true
+	result.Remaining = limiter.limit - current.count
+	return result, nil
+}
+
+func (limiter *MemoryLimiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < limiter.period {
+		return
+	}
+	for key, current := range limiter.windows {
+		if now.Sub(current.start) >= limiter.period {
+			delete(limiter.windows, key)
+		}
+	}
+	limiter.lastSweep = now
+}
@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	now := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+	limiter := NewMemoryLimiter(2, time.Minute)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		result, err := limiter.Allow(ctx, "jane.doe@mail.com")
+		assert.NoError(t, err)
+		assert.True(t, result.Allowed, "Should allow requests within limit")
+		assert.Equal(t, 1-i, result.Remaining, "Should count down remaining requests")
+	}
+
+	result, _ := limiter.Allow(ctx, "jane.doe@mail.com")
+	assert.False(t, result.Allowed, "Should reject requests over limit")
+	assert.Equal(t, time.Minute, result.ResetAfter, "Should reset at end of window")
+
+	result, _ = limiter.Allow(ctx, "john.doe@mail.com")
+	assert.True(t, result.Allowed, "Should count keys separately")
+
+	now = now.Add(time.Minute)
+	result, _ = limiter.Allow(ctx, "jane.doe@mail.com")
+	assert.True(t, result.Allowed, "Should allow requests in next window")
+}
+
+func newTestStores(t *testing.T, now *time.Time) map[string]Store {
+	memoryStore := NewMemoryStore()
+	memoryStore.now = func() time.Time { return *now }
+
+	server := miniredis.RunT(t)
+	redisStore := NewRedisStore(redis.NewClient(&redis.Options{Addr: server.Addr()}), "test:")
+	redisStore.now = func() time.Time { return *now }
+
+	return map[string]Store{"memory": memoryStore, "redis": redisStore}
+}
+
+func TestStore_TakeToken(t *testing.T) {
+	now := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+	quota := Quota{Limit: 2, Period: time.Minute}
+	ctx := context.Background()
+
+	for name, store := range newTestStores(t, &now) {
+		t.Run(name, func(t *testing.T) {
+			start := now
+			defer func() { now = start }()
+
+			for i := 0; i < 2; i++ {
+				result, err := store.TakeToken(ctx, "abc123", quota)
+				assert.NoError(t, err)
+				assert.True(t, result.Allowed, "Should allow burst up to limit")
+				assert.Equal(t, 1-i, result.Remaining, "Should count down remaining tokens")
+			}
+
+			result, err := store.TakeToken(ctx, "abc123", quota)
+			assert.NoError(t, err)
+			assert.False(t, result.Allowed, "Should reject requests without tokens")
+			assert.Equal(
+				t,
+				30*time.Second,
+				result.ResetAfter,
+				"Should reset when token is refilled",
+			)
+
+			result, _ = store.TakeToken(ctx, "abc124", quota)
+			assert.True(t, result.Allowed, "Should count keys separately")
+
+			now = now.Add(30 * time.Second)
+			result, _ = store.TakeToken(ctx, "abc123", quota)
+			assert.True(t, result.Allowed, "Should allow requests after refill")
+			assert.Equal(t, 0, result.Remaining, "Should refill tokens at rate")
+		})
+	}
+}
+
+func TestStore_SlideWindow(t *testing.T) {
+	now := time.Date(2022, 3, 3, 12, 0, 0, 0, time.UTC)
+	quota := Quota{Limit: 4, Period: time.Minute}
+	ctx := context.Background()
+
+	for name, store := range newTestStores(t, &now) {
+		t.Run(name, func(t *testing.T) {
+			start := now
+			defer func() { now = start }()
+
+			for i := 0; i < 4; i++ {
+				result, err := store.SlideWindow(ctx, "abc123", quota)
+				assert.NoError(t, err)
+				assert.True(t, result.Allowed, "Should allow requests within limit")
+			}
+
+			result, err := store.SlideWindow(ctx, "abc123", quota)
+			assert.NoError(t, err)
+			assert.False(t, result.Allowed, "Should reject requests over limit")
+			assert.Equal(t, 75*time.Second, result.ResetAfter, "Should reset when count decays")
+
+			now = now.Add(time.Minute + 30*time.Second)
+			result, _ = store.SlideWindow(ctx, "abc123", quota)
+			assert.True(t, result.Allowed, "Should weight previous window")
+			assert.Equal(t, 1, result.Remaining, "Should count half of previous window")
+
+			result, _ = store.SlideWindow(ctx, "abc123", quota)
+			assert.True(t, result.Allowed, "Should allow requests within weighted limit")
+
+			result, _ = store.SlideWindow(ctx, "abc123", quota)
+			assert.False(t, result.Allowed, "Should reject requests over weighted limit")
+			assert.Equal(t, 15*time.Second, result.ResetAfter, "Should reset when count decays")
+		})
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules(
+		"*=100/1m, POST /users/=10/1s, @pro=1000/1m, @pro get /users/:id=50/1s",
+	)
+
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		[]Rule{
+			{Quota: Quota{Limit: 100, Period: time.Minute}},
+			{Route: "POST /users/", Quota: Quota{Limit: 10, Period: time.Second}},
+			{Plan: "pro", Quota: Quota{Limit: 1000, Period: time.Minute}},
+			{Plan: "pro", Route: "GET /users/:id", Quota: Quota{Limit: 50, Period: time.Second}},
+		},
+		rules,
+		"Should parse rules",
+	)
+
+	_, err = ParseRules("POST=10/1s")
+	assert.Error(t, err, "Should reject route without path")
+	_, err = ParseRules("*=0/1s")
+	assert.Error(t, err, "Should reject limit of zero")
+}
+
+func TestPolicy_Allow(t *testing.T) {
+	rules, _ := ParseRules("*=1/1m,@pro=3/1m,POST /users/=2/1m")
+	policy := NewPolicy(NewMemoryStore(), TokenBucket, rules)
+	ctx := context.Background()
+
+	result, ok, _ := policy.Allow(ctx, "GET /users/", nil, "ip:10.0.0.1")
+	assert.True(t, ok, "Should apply default rule")
+	assert.Equal(t, 1, result.Limit, "Should use default quota")
+
+	result, _, _ = policy.Allow(ctx, "GET /users/", []string{"admin", "pro"}, "user:abc123")
+	assert.Equal(t, 3, result.Limit, "Should use quota of plan")
+
+	result, _, _ = policy.Allow(ctx, "POST /users/", []string{"pro"}, "user:abc123")
+	assert.Equal(t, 2, result.Limit, "Should prefer quota of route over plan")
+	assert.Equal(t, 1, result.Remaining, "Should count routes with own quota separately")
+
+	policy = NewPolicy(NewMemoryStore(), TokenBucket, rules[1:2])
+	_, ok, _ = policy.Allow(ctx, "GET /users/", nil, "ip:10.0.0.1")
+	assert.False(t, ok, "Should not limit requests without rule")
+}
@@ -0,0 +1,110 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+const memorySweepInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	period  time.Duration
+}
+
+type slidingWindow struct {
+	index    int64
+	previous int
+	current  int
+	period   time.Duration
+}
+
+// MemoryStore keeps the state of the algorithms in the process, for a single
+// instance.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	windows   map[string]*slidingWindow
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		buckets: map[string]*bucket{},
+		windows: map[string]*slidingWindow{},
+		now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) TakeToken(_ context.Context, key string, quota Quota) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	current, ok := store.buckets[key]
+	if !ok {
+		current = &bucket{tokens: float64(quota.Limit), updated: now}
+		store.buckets[key] = current
+	}
+	refill := float64(now.Sub(current.updated)) / float64(quota.Period) * float64(quota.Limit)
+	current.tokens = math.Min(float64(quota.Limit), current.tokens+math.Max(0, refill))
+	current.updated = now
+	current.period = quota.Period
+
+	allowed := current.tokens >= 1
+	if allowed {
+		current.tokens--
+	}
+	return tokenBucketResult(quota, current.tokens, allowed), nil
+}
+
+func (store *MemoryStore) SlideWindow(_ context.Context, key string, quota Quota) (Result, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	now := store.now()
+	store.sweep(now)
+
+	index := now.UnixNano() / int64(quota.Period)
+	elapsed := time.Duration(now.UnixNano() % int64(quota.Period))
+
+	current, ok := store.windows[key]
+	if !ok || current.index < index-1 {
+		current = &slidingWindow{index: index}
+		store.windows[key] = current
+	}
+	if current.index == index-1 {
+		current.previous, current.current, current.index = current.current, 0, index
+	}
+	current.period = quota.Period
+
+	weight := float64(quota.Period-elapsed) / float64(quota.Period)
+	allowed := float64(current.previous)*weight+float64(current.current)+1 <= float64(quota.Limit)
+	if allowed {
+		current.current++
+	}
+	return slidingWindowResult(quota, current.previous, current.current, elapsed, allowed), nil
+}
+
+func (store *MemoryStore) sweep(now time.Time) {
+	if now.Sub(store.lastSweep) < memorySweepInterval {
+		return
+	}
+	for key, current := range store.buckets {
+		if now.Sub(current.updated) >= current.period {
+			delete(store.buckets, key)
+		}
+	}
+	for key, current := range store.windows {
+		if now.UnixNano()/int64(current.period) > current.index+1 {
+			delete(store.windows, key)
+		}
+	}
+	store.lastSweep = now
+}
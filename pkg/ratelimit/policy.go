@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Key is what callers are told apart by.
+type Key string
+
+const (
+	KeyIP     Key = "ip"
+	KeyUser   Key = "user"
+	KeyAPIKey Key = "api_key"
+)
+
+func ParseKey(value string) (Key, error) {
+	switch key := Key(value); key {
+	case KeyIP, KeyUser, KeyAPIKey:
+		return key, nil
+	}
+	return "", fmt.Errorf("expecting key %s, %s or %s", KeyIP, KeyUser, KeyAPIKey)
+}
+
+// Rule is a quota for the callers of a plan on a route, as "GET /users/:id".
+// Rules without plan or route apply to all plans or routes.
+type Rule struct {
+	Plan  string
+	Route string
+	Quota Quota
+}
+
+func (rule Rule) name() string {
+	selector := "*"
+	if rule.Plan != "" {
+		selector = "@" + rule.Plan
+	}
+	if rule.Route != "" {
+		selector += " " + rule.Route
+	}
+	return selector
+}
+
+func (rule Rule) specificity() int {
+	specificity := 0
+	if rule.Route != "" {
+		specificity += 2
+	}
+	if rule.Plan != "" {
+		specificity++
+	}
+	return specificity
+}
+
+// ParseRules parses comma separated selector=limit/period entries, where the
+// selector is *, a route as "POST /users/", a plan as "@pro" or a plan and a
+// route as "@pro POST /users/", and period is a duration as "1m".
+func ParseRules(value string) ([]Rule, error) {
+	var rules []Rule
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		rule, err := parseRule(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rule %q: %v", entry, err)
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func parseRule(entry string) (Rule, error) {
+	var rule Rule
+	selector, quota, ok := strings.Cut(entry, "=")
+	if !ok {
+		return rule, errors.New("expecting selector=limit/period")
+	}
+
+	selector = strings.TrimSpace(selector)
+	if strings.HasPrefix(selector, "@") {
+		plan, route, _ := strings.Cut(selector[1:], " ")
+		rule.Plan = plan
+		selector = strings.TrimSpace(route)
+	}
+	if selector != "*" && selector != "" {
+		method, path, ok := strings.Cut(selector, " ")
+		if !ok || !strings.HasPrefix(path, "/") {
+			return rule, errors.New("expecting route as METHOD /path")
+		}
+		rule.Route = strings.ToUpper(method) + " " + path
+	}
+
+	limit, period, ok := strings.Cut(strings.TrimSpace(quota), "/")
+	if !ok {
+		return rule, errors.New("expecting quota as limit/period")
+	}
+	var err error
+	rule.Quota.Limit, err = strconv.Atoi(limit)
+	if err != nil || rule.Quota.Limit <= 0 {
+		return rule, errors.New("limit must be a positive integer")
+	}
+	rule.Quota.Period, err = time.ParseDuration(period)
+	if err != nil || rule.Quota.Period < time.Millisecond {
+		return rule, errors.New("period must be a duration of at least 1ms")
+	}
+	return rule, nil
+}
+
+// Policy applies the most specific rule for the plan of a caller and a
+// route, with the route taking precedence over the plan. Requests that no
+// rule applies to are not limited.
+type Policy struct {
+	rules    []Rule
+	limiters []Limiter
+}
+
+func NewPolicy(store Store, algorithm Algorithm, rules []Rule) *Policy {
+	policy := &Policy{rules: rules}
+	for _, rule := range rules {
+		policy.limiters = append(policy.limiters, NewStoreLimiter(store, algorithm, rule.Quota))
+	}
+	return policy
+}
+
+// Allow counts a request of the caller identified by key on route against the
+// most specific rule, the first one if several are equally specific. It
+// reports false if no rule applies.
+func (policy *Policy) Allow(
+	ctx context.Context,
+	route string,
+	plans []string,
+	key string,
+) (Result, bool, error) {
+	match := -1
+	for i, rule := range policy.rules {
+		if rule.Route != "" && rule.Route != route {
+			continue
+		}
+		if rule.Plan != "" && !contains(plans, rule.Plan) {
+			continue
+		}
+		if match == -1 || rule.specificity() > policy.rules[match].specificity() {
+			match = i
+		}
+	}
+	if match == -1 {
+		return Result{}, false, nil
+	}
+
+	result, err := policy.limiters[match].Allow(ctx, policy.rules[match].name()+"|"+key)
+	return result, true, err
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// The scripts take the time from the caller, as not every Redis compatible
+// server allows TIME in scripts. Instances should have synchronized clocks.
+var (
+	tokenBucketScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or limit
+local updated = tonumber(state[2]) or now
+tokens = math.min(limit, tokens + math.max(0, now - updated) * limit / period)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', tostring(now))
+redis.call('PEXPIRE', KEYS[1], period)
+return {allowed, tostring(tokens)}
+`)
+	slidingWindowScript = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+local allowed = 0
+if previous * (period - elapsed) / period + current + 1 <= limit then
+	current = redis.call('INCR', KEYS[1])
+	redis.call('PEXPIRE', KEYS[1], 2 * period)
+	allowed = 1
+end
+return {allowed, previous, current}
+`)
+)
+
+// RedisStore keeps the state of the algorithms in Redis or a Redis compatible
+// server, shared by a fleet of instances.
+type RedisStore struct {
+	client redis.Scripter
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisStore(client redis.Scripter, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix, now: time.Now}
+}
+
+func (store *RedisStore) TakeToken(ctx context.Context, key string, quota Quota) (Result, error) {
+	reply, err := tokenBucketScript.Run(
+		ctx,
+		store.client,
+		[]string{store.prefix + key},
+		quota.Limit,
+		quota.Period.Milliseconds(),
+		store.now().UnixMilli(),
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("error taking token: %v", err)
+	}
+
+	allowed, _ := reply[0].(int64)
+	tokens, err := strconv.ParseFloat(fmt.Sprint(reply[1]), 64)
+	if err != nil {
+		return Result{}, fmt.Errorf("error parsing tokens: %v", err)
+	}
+	return tokenBucketResult(quota, tokens, allowed == 1), nil
+}
+
+func (store *RedisStore) SlideWindow(
+	ctx context.Context,
+	key string,
+	quota Quota,
+) (Result, error) {
+	now := store.now().UnixMilli()
+	period := quota.Period.Milliseconds()
+	index := now / period
+	elapsed := now % period
+
+	// The hash tag keeps both windows in the same slot of a cluster.
+	tag := "{" + store.prefix + key + "}"
+	reply, err := slidingWindowScript.Run(
+		ctx,
+		store.client,
+		[]string{fmt.Sprintf("%s:%d", tag, index), fmt.Sprintf("%s:%d", tag, index-1)},
+		quota.Limit,
+		period,
+		elapsed,
+	).Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("error sliding window: %v", err)
+	}
+
+	allowed, _ := reply[0].(int64)
+	previous, _ := reply[1].(int64)
+	current, _ := reply[2].(int64)
+	return slidingWindowResult(
+		quota,
+		int(previous),
+		int(current),
+		time.Duration(elapsed)*time.Millisecond,
+		allowed == 1,
+	), nil
+}
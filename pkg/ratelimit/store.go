@@ -0,0 +1,112 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+type Algorithm string
+
+const (
+	// TokenBucket allows bursts of up to Limit requests and refills Limit
+	// tokens per Period.
+	TokenBucket Algorithm = "token_bucket"
+	// SlidingWindow allows Limit requests in any Period, estimated from the
+	// counts of the current and the previous fixed window.
+	SlidingWindow Algorithm = "sliding_window"
+)
+
+func ParseAlgorithm(value string) (Algorithm, error) {
+	switch algorithm := Algorithm(value); algorithm {
+	case TokenBucket, SlidingWindow:
+		return algorithm, nil
+	}
+	return "", fmt.Errorf("expecting algorithm %s or %s", TokenBucket, SlidingWindow)
+}
+
+type Quota struct {
+	Limit  int
+	Period time.Duration
+}
+
+// Store keeps the state of the algorithms, for a single instance or for a
+// fleet. Results are computed and counted atomically per key.
+type Store interface {
+	TakeToken(ctx context.Context, key string, quota Quota) (Result, error)
+	SlideWindow(ctx context.Context, key string, quota Quota) (Result, error)
+}
+
+// StoreLimiter limits requests per key with an algorithm whose state is kept
+// in a Store.
+type StoreLimiter struct {
+	store     Store
+	algorithm Algorithm
+	quota     Quota
+}
+
+func NewStoreLimiter(store Store, algorithm Algorithm, quota Quota) *StoreLimiter {
+	return &StoreLimiter{store: store, algorithm: algorithm, quota: quota}
+}
+
+func (limiter *StoreLimiter) Allow(ctx context.Context, key string) (Result, error) {
+	if limiter.algorithm == SlidingWindow {
+		return limiter.store.SlideWindow(ctx, key, limiter.quota)
+	}
+	return limiter.store.TakeToken(ctx, key, limiter.quota)
+}
+
+// tokenBucketResult describes a bucket holding tokens after a request. The
+// reset is when the next request is allowed if it was rejected, and when the
+// bucket is full otherwise.
+func tokenBucketResult(quota Quota, tokens float64, allowed bool) Result {
+	perToken := float64(quota.Period) / float64(quota.Limit)
+	missing := float64(quota.Limit) - tokens
+	if !allowed {
+		missing = 1 - tokens
+	}
+	return Result{
+		Allowed:    allowed,
+		Limit:      quota.Limit,
+		Remaining:  int(math.Floor(tokens)),
+		ResetAfter: time.Duration(math.Ceil(missing * perToken)),
+	}
+}
+
+// slidingWindowResult describes the counts of the previous and the current
+// window after a request, elapsed into the current window. The reset is when
+// the next request is allowed if it was rejected, and the end of the current
+// window otherwise.
+func slidingWindowResult(
+	quota Quota,
+	previous int,
+	current int,
+	elapsed time.Duration,
+	allowed bool,
+) Result {
+	period := float64(quota.Period)
+	weight := (period - float64(elapsed)) / period
+	count := float64(previous)*weight + float64(current)
+
+	result := Result{
+		Allowed:    allowed,
+		Limit:      quota.Limit,
+		Remaining:  int(math.Max(0, math.Floor(float64(quota.Limit)-count))),
+		ResetAfter: quota.Period - elapsed,
+	}
+	if allowed {
+		return result
+	}
+
+	// A request is allowed once the weighted count is at most limit-1.
+	budget := float64(quota.Limit - 1)
+	if float64(current) <= budget {
+		wait := (1-(budget-float64(current))/float64(previous))*period - float64(elapsed)
+		result.ResetAfter = time.Duration(math.Ceil(math.Max(0, wait)))
+		return result
+	}
+	wait := (1 - budget/float64(current)) * period
+	result.ResetAfter += time.Duration(math.Ceil(wait))
+	return result
+}
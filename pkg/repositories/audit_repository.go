@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type AuditFilter struct {
+	UserId string
+	Actor  string
+	Action string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
+
+type AuditRepository interface {
+	GetAuditEntries(ctx context.Context, filter AuditFilter) ([]*models.AuditEntry, error)
+}
+
+type AuditSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLAuditRepository(DB *gorm.DB) *AuditSQLRepository {
+	return &AuditSQLRepository{gormDB: DB}
+}
+
+func (repo *AuditSQLRepository) GetAuditEntries(
+	ctx context.Context,
+	filter AuditFilter,
+) ([]*models.AuditEntry, error) {
+	query := repo.gormDB.WithContext(ctx)
+	if filter.UserId != "" {
+		query = query.Where("user_id = ?", filter.UserId)
+	}
+	if filter.Actor != "" {
+		query = query.Where("actor = ?", filter.Actor)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+
+	var entries []*models.AuditEntry
+	err := query.Order("id DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).
+		Error
+	return entries, err
+}
@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+var ErrCredentialNotFound = errors.New("credential not found")
+
+type CredentialRepository interface {
+	GetCredentialByUserId(ctx context.Context, userId string) (*models.Credential, error)
+	GetCredentialByEmail(ctx context.Context, email string) (*models.Credential, error)
+	SaveCredential(ctx context.Context, credential *models.Credential) error
+	UpdatePasswordHash(ctx context.Context, userId string, passwordHash string) error
+}
+
+type CredentialSQLRepository struct {
+	gormDB           *gorm.DB
+	rowLevelSecurity bool
+}
+
+func NewSQLCredentialRepository(DB *gorm.DB) *CredentialSQLRepository {
+	return &CredentialSQLRepository{gormDB: DB}
+}
+
+// WithRowLevelSecurity looks up emails in a transaction that sets the tenant of
+// the context, for the policies installed by tenancy.MigrateRowLevelSecurity.
+func (repo *CredentialSQLRepository) WithRowLevelSecurity() *CredentialSQLRepository {
+	repo.rowLevelSecurity = true
+	return repo
+}
+
+func (repo *CredentialSQLRepository) GetCredentialByUserId(
+	ctx context.Context,
+	userId string,
+) (*models.Credential, error) {
+	credential := &models.Credential{}
+	err := repo.gormDB.WithContext(ctx).Where("user_id = ?", userId).First(credential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (repo *CredentialSQLRepository) GetCredentialByEmail(
+	ctx context.Context,
+	email string,
+) (*models.Credential, error) {
+	credential := &models.Credential{}
+	find := func(db *gorm.DB) error {
+		return db.Joins("JOIN users ON users.id = credentials.user_id").
+			Scopes(tenancy.Scope(ctx, "users")).
+			Where("lower(users.email) = lower(?)", email).
+			Order("credentials.created_at").
+			First(credential).
+			Error
+	}
+	var err error
+	if repo.rowLevelSecurity {
+		err = tenancy.Transaction(ctx, repo.gormDB, find)
+	} else {
+		err = find(repo.gormDB.WithContext(ctx))
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCredentialNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return credential, nil
+}
+
+func (repo *CredentialSQLRepository) SaveCredential(
+	ctx context.Context,
+	credential *models.Credential,
+) error {
+	return repo.gormDB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"password_hash", "password_changed_at", "updated_at"},
+			),
+		}).
+		Create(credential).
+		Error
+}
+
+func (repo *CredentialSQLRepository) UpdatePasswordHash(
+	ctx context.Context,
+	userId string,
+	passwordHash string,
+) error {
+	return repo.gormDB.WithContext(ctx).
+		Model(&models.Credential{}).
+		Where("user_id = ?", userId).
+		Update("password_hash", passwordHash).
+		Error
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var (
+	ErrGroupNotFound    = errors.New("group not found")
+	ErrNotGroupMember   = errors.New("user is not a member of the group")
+	ErrNotSubgroup      = errors.New("group is not a subgroup of the group")
+	ErrGroupNestingLoop = errors.New("group nesting would create a cycle")
+)
+
+// UserGroup is a group a user belongs to, either directly or through one of
+// its subgroups.
+type UserGroup struct {
+	models.Group `gorm:"embedded"`
+	Direct       bool
+}
+
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, group *models.Group) error
+	GetAllGroups(ctx context.Context, options ListOptions) ([]*models.Group, error)
+	GetGroupById(ctx context.Context, id string) (*models.Group, error)
+	UpdateGroupById(ctx context.Context, id string, updates *models.Group) (*models.Group, error)
+	DeleteGroupById(ctx context.Context, id string) error
+
+	AddMember(ctx context.Context, groupId string, userId string) error
+	RemoveMember(ctx context.Context, groupId string, userId string) error
+	GetMembers(ctx context.Context, groupId string) ([]*models.User, error)
+	AddSubgroup(ctx context.Context, groupId string, childId string) error
+	RemoveSubgroup(ctx context.Context, groupId string, childId string) error
+	GetSubgroups(ctx context.Context, groupId string) ([]*models.Group, error)
+	GetUserGroups(ctx context.Context, userId string) ([]*UserGroup, error)
+}
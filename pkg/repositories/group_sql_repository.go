@@ -0,0 +1,282 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+// groupNestingLockKey serializes changes to the group hierarchy so that two
+// concurrent nestings cannot form a cycle together.
+const groupNestingLockKey = 7_310_451_963
+
+const descendantsQuery = `WITH RECURSIVE descendants(id) AS (
+	SELECT CAST(? AS text)
+	UNION
+	SELECT group_nestings.child_id FROM group_nestings
+	JOIN descendants ON group_nestings.parent_id = descendants.id
+)
+SELECT EXISTS (SELECT 1 FROM descendants WHERE id = ?)`
+
+const userGroupsQuery = `WITH RECURSIVE memberships(group_id, direct) AS (
+	SELECT group_id, true FROM group_members WHERE user_id = ?
+	UNION
+	SELECT group_nestings.parent_id, false FROM group_nestings
+	JOIN memberships ON group_nestings.child_id = memberships.group_id
+)
+SELECT "groups".*, bool_or(memberships.direct) AS direct FROM "groups"
+JOIN memberships ON memberships.group_id = "groups".id
+GROUP BY "groups".id
+ORDER BY "groups".name, "groups".id`
+
+type GroupSQLRepository struct {
+	gormDB           *gorm.DB
+	rowLevelSecurity bool
+}
+
+func NewSQLGroupRepository(DB *gorm.DB) *GroupSQLRepository {
+	return &GroupSQLRepository{gormDB: DB}
+}
+
+// WithRowLevelSecurity sets the tenant of the context in the transactions that
+// read users, for the policies installed by tenancy.MigrateRowLevelSecurity.
+func (repo *GroupSQLRepository) WithRowLevelSecurity() *GroupSQLRepository {
+	repo.rowLevelSecurity = true
+	return repo
+}
+
+func (repo *GroupSQLRepository) CreateGroup(ctx context.Context, group *models.Group) error {
+	return repo.gormDB.WithContext(ctx).Create(group).Error
+}
+
+func (repo *GroupSQLRepository) GetAllGroups(
+	ctx context.Context,
+	options ListOptions,
+) ([]*models.Group, error) {
+	query := repo.gormDB.WithContext(ctx).Order("name").Order("id")
+	if options.Limit > 0 {
+		query = query.Limit(options.Limit).Offset(options.Offset)
+	}
+
+	var groups []*models.Group
+	err := query.Find(&groups).Error
+	return groups, err
+}
+
+func (repo *GroupSQLRepository) GetGroupById(
+	ctx context.Context,
+	id string,
+) (*models.Group, error) {
+	return getGroup(repo.gormDB.WithContext(ctx), id)
+}
+
+func (repo *GroupSQLRepository) UpdateGroupById(
+	ctx context.Context,
+	id string,
+	updates *models.Group,
+) (*models.Group, error) {
+	group, err := repo.GetGroupById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.gormDB.WithContext(ctx).
+		Model(group).
+		Select("Name", "Description").
+		Updates(updates).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+// DeleteGroupById deletes a group. Its memberships and nestings are removed by
+// the foreign key constraints.
+func (repo *GroupSQLRepository) DeleteGroupById(ctx context.Context, id string) error {
+	result := repo.gormDB.WithContext(ctx).Where("id = ?", id).Delete(&models.Group{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrGroupNotFound
+	}
+	return nil
+}
+
+func (repo *GroupSQLRepository) AddMember(
+	ctx context.Context,
+	groupId string,
+	userId string,
+) error {
+	return repo.transaction(ctx, func(tx *gorm.DB) error {
+		_, err := getGroup(tx, groupId)
+		if err != nil {
+			return err
+		}
+
+		err = tx.Select("id").Where("id = ?", userId).First(&models.User{}).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.GroupMember{GroupId: groupId, UserId: userId}).
+			Error
+	})
+}
+
+func (repo *GroupSQLRepository) RemoveMember(
+	ctx context.Context,
+	groupId string,
+	userId string,
+) error {
+	result := repo.gormDB.WithContext(ctx).
+		Where("group_id = ? AND user_id = ?", groupId, userId).
+		Delete(&models.GroupMember{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotGroupMember
+	}
+	return nil
+}
+
+func (repo *GroupSQLRepository) GetMembers(
+	ctx context.Context,
+	groupId string,
+) ([]*models.User, error) {
+	var users []*models.User
+	err := repo.transaction(ctx, func(tx *gorm.DB) error {
+		_, err := getGroup(tx, groupId)
+		if err != nil {
+			return err
+		}
+
+		members := tx.Model(&models.GroupMember{}).Select("user_id").Where("group_id = ?", groupId)
+		return tx.Where("id IN (?)", members).Order("id").Find(&users).Error
+	})
+	return users, err
+}
+
+// AddSubgroup nests childId in groupId. It returns ErrGroupNestingLoop if
+// groupId is childId or one of its descendants.
+func (repo *GroupSQLRepository) AddSubgroup(
+	ctx context.Context,
+	groupId string,
+	childId string,
+) error {
+	return repo.transaction(ctx, func(tx *gorm.DB) error {
+		err := tx.Exec("SELECT pg_advisory_xact_lock(?)", groupNestingLockKey).Error
+		if err != nil {
+			return err
+		}
+
+		for _, id := range []string{groupId, childId} {
+			_, err = getGroup(tx, id)
+			if err != nil {
+				return err
+			}
+		}
+
+		var cycle bool
+		err = tx.Raw(descendantsQuery, childId, groupId).Scan(&cycle).Error
+		if err != nil {
+			return err
+		}
+		if cycle {
+			return ErrGroupNestingLoop
+		}
+
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.GroupNesting{ParentId: groupId, ChildId: childId}).
+			Error
+	})
+}
+
+func (repo *GroupSQLRepository) RemoveSubgroup(
+	ctx context.Context,
+	groupId string,
+	childId string,
+) error {
+	result := repo.gormDB.WithContext(ctx).
+		Where("parent_id = ? AND child_id = ?", groupId, childId).
+		Delete(&models.GroupNesting{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotSubgroup
+	}
+	return nil
+}
+
+func (repo *GroupSQLRepository) GetSubgroups(
+	ctx context.Context,
+	groupId string,
+) ([]*models.Group, error) {
+	var groups []*models.Group
+	err := repo.transaction(ctx, func(tx *gorm.DB) error {
+		_, err := getGroup(tx, groupId)
+		if err != nil {
+			return err
+		}
+
+		children := tx.Model(&models.GroupNesting{}).
+			Select("child_id").
+			Where("parent_id = ?", groupId)
+		return tx.Where("id IN (?)", children).Order("name").Order("id").Find(&groups).Error
+	})
+	return groups, err
+}
+
+// GetUserGroups returns the groups of a user including the groups it inherits
+// through nesting.
+func (repo *GroupSQLRepository) GetUserGroups(
+	ctx context.Context,
+	userId string,
+) ([]*UserGroup, error) {
+	var groups []*UserGroup
+	err := repo.transaction(ctx, func(tx *gorm.DB) error {
+		err := tx.Select("id").Where("id = ?", userId).First(&models.User{}).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Raw(userGroupsQuery, userId).Scan(&groups).Error
+	})
+	return groups, err
+}
+
+func getGroup(tx *gorm.DB, id string) (*models.Group, error) {
+	group := &models.Group{}
+	err := tx.Where("id = ?", id).First(group).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return group, nil
+}
+
+func (repo *GroupSQLRepository) transaction(
+	ctx context.Context,
+	fn func(tx *gorm.DB) error,
+) error {
+	if repo.rowLevelSecurity {
+		return tenancy.Transaction(ctx, repo.gormDB, fn)
+	}
+	return repo.gormDB.WithContext(ctx).Transaction(fn)
+}
@@ -0,0 +1,72 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type HistoryRepository interface {
+	GetUserHistory(
+		ctx context.Context,
+		userId string,
+		options ListOptions,
+	) ([]*models.UserFieldChange, error)
+	GetUserChangesAfter(
+		ctx context.Context,
+		userId string,
+		after time.Time,
+	) ([]*models.UserFieldChange, error)
+	DeleteChangesBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+type HistorySQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLHistoryRepository(DB *gorm.DB) *HistorySQLRepository {
+	return &HistorySQLRepository{gormDB: DB}
+}
+
+func (repo *HistorySQLRepository) GetUserHistory(
+	ctx context.Context,
+	userId string,
+	options ListOptions,
+) ([]*models.UserFieldChange, error) {
+	var changes []*models.UserFieldChange
+	err := repo.gormDB.WithContext(ctx).
+		Where("user_id = ?", userId).
+		Order("id DESC").
+		Limit(options.Limit).
+		Offset(options.Offset).
+		Find(&changes).
+		Error
+	return changes, err
+}
+
+func (repo *HistorySQLRepository) GetUserChangesAfter(
+	ctx context.Context,
+	userId string,
+	after time.Time,
+) ([]*models.UserFieldChange, error) {
+	var changes []*models.UserFieldChange
+	err := repo.gormDB.WithContext(ctx).
+		Where("user_id = ? AND changed_at > ?", userId, after).
+		Order("id DESC").
+		Find(&changes).
+		Error
+	return changes, err
+}
+
+func (repo *HistorySQLRepository) DeleteChangesBefore(
+	ctx context.Context,
+	cutoff time.Time,
+) (int64, error) {
+	result := repo.gormDB.WithContext(ctx).
+		Where("changed_at < ?", cutoff).
+		Delete(&models.UserFieldChange{})
+	return result.RowsAffected, result.Error
+}
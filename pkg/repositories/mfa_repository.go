@@ -0,0 +1,179 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var ErrMFANotEnrolled = errors.New("mfa not enrolled")
+
+type MFARepository interface {
+	GetTOTPFactor(ctx context.Context, userId string) (*models.TOTPFactor, error)
+	SaveTOTPFactor(ctx context.Context, factor *models.TOTPFactor) error
+	ConfirmTOTPFactor(
+		ctx context.Context,
+		userId string,
+		step int64,
+		recoveryCodeHashes []string,
+	) error
+	UseTOTPStep(ctx context.Context, userId string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userId string, codeHash string) (bool, error)
+	RecordTOTPFailure(
+		ctx context.Context,
+		userId string,
+		maxFailures int,
+		lockedUntil time.Time,
+	) (bool, error)
+	ResetTOTPFailures(ctx context.Context, userId string) error
+	DeleteMFA(ctx context.Context, userId string) error
+}
+
+type MFASQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLMFARepository(DB *gorm.DB) *MFASQLRepository {
+	return &MFASQLRepository{gormDB: DB}
+}
+
+func (repo *MFASQLRepository) GetTOTPFactor(
+	ctx context.Context,
+	userId string,
+) (*models.TOTPFactor, error) {
+	factor := &models.TOTPFactor{}
+	err := repo.gormDB.WithContext(ctx).Where("user_id = ?", userId).First(factor).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return nil, err
+	}
+	return factor, nil
+}
+
+func (repo *MFASQLRepository) SaveTOTPFactor(
+	ctx context.Context,
+	factor *models.TOTPFactor,
+) error {
+	return repo.gormDB.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"secret", "confirmed_at", "last_used_step", "updated_at"},
+			),
+		}).
+		Create(factor).
+		Error
+}
+
+func (repo *MFASQLRepository) ConfirmTOTPFactor(
+	ctx context.Context,
+	userId string,
+	step int64,
+	recoveryCodeHashes []string,
+) error {
+	return repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.TOTPFactor{}).
+			Where("user_id = ? AND confirmed_at IS NULL", userId).
+			Updates(map[string]any{"confirmed_at": time.Now(), "last_used_step": step})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrMFANotEnrolled
+		}
+
+		err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		codes := make([]*models.RecoveryCode, len(recoveryCodeHashes))
+		for i, hash := range recoveryCodeHashes {
+			codes[i] = &models.RecoveryCode{UserId: userId, CodeHash: hash}
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseTOTPStep records the step of an accepted code and reports false if the
+// step or a later one was already used, so every code is accepted only once.
+func (repo *MFASQLRepository) UseTOTPStep(
+	ctx context.Context,
+	userId string,
+	step int64,
+) (bool, error) {
+	result := repo.gormDB.WithContext(ctx).
+		Model(&models.TOTPFactor{}).
+		Where("user_id = ? AND confirmed_at IS NOT NULL AND last_used_step < ?", userId, step).
+		Update("last_used_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (repo *MFASQLRepository) UseRecoveryCode(
+	ctx context.Context,
+	userId string,
+	codeHash string,
+) (bool, error) {
+	result := repo.gormDB.WithContext(ctx).
+		Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userId, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
+
+// RecordTOTPFailure counts an invalid code and, once maxFailures codes in a row
+// were invalid, locks the factor until lockedUntil and starts counting again.
+// It reports whether the factor was locked.
+func (repo *MFASQLRepository) RecordTOTPFailure(
+	ctx context.Context,
+	userId string,
+	maxFailures int,
+	lockedUntil time.Time,
+) (bool, error) {
+	locked := false
+	err := repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		factor := &models.TOTPFactor{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", userId).
+			First(factor).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrMFANotEnrolled
+		}
+		if err != nil {
+			return err
+		}
+
+		updates := map[string]any{"failed_attempts": factor.FailedAttempts + 1}
+		if factor.FailedAttempts+1 >= maxFailures {
+			updates = map[string]any{"failed_attempts": 0, "locked_until": lockedUntil}
+			locked = true
+		}
+		return tx.Model(factor).Updates(updates).Error
+	})
+	return locked, err
+}
+
+func (repo *MFASQLRepository) ResetTOTPFailures(ctx context.Context, userId string) error {
+	return repo.gormDB.WithContext(ctx).
+		Model(&models.TOTPFactor{}).
+		Where("user_id = ?", userId).
+		Update("failed_attempts", 0).
+		Error
+}
+
+func (repo *MFASQLRepository) DeleteMFA(ctx context.Context, userId string) error {
+	return repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ?", userId).Delete(&models.RecoveryCode{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userId).Delete(&models.TOTPFactor{}).Error
+	})
+}
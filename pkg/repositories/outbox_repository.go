@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var ErrOutboxMessageNotFound = errors.New("dead outbox message not found")
+
+// OutboxRepository manages outbox messages that the relay gave up on.
+type OutboxRepository interface {
+	GetDeadMessages(ctx context.Context, options ListOptions) ([]*models.OutboxMessage, error)
+	RetryDeadMessage(ctx context.Context, id int64) (*models.OutboxMessage, error)
+}
+
+type OutboxSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLOutboxRepository(DB *gorm.DB) *OutboxSQLRepository {
+	return &OutboxSQLRepository{gormDB: DB}
+}
+
+func (repo *OutboxSQLRepository) GetDeadMessages(
+	ctx context.Context,
+	options ListOptions,
+) ([]*models.OutboxMessage, error) {
+	messages := []*models.OutboxMessage{}
+	err := repo.gormDB.WithContext(ctx).
+		Where("dead_at IS NOT NULL").
+		Order("id").
+		Limit(options.Limit).
+		Offset(options.Offset).
+		Find(&messages).
+		Error
+	return messages, err
+}
+
+// RetryDeadMessage hands a dead message back to the relay with a fresh budget
+// of attempts.
+func (repo *OutboxSQLRepository) RetryDeadMessage(
+	ctx context.Context,
+	id int64,
+) (*models.OutboxMessage, error) {
+	message := &models.OutboxMessage{}
+	err := repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("id = ? AND dead_at IS NOT NULL", id).First(message).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrOutboxMessageNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		message.DeadAt = nil
+		message.Attempts = 0
+		message.LastError = ""
+		return tx.Model(message).Select("DeadAt", "Attempts", "LastError").Updates(message).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return message, nil
+}
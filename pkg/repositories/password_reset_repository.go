@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+var ErrPasswordResetTokenInvalid = errors.New("password reset token is invalid or expired")
+
+type PasswordResetRepository interface {
+	CreatePasswordResetToken(ctx context.Context, token *models.PasswordResetToken) error
+	GetPasswordResetToken(
+		ctx context.Context,
+		tokenHash string,
+		now time.Time,
+	) (*models.PasswordResetToken, error)
+	ResetPassword(
+		ctx context.Context,
+		tokenHash string,
+		now time.Time,
+		passwordHash string,
+	) (*models.PasswordResetToken, error)
+}
+
+type PasswordResetSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLPasswordResetRepository(DB *gorm.DB) *PasswordResetSQLRepository {
+	return &PasswordResetSQLRepository{gormDB: DB}
+}
+
+func (repo *PasswordResetSQLRepository) CreatePasswordResetToken(
+	ctx context.Context,
+	token *models.PasswordResetToken,
+) error {
+	return repo.gormDB.WithContext(ctx).Create(token).Error
+}
+
+// GetPasswordResetToken finds the token in any tenant, since links do not
+// carry the tenant. The token then selects the tenant of the user.
+func (repo *PasswordResetSQLRepository) GetPasswordResetToken(
+	ctx context.Context,
+	tokenHash string,
+	now time.Time,
+) (*models.PasswordResetToken, error) {
+	token := &models.PasswordResetToken{}
+	err := repo.gormDB.WithContext(tenancy.WithoutTenant(ctx)).
+		Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+		First(token).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPasswordResetTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// ResetPassword consumes the token, replaces the password and invalidates all
+// other outstanding reset tokens of the user in one transaction.
+func (repo *PasswordResetSQLRepository) ResetPassword(
+	ctx context.Context,
+	tokenHash string,
+	now time.Time,
+	passwordHash string,
+) (*models.PasswordResetToken, error) {
+	token := &models.PasswordResetToken{}
+	err := repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(token).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPasswordResetTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Model(&models.PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserId).
+			Update("used_at", now).
+			Error
+		if err != nil {
+			return err
+		}
+
+		credential := &models.Credential{
+			UserId:            token.UserId,
+			PasswordHash:      passwordHash,
+			PasswordChangedAt: now,
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns(
+				[]string{"password_hash", "password_changed_at", "updated_at"},
+			),
+		}).
+			Create(credential).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
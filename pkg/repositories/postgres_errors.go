@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	"errors"
+
+	"github.com/jackc/pgconn"
+	"github.com/lib/pq"
+)
+
+const (
+	uniqueViolation     = "23505"
+	foreignKeyViolation = "23503"
+)
+
+// violates reports whether err is a Postgres error with code raised by
+// constraint, or by any constraint if it is empty. Both the lib/pq and pgx
+// drivers are supported.
+func violates(err error, code string, constraint string) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return string(pqErr.Code) == code && (constraint == "" || pqErr.Constraint == constraint)
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == code && (constraint == "" || pgErr.ConstraintName == constraint)
+	}
+	return false
+}
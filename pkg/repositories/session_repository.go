@@ -0,0 +1,177 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var (
+	ErrSessionNotFound     = errors.New("session not found")
+	ErrRefreshTokenInvalid = errors.New("refresh token is invalid or expired")
+	ErrRefreshTokenReused  = errors.New("refresh token was already used")
+)
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *models.Session, tokenHash string) error
+	RotateRefreshToken(
+		ctx context.Context,
+		tokenHash string,
+		newTokenHash string,
+		update *models.Session,
+	) (*models.Session, error)
+	ListSessions(ctx context.Context, userId string, now time.Time) ([]models.Session, error)
+	RevokeSession(ctx context.Context, userId string, sessionId string, now time.Time) error
+	RevokeUserSessions(ctx context.Context, userId string, now time.Time) ([]string, error)
+}
+
+type SessionSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLSessionRepository(DB *gorm.DB) *SessionSQLRepository {
+	return &SessionSQLRepository{gormDB: DB}
+}
+
+func (repo *SessionSQLRepository) CreateSession(
+	ctx context.Context,
+	session *models.Session,
+	tokenHash string,
+) error {
+	return repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Create(session).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(&models.RefreshToken{SessionId: session.Id, TokenHash: tokenHash}).Error
+	})
+}
+
+// RotateRefreshToken replaces a refresh token with a new one and updates the
+// activity of its session from update. Presenting a token that was already
+// rotated revokes the session and returns ErrRefreshTokenReused along with the
+// revoked session.
+func (repo *SessionSQLRepository) RotateRefreshToken(
+	ctx context.Context,
+	tokenHash string,
+	newTokenHash string,
+	update *models.Session,
+) (*models.Session, error) {
+	session := &models.Session{}
+	reused := false
+	err := repo.gormDB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token := &models.RefreshToken{}
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", tokenHash).
+			First(token).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND revoked_at IS NULL AND expires_at > ?", token.SessionId, update.LastUsedAt).
+			First(session).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrRefreshTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		if token.UsedAt != nil {
+			reused = true
+			// Commit the revocation before reporting the reuse.
+			return tx.Model(session).Update("revoked_at", update.LastUsedAt).Error
+		}
+
+		err = tx.Model(token).Update("used_at", update.LastUsedAt).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Create(&models.RefreshToken{SessionId: session.Id, TokenHash: newTokenHash}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(session).
+			Select("user_agent", "ip_address", "last_used_at", "expires_at").
+			Updates(update).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return session, ErrRefreshTokenReused
+	}
+	return session, nil
+}
+
+func (repo *SessionSQLRepository) ListSessions(
+	ctx context.Context,
+	userId string,
+	now time.Time,
+) ([]models.Session, error) {
+	var sessions []models.Session
+	err := repo.gormDB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userId, now).
+		Order("last_used_at DESC").
+		Find(&sessions).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (repo *SessionSQLRepository) RevokeSession(
+	ctx context.Context,
+	userId string,
+	sessionId string,
+	now time.Time,
+) error {
+	result := repo.gormDB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionId, userId).
+		Update("revoked_at", now)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes all active sessions of a user and returns their
+// ids.
+func (repo *SessionSQLRepository) RevokeUserSessions(
+	ctx context.Context,
+	userId string,
+	now time.Time,
+) ([]string, error) {
+	var sessions []models.Session
+	err := repo.gormDB.WithContext(ctx).
+		Model(&sessions).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ? AND revoked_at IS NULL", userId).
+		Update("revoked_at", now).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		ids = append(ids, session.Id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var (
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant already exists")
+	ErrTenantInUse    = errors.New("tenant still has users or groups")
+)
+
+type TenantRepository interface {
+	CreateTenant(ctx context.Context, tenant *models.Tenant) error
+	GetAllTenants(ctx context.Context) ([]*models.Tenant, error)
+	GetTenantById(ctx context.Context, id string) (*models.Tenant, error)
+	UpdateTenantById(ctx context.Context, id string, updates *models.Tenant) (*models.Tenant, error)
+	DeleteTenantById(ctx context.Context, id string) error
+	TenantExists(ctx context.Context, id string) (bool, error)
+}
+
+type TenantSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLTenantRepository(DB *gorm.DB) *TenantSQLRepository {
+	return &TenantSQLRepository{gormDB: DB}
+}
+
+func (repo *TenantSQLRepository) CreateTenant(ctx context.Context, tenant *models.Tenant) error {
+	err := repo.gormDB.WithContext(ctx).Create(tenant).Error
+	if violates(err, uniqueViolation, "") {
+		return ErrTenantExists
+	}
+	return err
+}
+
+func (repo *TenantSQLRepository) GetAllTenants(ctx context.Context) ([]*models.Tenant, error) {
+	var tenants []*models.Tenant
+	err := repo.gormDB.WithContext(ctx).Order("id").Find(&tenants).Error
+	return tenants, err
+}
+
+func (repo *TenantSQLRepository) GetTenantById(
+	ctx context.Context,
+	id string,
+) (*models.Tenant, error) {
+	tenant := &models.Tenant{}
+	err := repo.gormDB.WithContext(ctx).Where("id = ?", id).First(tenant).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrTenantNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+func (repo *TenantSQLRepository) UpdateTenantById(
+	ctx context.Context,
+	id string,
+	updates *models.Tenant,
+) (*models.Tenant, error) {
+	tenant, err := repo.GetTenantById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.gormDB.WithContext(ctx).Model(tenant).Select("Name").Updates(updates).Error
+	if err != nil {
+		return nil, err
+	}
+	return tenant, nil
+}
+
+// DeleteTenantById deletes a tenant without users or groups, which are not
+// deleted along with it.
+func (repo *TenantSQLRepository) DeleteTenantById(ctx context.Context, id string) error {
+	result := repo.gormDB.WithContext(ctx).Where("id = ?", id).Delete(&models.Tenant{})
+	if violates(result.Error, foreignKeyViolation, "") {
+		return ErrTenantInUse
+	}
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTenantNotFound
+	}
+	return nil
+}
+
+func (repo *TenantSQLRepository) TenantExists(ctx context.Context, id string) (bool, error) {
+	var count int64
+	err := repo.gormDB.WithContext(ctx).
+		Model(&models.Tenant{}).
+		Where("id = ?", id).
+		Count(&count).
+		Error
+	return count > 0, err
+}
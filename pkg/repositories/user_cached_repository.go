@@ -0,0 +1,249 @@
+package repositories
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"github.com/johannaojeling/go-rest-api/pkg/cache"
+	"github.com/johannaojeling/go-rest-api/pkg/database"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type CacheStats struct {
+	Hits         int64 `json:"hits"`
+	NegativeHits int64 `json:"negative_hits"`
+	Misses       int64 `json:"misses"`
+	Errors       int64 `json:"errors"`
+}
+
+// UserInvalidator is implemented by user repositories that must be told about
+// users changed by other means than the repository.
+type UserInvalidator interface {
+	InvalidateUser(ctx context.Context, id string)
+}
+
+// CachedUserRepository caches the users returned by GetUserById of the
+// wrapped repository, and for a shorter time the ids that were not found, and
+// invalidates them when users are created, updated or deleted through it.
+// Other methods are passed through. Cache errors are logged and fall back to
+// the wrapped repository.
+type CachedUserRepository struct {
+	UserRepository
+	cache       cache.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	window      time.Duration
+	group       singleflight.Group
+	stats       CacheStats
+}
+
+func NewCachedUserRepository(
+	userRepository UserRepository,
+	userCache cache.Cache,
+	ttl time.Duration,
+	negativeTTL time.Duration,
+) *CachedUserRepository {
+	return &CachedUserRepository{
+		UserRepository: userRepository,
+		cache:          userCache,
+		ttl:            ttl,
+		negativeTTL:    negativeTTL,
+	}
+}
+
+// WithStickyWindow reads users from the primary on misses within window of
+// their invalidation, so that lagging replicas are not cached.
+func (repo *CachedUserRepository) WithStickyWindow(window time.Duration) *CachedUserRepository {
+	repo.window = window
+	return repo
+}
+
+func (repo *CachedUserRepository) Stats() CacheStats {
+	return CacheStats{
+		Hits:         atomic.LoadInt64(&repo.stats.Hits),
+		NegativeHits: atomic.LoadInt64(&repo.stats.NegativeHits),
+		Misses:       atomic.LoadInt64(&repo.stats.Misses),
+		Errors:       atomic.LoadInt64(&repo.stats.Errors),
+	}
+}
+
+func (repo *CachedUserRepository) CreateUser(ctx context.Context, user *models.User) error {
+	err := repo.UserRepository.CreateUser(ctx, user)
+	if err != nil {
+		return err
+	}
+	// Users created with a given id may have been cached as not found.
+	repo.delete(
+		ctx,
+		user.Id,
+		userCacheKey(user.Id),
+		missingUserCacheKey(user.Id, user.TenantId),
+		missingUserCacheKey(user.Id, anyTenant),
+	)
+	return nil
+}
+
+// GetUserById returns cached users if they belong to the tenant in ctx, like
+// the wrapped repository would.
+func (repo *CachedUserRepository) GetUserById(
+	ctx context.Context,
+	id string,
+) (*models.User, error) {
+	tenantId, ok := tenancy.TenantFromContext(ctx)
+	if !ok {
+		tenantId = anyTenant
+	}
+
+	value, ok := repo.get(ctx, userCacheKey(id))
+	if ok {
+		user, err := decodeUser(value)
+		if err == nil {
+			atomic.AddInt64(&repo.stats.Hits, 1)
+			if tenantId != anyTenant && user.TenantId != tenantId {
+				return nil, ErrUserNotFound
+			}
+			return user, nil
+		}
+		log.Printf("error decoding cached user %s: %v", id, err)
+	}
+	missingKey := missingUserCacheKey(id, tenantId)
+	if _, ok := repo.get(ctx, missingKey); ok {
+		atomic.AddInt64(&repo.stats.NegativeHits, 1)
+		return nil, ErrUserNotFound
+	}
+	atomic.AddInt64(&repo.stats.Misses, 1)
+
+	// Concurrent misses share one lookup and its result, which callers must
+	// not modify.
+	result, err, _ := repo.group.Do(missingKey, func() (any, error) {
+		return repo.load(ctx, id, missingKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+	user := *result.(*models.User)
+	return &user, nil
+}
+
+func (repo *CachedUserRepository) UpdateUserById(
+	ctx context.Context,
+	id string,
+	updates *models.User,
+) (*models.User, error) {
+	user, err := repo.UserRepository.UpdateUserById(ctx, id, updates)
+	if err != nil {
+		return nil, err
+	}
+	repo.InvalidateUser(ctx, id)
+	return user, nil
+}
+
+func (repo *CachedUserRepository) DeleteUserById(ctx context.Context, id string) error {
+	err := repo.UserRepository.DeleteUserById(ctx, id)
+	if err != nil {
+		return err
+	}
+	repo.InvalidateUser(ctx, id)
+	return nil
+}
+
+// InvalidateUser removes a user that was changed by other means than the
+// repository from the cache.
+func (repo *CachedUserRepository) InvalidateUser(ctx context.Context, id string) {
+	repo.delete(ctx, id, userCacheKey(id))
+}
+
+func (repo *CachedUserRepository) load(
+	ctx context.Context,
+	id string,
+	missingKey string,
+) (any, error) {
+	if repo.window > 0 {
+		if _, ok := repo.get(ctx, invalidatedUserCacheKey(id)); ok {
+			ctx = database.WithPrimary(ctx)
+		}
+	}
+	user, err := repo.UserRepository.GetUserById(ctx, id)
+	if errors.Is(err, ErrUserNotFound) {
+		repo.set(ctx, missingKey, []byte{1}, repo.negativeTTL)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(user)
+	if err != nil {
+		log.Printf("error encoding user %s: %v", id, err)
+		return user, nil
+	}
+	repo.set(ctx, userCacheKey(id), value, repo.ttl)
+	return user, nil
+}
+
+func (repo *CachedUserRepository) get(ctx context.Context, key string) ([]byte, bool) {
+	value, ok, err := repo.cache.Get(ctx, key)
+	if err != nil {
+		atomic.AddInt64(&repo.stats.Errors, 1)
+		log.Printf("error getting %s from cache: %v", key, err)
+		return nil, false
+	}
+	return value, ok
+}
+
+func (repo *CachedUserRepository) set(
+	ctx context.Context,
+	key string,
+	value []byte,
+	ttl time.Duration,
+) {
+	if ttl <= 0 {
+		return
+	}
+	err := repo.cache.Set(ctx, key, value, ttl)
+	if err != nil {
+		atomic.AddInt64(&repo.stats.Errors, 1)
+		log.Printf("error caching %s: %v", key, err)
+	}
+}
+
+// delete removes the keys of a user and marks the user as invalidated for the
+// sticky window.
+func (repo *CachedUserRepository) delete(ctx context.Context, id string, keys ...string) {
+	repo.set(ctx, invalidatedUserCacheKey(id), []byte{1}, repo.window)
+	err := repo.cache.Delete(ctx, keys...)
+	if err != nil {
+		atomic.AddInt64(&repo.stats.Errors, 1)
+		log.Printf("error invalidating cached user %s: %v", id, err)
+	}
+}
+
+const anyTenant = "*"
+
+// The keys tag the id, so that the keys of a user share a slot of a Redis
+// cluster.
+func userCacheKey(id string) string {
+	return fmt.Sprintf("users:{%s}", id)
+}
+
+func missingUserCacheKey(id string, tenantId string) string {
+	return fmt.Sprintf("users:{%s}:missing:%s", id, tenantId)
+}
+
+func invalidatedUserCacheKey(id string) string {
+	return fmt.Sprintf("users:{%s}:invalidated", id)
+}
+
+func decodeUser(value []byte) (*models.User, error) {
+	user := &models.User{}
+	err := json.Unmarshal(value, user)
+	return user, err
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/cache"
+	"github.com/johannaojeling/go-rest-api/pkg/database"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type countingUserRepository struct {
+	*UserMemoryRepository
+	lookups        int64
+	primaryLookups int64
+	release        chan struct{}
+}
+
+func (repo *countingUserRepository) GetUserById(
+	ctx context.Context,
+	id string,
+) (*models.User, error) {
+	atomic.AddInt64(&repo.lookups, 1)
+	if database.UsesPrimary(ctx) {
+		atomic.AddInt64(&repo.primaryLookups, 1)
+	}
+	if repo.release != nil {
+		<-repo.release
+	}
+	return repo.UserMemoryRepository.GetUserById(ctx, id)
+}
+
+func newCachedUserRepository() (*CachedUserRepository, *countingUserRepository) {
+	userRepository := &countingUserRepository{UserMemoryRepository: NewMemoryUserRepository()}
+	cachedRepository := NewCachedUserRepository(
+		userRepository,
+		cache.NewLRU(100),
+		time.Minute,
+		time.Minute,
+	)
+	return cachedRepository, userRepository
+}
+
+func TestCachedUserRepository_GetUserById(t *testing.T) {
+	cachedRepository, userRepository := newCachedUserRepository()
+	ctx := context.Background()
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Email: "jane.doe@mail.com"}
+	_ = cachedRepository.CreateUser(ctx, user)
+
+	for i := 0; i < 2; i++ {
+		actual, err := cachedRepository.GetUserById(ctx, user.Id)
+		assert.NoError(t, err)
+		assert.Equal(t, "Jane", actual.FirstName, "Should return user")
+	}
+	assert.Equal(t, int64(1), userRepository.lookups, "Should look up user once")
+
+	_, err := cachedRepository.GetUserById(
+		tenancy.WithTenant(ctx, "acme"),
+		user.Id,
+	)
+	assert.ErrorIs(t, err, ErrUserNotFound, "Should not return cached user of other tenant")
+
+	_, err = cachedRepository.UpdateUserById(ctx, user.Id, &models.User{LastName: "Roe"})
+	assert.NoError(t, err)
+	actual, _ := cachedRepository.GetUserById(ctx, user.Id)
+	assert.Equal(t, "Roe", actual.LastName, "Should invalidate user on update")
+
+	_ = cachedRepository.DeleteUserById(ctx, user.Id)
+	_, err = cachedRepository.GetUserById(ctx, user.Id)
+	assert.ErrorIs(t, err, ErrUserNotFound, "Should invalidate user on delete")
+
+	assert.Equal(
+		t,
+		CacheStats{Hits: 2, Misses: 3},
+		cachedRepository.Stats(),
+		"Should count hits and misses",
+	)
+}
+
+func TestCachedUserRepository_NegativeLookup(t *testing.T) {
+	cachedRepository, userRepository := newCachedUserRepository()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		_, err := cachedRepository.GetUserById(ctx, "abc123")
+		assert.ErrorIs(t, err, ErrUserNotFound, "Should not find user")
+	}
+	assert.Equal(t, int64(1), userRepository.lookups, "Should cache negative lookup")
+	assert.Equal(t, int64(1), cachedRepository.Stats().NegativeHits, "Should count negative hit")
+
+	_ = cachedRepository.CreateUser(ctx, &models.User{Id: "abc123", FirstName: "Jane"})
+	actual, err := cachedRepository.GetUserById(ctx, "abc123")
+	assert.NoError(t, err, "Should invalidate negative lookup on create")
+	assert.Equal(t, "Jane", actual.FirstName, "Should return created user")
+}
+
+func TestCachedUserRepository_PrimaryAfterInvalidation(t *testing.T) {
+	cachedRepository, userRepository := newCachedUserRepository()
+	cachedRepository.WithStickyWindow(time.Minute)
+	ctx := context.Background()
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Email: "jane.doe@mail.com"}
+	_ = userRepository.CreateUser(ctx, user)
+
+	_, _ = cachedRepository.GetUserById(ctx, user.Id)
+	assert.Equal(t, int64(0), userRepository.primaryLookups, "Should read from replicas")
+
+	cachedRepository.InvalidateUser(ctx, user.Id)
+	_, _ = cachedRepository.GetUserById(ctx, user.Id)
+	assert.Equal(
+		t,
+		int64(1),
+		userRepository.primaryLookups,
+		"Should read from primary after invalidation",
+	)
+}
+
+func TestCachedUserRepository_CollapseMisses(t *testing.T) {
+	cachedRepository, userRepository := newCachedUserRepository()
+	ctx := context.Background()
+	user := &models.User{FirstName: "Jane"}
+	_ = userRepository.CreateUser(ctx, user)
+	userRepository.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			actual, err := cachedRepository.GetUserById(ctx, user.Id)
+			assert.NoError(t, err)
+			assert.Equal(t, "Jane", actual.FirstName, "Should share looked up user")
+		}()
+	}
+	for atomic.LoadInt64(&cachedRepository.stats.Misses) < 10 {
+		time.Sleep(time.Millisecond)
+	}
+	// Gives the last callers time to join the lookup after counting a miss.
+	time.Sleep(10 * time.Millisecond)
+	close(userRepository.release)
+	wg.Wait()
+
+	assert.Equal(t, int64(1), userRepository.lookups, "Should collapse concurrent misses")
+}
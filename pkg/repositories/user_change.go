@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type Operation string
+
+const (
+	OperationCreate Operation = "create"
+	OperationUpdate Operation = "update"
+	OperationDelete Operation = "delete"
+)
+
+type UserChange struct {
+	Operation Operation
+	Before    *models.User
+	After     *models.User
+}
+
+func (change *UserChange) UserId() string {
+	if change.After != nil {
+		return change.After.Id
+	}
+	return change.Before.Id
+}
+
+func (change *UserChange) TenantId() string {
+	if change.After != nil {
+		return change.After.TenantId
+	}
+	return change.Before.TenantId
+}
+
+type UserChangeHook interface {
+	OnUserChange(tx *gorm.DB, change *UserChange) error
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type UserField string
+
+const (
+	UserFieldId        UserField = "id"
+	UserFieldFirstName UserField = "first_name"
+	UserFieldLastName  UserField = "last_name"
+	UserFieldEmail     UserField = "email"
+)
+
+type FilterOperator string
+
+const (
+	FilterEqual      FilterOperator = "eq"
+	FilterNotEqual   FilterOperator = "ne"
+	FilterContains   FilterOperator = "co"
+	FilterStartsWith FilterOperator = "sw"
+	FilterEndsWith   FilterOperator = "ew"
+	FilterPresent    FilterOperator = "pr"
+)
+
+// UserFilter either compares a field with a value or combines other filters
+// with And, Or or Not. Comparisons ignore case. A nil filter matches all
+// users.
+type UserFilter struct {
+	Field    UserField
+	Operator FilterOperator
+	Value    string
+
+	And []*UserFilter
+	Or  []*UserFilter
+	Not *UserFilter
+}
+
+func (filter *UserFilter) Matches(user *models.User) bool {
+	switch {
+	case filter == nil:
+		return true
+	case filter.Not != nil:
+		return !filter.Not.Matches(user)
+	case len(filter.And) > 0:
+		for _, operand := range filter.And {
+			if !operand.Matches(user) {
+				return false
+			}
+		}
+		return true
+	case len(filter.Or) > 0:
+		for _, operand := range filter.Or {
+			if operand.Matches(user) {
+				return true
+			}
+		}
+		return false
+	}
+
+	actual := strings.ToLower(userFieldValue(user, filter.Field))
+	value := strings.ToLower(filter.Value)
+	switch filter.Operator {
+	case FilterEqual:
+		return actual == value
+	case FilterNotEqual:
+		return actual != value
+	case FilterContains:
+		return strings.Contains(actual, value)
+	case FilterStartsWith:
+		return strings.HasPrefix(actual, value)
+	case FilterEndsWith:
+		return strings.HasSuffix(actual, value)
+	case FilterPresent:
+		return actual != ""
+	}
+	return false
+}
+
+// sql returns a condition for the filter. Field names are only taken from the
+// known columns and values are always bound as arguments.
+func (filter *UserFilter) sql() (string, []any, error) {
+	switch {
+	case filter.Not != nil:
+		condition, args, err := filter.Not.sql()
+		if err != nil {
+			return "", nil, err
+		}
+		return "NOT (" + condition + ")", args, nil
+	case len(filter.And) > 0:
+		return joinFilters(filter.And, " AND ")
+	case len(filter.Or) > 0:
+		return joinFilters(filter.Or, " OR ")
+	}
+
+	var column string
+	switch filter.Field {
+	case UserFieldId, UserFieldFirstName, UserFieldLastName, UserFieldEmail:
+		column = string(filter.Field)
+	default:
+		return "", nil, fmt.Errorf("unknown user field %q", filter.Field)
+	}
+
+	value := strings.ToLower(filter.Value)
+	switch filter.Operator {
+	case FilterEqual:
+		return "lower(" + column + ") = ?", []any{value}, nil
+	case FilterNotEqual:
+		return "lower(" + column + ") <> ?", []any{value}, nil
+	case FilterContains:
+		return "lower(" + column + ") LIKE ?", []any{"%" + escapeLike(value) + "%"}, nil
+	case FilterStartsWith:
+		return "lower(" + column + ") LIKE ?", []any{escapeLike(value) + "%"}, nil
+	case FilterEndsWith:
+		return "lower(" + column + ") LIKE ?", []any{"%" + escapeLike(value)}, nil
+	case FilterPresent:
+		return "coalesce(" + column + ", '') <> ''", nil, nil
+	}
+	return "", nil, fmt.Errorf("unknown filter operator %q", filter.Operator)
+}
+
+func joinFilters(filters []*UserFilter, separator string) (string, []any, error) {
+	conditions := make([]string, 0, len(filters))
+	var args []any
+	for _, operand := range filters {
+		condition, operandArgs, err := operand.sql()
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, "("+condition+")")
+		args = append(args, operandArgs...)
+	}
+	return strings.Join(conditions, separator), args, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+func userFieldValue(user *models.User, field UserField) string {
+	switch field {
+	case UserFieldId:
+		return user.Id
+	case UserFieldFirstName:
+		return user.FirstName
+	case UserFieldLastName:
+		return user.LastName
+	case UserFieldEmail:
+		return user.Email
+	}
+	return ""
+}
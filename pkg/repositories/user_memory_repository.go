@@ -0,0 +1,253 @@
+package repositories
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type UserMemoryRepository struct {
+	mu    sync.RWMutex
+	users map[string]models.User
+	// deletedAt holds the time of the latest deletion by tenant.
+	deletedAt map[string]time.Time
+}
+
+func NewMemoryUserRepository() *UserMemoryRepository {
+	return &UserMemoryRepository{
+		users:     map[string]models.User{},
+		deletedAt: map[string]time.Time{},
+	}
+}
+
+func (repo *UserMemoryRepository) CreateUser(ctx context.Context, user *models.User) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if tenantId, ok := tenancy.TenantFromContext(ctx); ok {
+		user.TenantId = tenantId
+	} else if user.TenantId == "" {
+		user.TenantId = tenancy.DefaultTenant
+	}
+	if repo.emailTaken(user) {
+		return ErrEmailTaken
+	}
+
+	if user.Id == "" {
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		user.Id = id
+	}
+	if _, ok := repo.users[user.Id]; ok {
+		return fmt.Errorf("user with id %q already exists", user.Id)
+	}
+
+	now := time.Now()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	repo.users[user.Id] = *user
+	return nil
+}
+
+func (repo *UserMemoryRepository) GetUserById(
+	ctx context.Context,
+	id string,
+) (*models.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	user, ok := repo.users[id]
+	if !ok || !inTenant(ctx, &user) {
+		return nil, ErrUserNotFound
+	}
+	return &user, nil
+}
+
+func (repo *UserMemoryRepository) GetUsersByIds(
+	ctx context.Context,
+	ids []string,
+) ([]*models.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	var users []*models.User
+	for _, id := range ids {
+		if user, ok := repo.users[id]; ok && inTenant(ctx, &user) {
+			users = append(users, &user)
+		}
+	}
+	return users, nil
+}
+
+func (repo *UserMemoryRepository) GetAllUsers(
+	ctx context.Context,
+	options ListOptions,
+) ([]*models.User, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	ids := make([]string, 0, len(repo.users))
+	for id, user := range repo.users {
+		if inTenant(ctx, &user) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	if options.Limit > 0 {
+		start := options.Offset
+		if start > len(ids) {
+			start = len(ids)
+		}
+		end := start + options.Limit
+		if end > len(ids) {
+			end = len(ids)
+		}
+		ids = ids[start:end]
+	}
+
+	users := make([]*models.User, len(ids))
+	for i, id := range ids {
+		user := repo.users[id]
+		users[i] = &user
+	}
+	return users, nil
+}
+
+func (repo *UserMemoryRepository) GetUsersVersion(
+	ctx context.Context,
+	filter *UserFilter,
+) (*UsersVersion, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	version := &UsersVersion{}
+	for _, user := range repo.users {
+		if !inTenant(ctx, &user) || !filter.Matches(&user) {
+			continue
+		}
+		version.Count++
+		if user.UpdatedAt.After(version.LastModified) {
+			version.LastModified = user.UpdatedAt
+		}
+	}
+	for tenantId, deletedAt := range repo.deletedAt {
+		user := models.User{TenantId: tenantId}
+		if inTenant(ctx, &user) && deletedAt.After(version.LastModified) {
+			version.LastModified = deletedAt
+		}
+	}
+	return version, nil
+}
+
+func (repo *UserMemoryRepository) SearchUsers(
+	ctx context.Context,
+	filter *UserFilter,
+	options ListOptions,
+) ([]*models.User, int64, error) {
+	repo.mu.RLock()
+	defer repo.mu.RUnlock()
+
+	ids := make([]string, 0, len(repo.users))
+	for id, user := range repo.users {
+		if inTenant(ctx, &user) && filter.Matches(&user) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	total := int64(len(ids))
+
+	start := options.Offset
+	if start > len(ids) {
+		start = len(ids)
+	}
+	end := start + options.Limit
+	if end > len(ids) {
+		end = len(ids)
+	}
+
+	users := make([]*models.User, 0, end-start)
+	for _, id := range ids[start:end] {
+		user := repo.users[id]
+		users = append(users, &user)
+	}
+	return users, total, nil
+}
+
+func (repo *UserMemoryRepository) UpdateUserById(
+	ctx context.Context,
+	id string,
+	updates *models.User,
+) (*models.User, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	user, ok := repo.users[id]
+	if !ok || !inTenant(ctx, &user) {
+		return nil, ErrUserNotFound
+	}
+	if updates.FirstName != "" {
+		user.FirstName = updates.FirstName
+	}
+	if updates.LastName != "" {
+		user.LastName = updates.LastName
+	}
+	if updates.Email != "" {
+		user.Email = updates.Email
+		if repo.emailTaken(&user) {
+			return nil, ErrEmailTaken
+		}
+	}
+	user.UpdatedAt = time.Now()
+	repo.users[id] = user
+	return &user, nil
+}
+
+func (repo *UserMemoryRepository) DeleteUserById(ctx context.Context, id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	user, ok := repo.users[id]
+	if !ok || !inTenant(ctx, &user) {
+		return ErrUserNotFound
+	}
+	delete(repo.users, id)
+	repo.deletedAt[user.TenantId] = time.Now()
+	return nil
+}
+
+func (repo *UserMemoryRepository) emailTaken(user *models.User) bool {
+	for id, other := range repo.users {
+		if id != user.Id &&
+			other.TenantId == user.TenantId &&
+			strings.EqualFold(other.Email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+// inTenant reports whether user belongs to the tenant in ctx, if there is one.
+func inTenant(ctx context.Context, user *models.User) bool {
+	tenantId, ok := tenancy.TenantFromContext(ctx)
+	return !ok || user.TenantId == tenantId
+}
+
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("error generating id: %v", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -1,17 +1,42 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"github.com/johannaojeling/go-rest-api/pkg/models"
 )
 
-var ErrUserNotFound = errors.New("user not found")
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email is already taken in tenant")
+)
+
+type ListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// UsersVersion summarizes a collection of users, so that it changes whenever
+// users are added to, changed in or removed from the collection. LastModified
+// also covers the latest removal of a user from the tenant.
+type UsersVersion struct {
+	Count        int64
+	LastModified time.Time
+}
 
 type UserRepository interface {
-	CreateUser(user *models.User) error
-	GetAllUsers() ([]*models.User, error)
-	GetUserById(id string) (*models.User, error)
-	UpdateUserById(id string, updates *models.User) (*models.User, error)
-	DeleteUserById(id string) error
+	CreateUser(ctx context.Context, user *models.User) error
+	GetAllUsers(ctx context.Context, options ListOptions) ([]*models.User, error)
+	SearchUsers(
+		ctx context.Context,
+		filter *UserFilter,
+		options ListOptions,
+	) ([]*models.User, int64, error)
+	GetUsersVersion(ctx context.Context, filter *UserFilter) (*UsersVersion, error)
+	GetUserById(ctx context.Context, id string) (*models.User, error)
+	GetUsersByIds(ctx context.Context, ids []string) ([]*models.User, error)
+	UpdateUserById(ctx context.Context, id string, updates *models.User) (*models.User, error)
+	DeleteUserById(ctx context.Context, id string) error
 }
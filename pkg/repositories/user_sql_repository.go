@@ -1,28 +1,55 @@
 package repositories
 
 import (
+	"context"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 
 	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
 )
 
+const userEmailIndex = "idx_users_tenant_email"
+
 type UserSQLRepository struct {
-	gormDB *gorm.DB
+	gormDB           *gorm.DB
+	hooks            []UserChangeHook
+	rowLevelSecurity bool
 }
 
-func NewSQLUserRepository(DB *gorm.DB) *UserSQLRepository {
-	return &UserSQLRepository{gormDB: DB}
+func NewSQLUserRepository(DB *gorm.DB, hooks ...UserChangeHook) *UserSQLRepository {
+	return &UserSQLRepository{gormDB: DB, hooks: hooks}
 }
 
-func (repo *UserSQLRepository) CreateUser(user *models.User) error {
-	return repo.gormDB.Create(user).Error
+// WithRowLevelSecurity runs every call in a transaction that sets the tenant
+// of its context for the policies installed by
+// tenancy.MigrateRowLevelSecurity.
+func (repo *UserSQLRepository) WithRowLevelSecurity() *UserSQLRepository {
+	repo.rowLevelSecurity = true
+	return repo
 }
 
-func (repo *UserSQLRepository) GetUserById(id string) (*models.User, error) {
+func (repo *UserSQLRepository) CreateUser(ctx context.Context, user *models.User) error {
+	err := repo.transaction(ctx, func(tx *gorm.DB) error {
+		err := tx.Create(user).Error
+		if err != nil {
+			return err
+		}
+		return repo.notify(tx, &UserChange{Operation: OperationCreate, After: user})
+	})
+	if violates(err, uniqueViolation, userEmailIndex) {
+		return ErrEmailTaken
+	}
+	return err
+}
+
+func (repo *UserSQLRepository) GetUserById(ctx context.Context, id string) (*models.User, error) {
 	user := &models.User{}
-	err := repo.gormDB.Where("id = ?", id).First(user).Error
+	err := repo.query(ctx, func(db *gorm.DB) error {
+		return db.Where("id = ?", id).First(user).Error
+	})
 	if errors.Is(err, gorm.ErrRecordNotFound) {
 		return nil, ErrUserNotFound
 	}
@@ -32,36 +59,183 @@ func (repo *UserSQLRepository) GetUserById(id string) (*models.User, error) {
 	return user, nil
 }
 
-func (repo *UserSQLRepository) GetAllUsers() ([]*models.User, error) {
+func (repo *UserSQLRepository) GetUsersByIds(
+	ctx context.Context,
+	ids []string,
+) ([]*models.User, error) {
 	var users []*models.User
-	err := repo.gormDB.Find(&users).Error
+	err := repo.query(ctx, func(db *gorm.DB) error {
+		return db.Where("id IN ?", ids).Find(&users).Error
+	})
 	return users, err
 }
 
+func (repo *UserSQLRepository) GetAllUsers(
+	ctx context.Context,
+	options ListOptions,
+) ([]*models.User, error) {
+	var users []*models.User
+	err := repo.query(ctx, func(db *gorm.DB) error {
+		if options.Limit > 0 {
+			db = db.Order("id").Limit(options.Limit).Offset(options.Offset)
+		}
+		return db.Find(&users).Error
+	})
+	return users, err
+}
+
+func (repo *UserSQLRepository) GetUsersVersion(
+	ctx context.Context,
+	filter *UserFilter,
+) (*UsersVersion, error) {
+	var row struct {
+		Count        int64
+		LastModified *time.Time
+	}
+	err := repo.query(ctx, func(db *gorm.DB) error {
+		// Deleted users leave no row behind, so their removal is taken from
+		// the user.deleted events of the outbox.
+		deletions := db.Session(&gorm.Session{NewDB: true}).
+			Model(&models.OutboxMessage{}).
+			Select("max(created_at)").
+			Where("aggregate_type = ? AND event_type = ?", "user", "user.deleted")
+		query := db.Model(&models.User{}).
+			Select(
+				"count(*) AS count, greatest(max(updated_at), (?)) AS last_modified",
+				deletions,
+			)
+		if filter != nil {
+			condition, args, err := filter.sql()
+			if err != nil {
+				return err
+			}
+			query = query.Where(condition, args...)
+		}
+		return query.Scan(&row).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	version := &UsersVersion{Count: row.Count}
+	if row.LastModified != nil {
+		version.LastModified = *row.LastModified
+	}
+	return version, nil
+}
+
+// SearchUsers returns a page of the users matching the filter, ordered by id,
+// and the number of all matching users.
+func (repo *UserSQLRepository) SearchUsers(
+	ctx context.Context,
+	filter *UserFilter,
+	options ListOptions,
+) ([]*models.User, int64, error) {
+	users := []*models.User{}
+	var total int64
+	err := repo.query(ctx, func(db *gorm.DB) error {
+		query := db.Model(&models.User{})
+		if filter != nil {
+			condition, args, err := filter.sql()
+			if err != nil {
+				return err
+			}
+			query = query.Where(condition, args...)
+		}
+		query = query.Session(&gorm.Session{})
+
+		err := query.Count(&total).Error
+		if err != nil {
+			return err
+		}
+		if options.Limit == 0 || int64(options.Offset) >= total {
+			return nil
+		}
+		return query.Order("id").Limit(options.Limit).Offset(options.Offset).Find(&users).Error
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
 func (repo *UserSQLRepository) UpdateUserById(
+	ctx context.Context,
 	id string,
 	updates *models.User,
 ) (*models.User, error) {
 	user := &models.User{}
-	err := repo.gormDB.Where("id = ?", id).First(user).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return nil, ErrUserNotFound
+	err := repo.transaction(ctx, func(tx *gorm.DB) error {
+		err := tx.Where("id = ?", id).First(user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		before := *user
+		err = tx.Model(user).Updates(updates).Error
+		if err != nil {
+			return err
+		}
+		return repo.notify(
+			tx,
+			&UserChange{Operation: OperationUpdate, Before: &before, After: user},
+		)
+	})
+	if violates(err, uniqueViolation, userEmailIndex) {
+		return nil, ErrEmailTaken
 	}
 	if err != nil {
 		return nil, err
 	}
-	err = repo.gormDB.Model(user).Updates(updates).Error
-	return user, err
+	return user, nil
 }
 
-func (repo *UserSQLRepository) DeleteUserById(id string) error {
-	user := &models.User{}
-	err := repo.gormDB.Where("id = ?", id).First(user).Error
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		return ErrUserNotFound
+func (repo *UserSQLRepository) DeleteUserById(ctx context.Context, id string) error {
+	return repo.transaction(ctx, func(tx *gorm.DB) error {
+		user := &models.User{}
+		err := tx.Where("id = ?", id).First(user).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		err = tx.Delete(&user).Error
+		if err != nil {
+			return err
+		}
+		return repo.notify(tx, &UserChange{Operation: OperationDelete, Before: user})
+	})
+}
+
+func (repo *UserSQLRepository) transaction(
+	ctx context.Context,
+	fn func(tx *gorm.DB) error,
+) error {
+	if repo.rowLevelSecurity {
+		return tenancy.Transaction(ctx, repo.gormDB, fn)
 	}
-	if err != nil {
-		return err
+	return repo.gormDB.WithContext(ctx).Transaction(fn)
+}
+
+// query runs reads in a transaction only if row-level security requires one.
+func (repo *UserSQLRepository) query(ctx context.Context, fn func(db *gorm.DB) error) error {
+	if !repo.rowLevelSecurity {
+		return fn(repo.gormDB.WithContext(ctx))
+	}
+	return repo.transaction(ctx, fn)
+}
+
+func (repo *UserSQLRepository) notify(tx *gorm.DB, change *UserChange) error {
+	for _, hook := range repo.hooks {
+		err := hook.OnUserChange(tx, change)
+		if err != nil {
+			return err
+		}
 	}
-	return repo.gormDB.Delete(&user).Error
+	return nil
 }
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+var ErrVerificationTokenInvalid = errors.New("verification token is invalid or expired")
+
+type VerificationRepository interface {
+	CreateVerificationToken(ctx context.Context, token *models.VerificationToken) error
+	ConsumeVerificationToken(
+		ctx context.Context,
+		tokenHash string,
+		now time.Time,
+	) (*models.VerificationToken, error)
+}
+
+type VerificationSQLRepository struct {
+	gormDB           *gorm.DB
+	rowLevelSecurity bool
+}
+
+func NewSQLVerificationRepository(DB *gorm.DB) *VerificationSQLRepository {
+	return &VerificationSQLRepository{gormDB: DB}
+}
+
+// WithRowLevelSecurity sets the tenant of the context in the transactions that
+// update users, for the policies installed by tenancy.MigrateRowLevelSecurity.
+func (repo *VerificationSQLRepository) WithRowLevelSecurity() *VerificationSQLRepository {
+	repo.rowLevelSecurity = true
+	return repo
+}
+
+func (repo *VerificationSQLRepository) CreateVerificationToken(
+	ctx context.Context,
+	token *models.VerificationToken,
+) error {
+	return repo.gormDB.WithContext(ctx).Create(token).Error
+}
+
+// ConsumeVerificationToken finds the token in any tenant, since links do not
+// carry the tenant, and verifies the user in the tenant of the token.
+func (repo *VerificationSQLRepository) ConsumeVerificationToken(
+	ctx context.Context,
+	tokenHash string,
+	now time.Time,
+) (*models.VerificationToken, error) {
+	token := &models.VerificationToken{}
+	err := repo.gormDB.WithContext(tenancy.WithoutTenant(ctx)).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokenHash, now).
+			First(token).
+			Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrVerificationTokenInvalid
+		}
+		if err != nil {
+			return err
+		}
+
+		tx = tx.WithContext(tenancy.WithTenant(ctx, token.TenantId))
+		if repo.rowLevelSecurity {
+			err = tenancy.SetLocal(tx)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = tx.Model(token).Update("used_at", now).Error
+		if err != nil {
+			return err
+		}
+
+		// The token only verifies the address it was sent to.
+		result := tx.Model(&models.User{}).
+			Where("id = ? AND email = ?", token.UserId, token.Email).
+			Update("email_verified", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVerificationTokenInvalid
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
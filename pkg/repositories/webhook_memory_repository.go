@@ -0,0 +1,267 @@
+package repositories
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type WebhookMemoryRepository struct {
+	mu            sync.Mutex
+	webhooks      map[string]models.Webhook
+	deliveries    map[int64]models.WebhookDelivery
+	attempts      map[int64][]models.WebhookAttempt
+	lastDelivery  int64
+	lastAttemptId int64
+}
+
+func NewMemoryWebhookRepository() *WebhookMemoryRepository {
+	return &WebhookMemoryRepository{
+		webhooks:   map[string]models.Webhook{},
+		deliveries: map[int64]models.WebhookDelivery{},
+		attempts:   map[int64][]models.WebhookAttempt{},
+	}
+}
+
+func (repo *WebhookMemoryRepository) CreateWebhook(webhook *models.Webhook) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if webhook.Id == "" {
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		webhook.Id = id
+	}
+	if webhook.TenantId == "" {
+		webhook.TenantId = tenancy.DefaultTenant
+	}
+
+	now := time.Now()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	repo.webhooks[webhook.Id] = *webhook
+	return nil
+}
+
+func (repo *WebhookMemoryRepository) GetAllWebhooks() ([]*models.Webhook, error) {
+	return repo.findWebhooks(func(*models.Webhook) bool { return true }), nil
+}
+
+func (repo *WebhookMemoryRepository) GetWebhookById(id string) (*models.Webhook, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	webhook, ok := repo.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	return &webhook, nil
+}
+
+func (repo *WebhookMemoryRepository) UpdateWebhookById(
+	id string,
+	updates *models.Webhook,
+) (*models.Webhook, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	webhook, ok := repo.webhooks[id]
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+	webhook.URL = updates.URL
+	webhook.Events = updates.Events
+	webhook.Secret = updates.Secret
+	webhook.Active = updates.Active
+	webhook.UpdatedAt = time.Now()
+	repo.webhooks[id] = webhook
+	return &webhook, nil
+}
+
+func (repo *WebhookMemoryRepository) DeleteWebhookById(id string) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	if _, ok := repo.webhooks[id]; !ok {
+		return ErrWebhookNotFound
+	}
+	delete(repo.webhooks, id)
+	for deliveryId, delivery := range repo.deliveries {
+		if delivery.WebhookId == id {
+			delete(repo.deliveries, deliveryId)
+			delete(repo.attempts, deliveryId)
+		}
+	}
+	return nil
+}
+
+func (repo *WebhookMemoryRepository) GetActiveWebhooks() ([]*models.Webhook, error) {
+	return repo.findWebhooks(func(webhook *models.Webhook) bool { return webhook.Active }), nil
+}
+
+func (repo *WebhookMemoryRepository) CreateDeliveries(deliveries []*models.WebhookDelivery) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		if repo.hasDelivery(delivery.WebhookId, delivery.EventId) {
+			continue
+		}
+		repo.lastDelivery++
+		now := time.Now()
+		delivery.Id = repo.lastDelivery
+		delivery.CreatedAt = now
+		delivery.UpdatedAt = now
+		repo.deliveries[delivery.Id] = *delivery
+	}
+	return nil
+}
+
+func (repo *WebhookMemoryRepository) GetDeliveriesByWebhookId(
+	webhookId string,
+) ([]*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	deliveries := repo.findDeliveries(func(delivery *models.WebhookDelivery) bool {
+		return delivery.WebhookId == webhookId
+	})
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].Id > deliveries[j].Id
+	})
+	return deliveries, nil
+}
+
+func (repo *WebhookMemoryRepository) GetDeliveryById(
+	webhookId string,
+	id int64,
+) (*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delivery, ok := repo.deliveries[id]
+	if !ok || delivery.WebhookId != webhookId {
+		return nil, ErrDeliveryNotFound
+	}
+	for _, attempt := range repo.attempts[id] {
+		attempt := attempt
+		delivery.AttemptLog = append(delivery.AttemptLog, &attempt)
+	}
+	return &delivery, nil
+}
+
+func (repo *WebhookMemoryRepository) ClaimDueDeliveries(
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	deliveries := repo.findDeliveries(func(delivery *models.WebhookDelivery) bool {
+		return delivery.Status == models.DeliveryPending && !delivery.NextAttemptAt.After(now)
+	})
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	for _, delivery := range deliveries {
+		stored := repo.deliveries[delivery.Id]
+		stored.NextAttemptAt = now.Add(lease)
+		repo.deliveries[delivery.Id] = stored
+	}
+	return deliveries, nil
+}
+
+func (repo *WebhookMemoryRepository) RecordAttempt(
+	delivery *models.WebhookDelivery,
+	attempt *models.WebhookAttempt,
+) error {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	stored, ok := repo.deliveries[delivery.Id]
+	if !ok {
+		return ErrDeliveryNotFound
+	}
+	repo.lastAttemptId++
+	attempt.Id = repo.lastAttemptId
+	attempt.DeliveryId = delivery.Id
+	repo.attempts[delivery.Id] = append(repo.attempts[delivery.Id], *attempt)
+
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.NextAttemptAt = delivery.NextAttemptAt
+	stored.LastStatusCode = delivery.LastStatusCode
+	stored.LastError = delivery.LastError
+	stored.UpdatedAt = time.Now()
+	repo.deliveries[delivery.Id] = stored
+	return nil
+}
+
+func (repo *WebhookMemoryRepository) ReplayDelivery(
+	webhookId string,
+	id int64,
+) (*models.WebhookDelivery, error) {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	delivery, ok := repo.deliveries[id]
+	if !ok || delivery.WebhookId != webhookId {
+		return nil, ErrDeliveryNotFound
+	}
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	repo.deliveries[id] = delivery
+	return &delivery, nil
+}
+
+func (repo *WebhookMemoryRepository) findWebhooks(
+	match func(webhook *models.Webhook) bool,
+) []*models.Webhook {
+	repo.mu.Lock()
+	defer repo.mu.Unlock()
+
+	var webhooks []*models.Webhook
+	for _, webhook := range repo.webhooks {
+		webhook := webhook
+		if match(&webhook) {
+			webhooks = append(webhooks, &webhook)
+		}
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+	})
+	return webhooks
+}
+
+func (repo *WebhookMemoryRepository) findDeliveries(
+	match func(delivery *models.WebhookDelivery) bool,
+) []*models.WebhookDelivery {
+	var deliveries []*models.WebhookDelivery
+	for _, delivery := range repo.deliveries {
+		delivery := delivery
+		if match(&delivery) {
+			deliveries = append(deliveries, &delivery)
+		}
+	}
+	return deliveries
+}
+
+func (repo *WebhookMemoryRepository) hasDelivery(webhookId string, eventId int64) bool {
+	for _, delivery := range repo.deliveries {
+		if delivery.WebhookId == webhookId && delivery.EventId == eventId {
+			return true
+		}
+	}
+	return false
+}
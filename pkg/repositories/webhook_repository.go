@@ -0,0 +1,33 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var (
+	ErrWebhookNotFound  = errors.New("webhook not found")
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+)
+
+type WebhookRepository interface {
+	CreateWebhook(webhook *models.Webhook) error
+	GetAllWebhooks() ([]*models.Webhook, error)
+	GetWebhookById(id string) (*models.Webhook, error)
+	UpdateWebhookById(id string, updates *models.Webhook) (*models.Webhook, error)
+	DeleteWebhookById(id string) error
+	GetActiveWebhooks() ([]*models.Webhook, error)
+
+	CreateDeliveries(deliveries []*models.WebhookDelivery) error
+	GetDeliveriesByWebhookId(webhookId string) ([]*models.WebhookDelivery, error)
+	GetDeliveryById(webhookId string, id int64) (*models.WebhookDelivery, error)
+	ClaimDueDeliveries(
+		now time.Time,
+		lease time.Duration,
+		limit int,
+	) ([]*models.WebhookDelivery, error)
+	RecordAttempt(delivery *models.WebhookDelivery, attempt *models.WebhookAttempt) error
+	ReplayDelivery(webhookId string, id int64) (*models.WebhookDelivery, error)
+}
@@ -0,0 +1,191 @@
+package repositories
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+type WebhookSQLRepository struct {
+	gormDB *gorm.DB
+}
+
+func NewSQLWebhookRepository(DB *gorm.DB) *WebhookSQLRepository {
+	return &WebhookSQLRepository{gormDB: DB}
+}
+
+func (repo *WebhookSQLRepository) CreateWebhook(webhook *models.Webhook) error {
+	return repo.gormDB.Create(webhook).Error
+}
+
+func (repo *WebhookSQLRepository) GetAllWebhooks() ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	err := repo.gormDB.Order("created_at").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (repo *WebhookSQLRepository) GetWebhookById(id string) (*models.Webhook, error) {
+	webhook := &models.Webhook{}
+	err := repo.gormDB.Where("id = ?", id).First(webhook).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (repo *WebhookSQLRepository) UpdateWebhookById(
+	id string,
+	updates *models.Webhook,
+) (*models.Webhook, error) {
+	webhook, err := repo.GetWebhookById(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.gormDB.Model(webhook).
+		Select("URL", "Events", "Secret", "Active").
+		Updates(updates).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (repo *WebhookSQLRepository) DeleteWebhookById(id string) error {
+	return repo.gormDB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ?", id).Delete(&models.Webhook{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+
+		deliveries := tx.Model(&models.WebhookDelivery{}).Select("id").Where("webhook_id = ?", id)
+		err := tx.Where("delivery_id IN (?)", deliveries).Delete(&models.WebhookAttempt{}).Error
+		if err != nil {
+			return err
+		}
+		return tx.Where("webhook_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+}
+
+func (repo *WebhookSQLRepository) GetActiveWebhooks() ([]*models.Webhook, error) {
+	var webhooks []*models.Webhook
+	err := repo.gormDB.Where("active").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (repo *WebhookSQLRepository) CreateDeliveries(deliveries []*models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return repo.gormDB.Clauses(clause.OnConflict{DoNothing: true}).Create(deliveries).Error
+}
+
+func (repo *WebhookSQLRepository) GetDeliveriesByWebhookId(
+	webhookId string,
+) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := repo.gormDB.Where("webhook_id = ?", webhookId).
+		Order("id DESC").
+		Find(&deliveries).
+		Error
+	return deliveries, err
+}
+
+func (repo *WebhookSQLRepository) GetDeliveryById(
+	webhookId string,
+	id int64,
+) (*models.WebhookDelivery, error) {
+	delivery := &models.WebhookDelivery{}
+	err := repo.gormDB.Preload("AttemptLog", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id")
+	}).
+		Where("webhook_id = ? AND id = ?", webhookId, id).
+		First(delivery).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrDeliveryNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
+
+func (repo *WebhookSQLRepository) ClaimDueDeliveries(
+	now time.Time,
+	lease time.Duration,
+	limit int,
+) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := repo.gormDB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&deliveries).
+			Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]int64, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.Id
+		}
+		return tx.Model(&models.WebhookDelivery{}).
+			Where("id IN ?", ids).
+			Update("next_attempt_at", now.Add(lease)).
+			Error
+	})
+	return deliveries, err
+}
+
+func (repo *WebhookSQLRepository) RecordAttempt(
+	delivery *models.WebhookDelivery,
+	attempt *models.WebhookAttempt,
+) error {
+	return repo.gormDB.Transaction(func(tx *gorm.DB) error {
+		attempt.DeliveryId = delivery.Id
+		err := tx.Create(attempt).Error
+		if err != nil {
+			return err
+		}
+		return tx.Model(delivery).
+			Select("Status", "Attempts", "NextAttemptAt", "LastStatusCode", "LastError").
+			Updates(delivery).
+			Error
+	})
+}
+
+func (repo *WebhookSQLRepository) ReplayDelivery(
+	webhookId string,
+	id int64,
+) (*models.WebhookDelivery, error) {
+	delivery, err := repo.GetDeliveryById(webhookId, id)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery.Status = models.DeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	err = repo.gormDB.Model(delivery).
+		Select("Status", "Attempts", "NextAttemptAt").
+		Updates(delivery).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	return delivery, nil
+}
@@ -0,0 +1,31 @@
+package schemas
+
+import (
+	"time"
+)
+
+type AuditPageQuery struct {
+	Limit  int `form:"limit"  binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+type AuditQuery struct {
+	AuditPageQuery
+	UserId string    `form:"user_id"`
+	Actor  string    `form:"actor"`
+	Action string    `form:"action"  binding:"omitempty,oneof=create update delete"`
+	Since  time.Time `form:"since"`
+	Until  time.Time `form:"until"`
+}
+
+type AuditEntryResponse struct {
+	Id        int64          `json:"id"`
+	Action    string         `json:"action"`
+	UserId    string         `json:"user_id"`
+	Actor     string         `json:"actor"`
+	RequestId string         `json:"request_id"`
+	IP        string         `json:"ip"`
+	Before    map[string]any `json:"before"`
+	After     map[string]any `json:"after"`
+	CreatedAt time.Time      `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package schemas
+
+import (
+	"time"
+)
+
+type GroupURI struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+}
+
+type GroupMemberURI struct {
+	Id     string `json:"id"      uri:"id"     binding:"required"`
+	UserId string `json:"user_id" uri:"userId" binding:"required"`
+}
+
+type SubgroupURI struct {
+	Id      string `json:"id"       uri:"id"      binding:"required"`
+	GroupId string `json:"group_id" uri:"groupId" binding:"required"`
+}
+
+type GroupListQuery struct {
+	Limit  int `form:"limit"  binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+type GroupRequest struct {
+	Name        string `json:"name"        binding:"required,max=255"`
+	Description string `json:"description" binding:"max=1024"`
+}
+
+type GroupResponse struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type UserGroupResponse struct {
+	GroupResponse
+	Direct bool `json:"direct"`
+}
@@ -0,0 +1,25 @@
+package schemas
+
+import (
+	"time"
+)
+
+type OutboxMessageURI struct {
+	Id int64 `json:"id" uri:"id" binding:"required,min=1"`
+}
+
+type OutboxPageQuery struct {
+	Limit  int `form:"limit"  binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+type OutboxMessageResponse struct {
+	Id            int64      `json:"id"`
+	AggregateType string     `json:"aggregate_type"`
+	AggregateId   string     `json:"aggregate_id"`
+	EventType     string     `json:"event_type"`
+	Attempts      int        `json:"attempts"`
+	LastError     string     `json:"last_error"`
+	CreatedAt     time.Time  `json:"created_at"`
+	DeadAt        *time.Time `json:"dead_at"`
+}
@@ -0,0 +1,20 @@
+package schemas
+
+import (
+	"time"
+)
+
+type SessionURI struct {
+	Id        string `json:"id"  uri:"id"  binding:"required"`
+	SessionId string `json:"sid" uri:"sid" binding:"required"`
+}
+
+type SessionResponse struct {
+	Id         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
@@ -0,0 +1,25 @@
+package schemas
+
+import (
+	"time"
+)
+
+type TenantURI struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+}
+
+type TenantRequest struct {
+	Id   string `json:"id"   binding:"required,max=63"`
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type TenantUpdateRequest struct {
+	Name string `json:"name" binding:"required,max=255"`
+}
+
+type TenantResponse struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
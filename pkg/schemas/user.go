@@ -1,9 +1,21 @@
 package schemas
 
+import (
+	"time"
+)
+
 type UserURI struct {
 	Id string `json:"id" uri:"id" binding:"required"`
 }
 
+type UserListQuery struct {
+	Limit     int    `form:"limit"      binding:"omitempty,min=1,max=100"`
+	Offset    int    `form:"offset"     binding:"omitempty,min=0"`
+	FirstName string `form:"first_name"`
+	LastName  string `form:"last_name"`
+	Email     string `form:"email"`
+}
+
 type UserRequest struct {
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name"  binding:"required"`
@@ -11,8 +23,35 @@ type UserRequest struct {
 }
 
 type UserResponse struct {
-	Id        string `json:"id"`
-	FirstName string `json:"first_name"`
-	LastName  string `json:"last_name"`
-	Email     string `json:"email"`
+	Id            string `json:"id"`
+	FirstName     string `json:"first_name"`
+	LastName      string `json:"last_name"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+type UserEventsQuery struct {
+	UserId string `form:"user_id"`
+}
+
+type UserEventsHeader struct {
+	LastEventId int64 `header:"Last-Event-ID" binding:"omitempty,min=0"`
+}
+
+type UserQuery struct {
+	AsOf time.Time `form:"as_of"`
+}
+
+type UserHistoryQuery struct {
+	Limit  int `form:"limit"  binding:"omitempty,min=1,max=100"`
+	Offset int `form:"offset" binding:"omitempty,min=0"`
+}
+
+type UserFieldChangeResponse struct {
+	Id        int64     `json:"id"`
+	Field     string    `json:"field"`
+	OldValue  string    `json:"old_value"`
+	NewValue  string    `json:"new_value"`
+	Actor     string    `json:"actor"`
+	ChangedAt time.Time `json:"changed_at"`
 }
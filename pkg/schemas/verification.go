@@ -0,0 +1,11 @@
+package schemas
+
+type VerifyEmailQuery struct {
+	Token string `form:"token" binding:"required"`
+}
+
+type VerifyEmailResponse struct {
+	UserId        string `json:"user_id"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+}
@@ -0,0 +1,57 @@
+package schemas
+
+import (
+	"time"
+)
+
+type WebhookURI struct {
+	Id string `json:"id" uri:"id" binding:"required"`
+}
+
+type DeliveryURI struct {
+	Id         string `json:"id"          uri:"id"          binding:"required"`
+	DeliveryId int64  `json:"delivery_id" uri:"delivery_id" binding:"required,min=1"`
+}
+
+type WebhookRequest struct {
+	URL    string   `json:"url"    binding:"required,url"`
+	Events []string `json:"events" binding:"required,min=1,dive,oneof=* user.created user.updated user.deleted"`
+	Secret string   `json:"secret" binding:"required,min=16"`
+	Active *bool    `json:"active"`
+}
+
+type WebhookResponse struct {
+	Id        string    `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type DeliveryResponse struct {
+	Id             int64     `json:"id"`
+	WebhookId      string    `json:"webhook_id"`
+	EventId        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code"`
+	LastError      string    `json:"last_error"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type AttemptResponse struct {
+	Attempt     int       `json:"attempt"`
+	StatusCode  int       `json:"status_code"`
+	Error       string    `json:"error"`
+	DurationMs  int64     `json:"duration_ms"`
+	AttemptedAt time.Time `json:"attempted_at"`
+}
+
+type DeliveryDetailResponse struct {
+	DeliveryResponse
+	Payload    map[string]any    `json:"payload"`
+	AttemptLog []AttemptResponse `json:"attempt_log"`
+}
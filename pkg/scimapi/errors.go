@@ -0,0 +1,40 @@
+package scimapi
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	scimTypeInvalidFilter = "invalidFilter"
+	scimTypeUniqueness    = "uniqueness"
+	scimTypeInvalidSyntax = "invalidSyntax"
+	scimTypeInvalidPath   = "invalidPath"
+	scimTypeNoTarget      = "noTarget"
+	scimTypeInvalidValue  = "invalidValue"
+)
+
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+func newError(status int, scimType string, detail string, a ...any) *Error {
+	return &Error{
+		Schemas:  []string{ErrorSchema},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   fmt.Sprintf(detail, a...),
+	}
+}
+
+func (err *Error) Error() string {
+	return err.Detail
+}
+
+func (err *Error) StatusCode() int {
+	status, _ := strconv.Atoi(err.Status)
+	return status
+}
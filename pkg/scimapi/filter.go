@@ -0,0 +1,278 @@
+package scimapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+var errInvalidFilter = errors.New("invalid filter")
+
+var filterAttributes = map[string]repositories.UserField{
+	"id":              repositories.UserFieldId,
+	"username":        repositories.UserFieldEmail,
+	"emails":          repositories.UserFieldEmail,
+	"emails.value":    repositories.UserFieldEmail,
+	"name.givenname":  repositories.UserFieldFirstName,
+	"name.familyname": repositories.UserFieldLastName,
+}
+
+var filterOperators = map[string]repositories.FilterOperator{
+	"eq": repositories.FilterEqual,
+	"ne": repositories.FilterNotEqual,
+	"co": repositories.FilterContains,
+	"sw": repositories.FilterStartsWith,
+	"ew": repositories.FilterEndsWith,
+}
+
+type tokenKind int
+
+const (
+	tokenWord tokenKind = iota
+	tokenString
+	tokenOpen
+	tokenClose
+	tokenOpenBracket
+	tokenCloseBracket
+	tokenEnd
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+// ParseFilter parses a SCIM filter expression into a user filter. It supports
+// the comparison operators eq, ne, co, sw, ew and pr on string attributes,
+// combined with and, or, not, grouping and value paths like emails[value co
+// "x"].
+func ParseFilter(filter string) (*repositories.UserFilter, error) {
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser{tokens: tokens}
+	result, err := parser.parseOr("")
+	if err != nil {
+		return nil, err
+	}
+	if next := parser.peek(); next.kind != tokenEnd {
+		return nil, fmt.Errorf("%w: unexpected %q", errInvalidFilter, next.value)
+	}
+	return result, nil
+}
+
+type filterParser struct {
+	tokens   []token
+	position int
+}
+
+func (parser *filterParser) peek() token {
+	return parser.tokens[parser.position]
+}
+
+func (parser *filterParser) next() token {
+	next := parser.tokens[parser.position]
+	if next.kind != tokenEnd {
+		parser.position++
+	}
+	return next
+}
+
+func (parser *filterParser) expect(kind tokenKind, description string) error {
+	if next := parser.next(); next.kind != kind {
+		return fmt.Errorf("%w: expecting %s", errInvalidFilter, description)
+	}
+	return nil
+}
+
+func (parser *filterParser) peekKeyword(keyword string) bool {
+	next := parser.peek()
+	return next.kind == tokenWord && strings.EqualFold(next.value, keyword)
+}
+
+func (parser *filterParser) parseOr(scope string) (*repositories.UserFilter, error) {
+	left, err := parser.parseAnd(scope)
+	if err != nil {
+		return nil, err
+	}
+	operands := []*repositories.UserFilter{left}
+	for parser.peekKeyword("or") {
+		parser.next()
+		right, err := parser.parseAnd(scope)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+	if len(operands) == 1 {
+		return left, nil
+	}
+	return &repositories.UserFilter{Or: operands}, nil
+}
+
+func (parser *filterParser) parseAnd(scope string) (*repositories.UserFilter, error) {
+	left, err := parser.parseNot(scope)
+	if err != nil {
+		return nil, err
+	}
+	operands := []*repositories.UserFilter{left}
+	for parser.peekKeyword("and") {
+		parser.next()
+		right, err := parser.parseNot(scope)
+		if err != nil {
+			return nil, err
+		}
+		operands = append(operands, right)
+	}
+	if len(operands) == 1 {
+		return left, nil
+	}
+	return &repositories.UserFilter{And: operands}, nil
+}
+
+func (parser *filterParser) parseNot(scope string) (*repositories.UserFilter, error) {
+	if !parser.peekKeyword("not") {
+		return parser.parsePrimary(scope)
+	}
+	parser.next()
+	if err := parser.expect(tokenOpen, `"(" after not`); err != nil {
+		return nil, err
+	}
+	operand, err := parser.parseOr(scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.expect(tokenClose, `")"`); err != nil {
+		return nil, err
+	}
+	return &repositories.UserFilter{Not: operand}, nil
+}
+
+func (parser *filterParser) parsePrimary(scope string) (*repositories.UserFilter, error) {
+	next := parser.next()
+	switch next.kind {
+	case tokenOpen:
+		operand, err := parser.parseOr(scope)
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.expect(tokenClose, `")"`); err != nil {
+			return nil, err
+		}
+		return operand, nil
+	case tokenWord:
+	default:
+		return nil, fmt.Errorf("%w: expecting attribute", errInvalidFilter)
+	}
+
+	path := normalizePath(next.value)
+	if scope != "" {
+		path = scope + "." + path
+	}
+
+	if parser.peek().kind == tokenOpenBracket {
+		if scope != "" {
+			return nil, fmt.Errorf("%w: nested value paths are not supported", errInvalidFilter)
+		}
+		parser.next()
+		operand, err := parser.parseOr(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := parser.expect(tokenCloseBracket, `"]"`); err != nil {
+			return nil, err
+		}
+		return operand, nil
+	}
+
+	field, ok := filterAttributes[path]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported attribute %q", errInvalidFilter, next.value)
+	}
+
+	operatorToken := parser.next()
+	if operatorToken.kind != tokenWord {
+		return nil, fmt.Errorf("%w: expecting operator after %q", errInvalidFilter, next.value)
+	}
+	operatorName := strings.ToLower(operatorToken.value)
+	if operatorName == "pr" {
+		return &repositories.UserFilter{Field: field, Operator: repositories.FilterPresent}, nil
+	}
+	operator, ok := filterOperators[operatorName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported operator %q", errInvalidFilter, operatorToken.value)
+	}
+
+	value := parser.next()
+	if value.kind != tokenString {
+		return nil, fmt.Errorf("%w: expecting string value for %q", errInvalidFilter, next.value)
+	}
+	return &repositories.UserFilter{Field: field, Operator: operator, Value: value.value}, nil
+}
+
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(filter); {
+		char := filter[i]
+		switch {
+		case char == ' ' || char == '\t' || char == '\n' || char == '\r':
+			i++
+		case char == '(':
+			tokens = append(tokens, token{kind: tokenOpen, value: "("})
+			i++
+		case char == ')':
+			tokens = append(tokens, token{kind: tokenClose, value: ")"})
+			i++
+		case char == '[':
+			tokens = append(tokens, token{kind: tokenOpenBracket, value: "["})
+			i++
+		case char == ']':
+			tokens = append(tokens, token{kind: tokenCloseBracket, value: "]"})
+			i++
+		case char == '"':
+			end := i + 1
+			for end < len(filter) && filter[end] != '"' {
+				if filter[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(filter) {
+				return nil, fmt.Errorf("%w: unterminated string", errInvalidFilter)
+			}
+			var value string
+			err := json.Unmarshal([]byte(filter[i:end+1]), &value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string %s", errInvalidFilter, filter[i:end+1])
+			}
+			tokens = append(tokens, token{kind: tokenString, value: value})
+			i = end + 1
+		default:
+			end := i
+			for end < len(filter) && isWordChar(rune(filter[end])) {
+				end++
+			}
+			if end == i {
+				return nil, fmt.Errorf("%w: unexpected character %q", errInvalidFilter, char)
+			}
+			tokens = append(tokens, token{kind: tokenWord, value: filter[i:end]})
+			i = end
+		}
+	}
+	return append(tokens, token{kind: tokenEnd}), nil
+}
+
+func isWordChar(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char) || strings.ContainsRune(":._-$", char)
+}
+
+// normalizePath lowercases an attribute path and strips the core user schema.
+func normalizePath(path string) string {
+	path = strings.ToLower(path)
+	return strings.TrimPrefix(path, strings.ToLower(UserSchema)+":")
+}
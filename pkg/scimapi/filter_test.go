@@ -0,0 +1,91 @@
+package scimapi
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+func TestParseFilter(t *testing.T) {
+	testCases := []struct {
+		reason   string
+		filter   string
+		expected *repositories.UserFilter
+	}{
+		{
+			reason: "Should map userName to email",
+			filter: `userName eq "Jane.Doe@mail.com"`,
+			expected: &repositories.UserFilter{
+				Field:    repositories.UserFieldEmail,
+				Operator: repositories.FilterEqual,
+				Value:    "Jane.Doe@mail.com",
+			},
+		},
+		{
+			reason: "Should accept schema prefix and any case",
+			filter: `urn:ietf:params:scim:schemas:core:2.0:User:emails.VALUE CO "mail.com"`,
+			expected: &repositories.UserFilter{
+				Field:    repositories.UserFieldEmail,
+				Operator: repositories.FilterContains,
+				Value:    "mail.com",
+			},
+		},
+		{
+			reason: "Should bind and tighter than or",
+			filter: `name.givenName sw "J" or name.familyName pr and not (id eq "a\"b")`,
+			expected: &repositories.UserFilter{Or: []*repositories.UserFilter{
+				{
+					Field:    repositories.UserFieldFirstName,
+					Operator: repositories.FilterStartsWith,
+					Value:    "J",
+				},
+				{And: []*repositories.UserFilter{
+					{Field: repositories.UserFieldLastName, Operator: repositories.FilterPresent},
+					{Not: &repositories.UserFilter{
+						Field:    repositories.UserFieldId,
+						Operator: repositories.FilterEqual,
+						Value:    `a"b`,
+					}},
+				}},
+			}},
+		},
+		{
+			reason: "Should scope value paths",
+			filter: `emails[value ew "@mail.com"]`,
+			expected: &repositories.UserFilter{
+				Field:    repositories.UserFieldEmail,
+				Operator: repositories.FilterEndsWith,
+				Value:    "@mail.com",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.reason, func(t *testing.T) {
+			actual, err := ParseFilter(tc.filter)
+
+			assert.NoError(t, err, tc.reason)
+			assert.Equal(t, tc.expected, actual, tc.reason)
+		})
+	}
+}
+
+func TestParseFilter_Invalid(t *testing.T) {
+	filters := []string{
+		`userName eq`,
+		`userName gt "a"`,
+		`title eq "a"`,
+		`userName eq "a" and`,
+		`(userName eq "a"`,
+		`userName eq "a`,
+		`emails[type eq "work"]`,
+		`active eq true`,
+	}
+
+	for _, filter := range filters {
+		_, err := ParseFilter(filter)
+		assert.ErrorIs(t, err, errInvalidFilter, "Should reject %s", filter)
+	}
+}
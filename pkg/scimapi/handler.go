@@ -0,0 +1,392 @@
+package scimapi
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+const contentTypeSCIM = "application/scim+json"
+
+type Handler struct {
+	userRepository repositories.UserRepository
+	baseURL        string
+}
+
+// NewHandler creates a SCIM 2.0 handler. The baseURL is the public URL the
+// handler is registered at and is used for resource locations.
+func NewHandler(userRepository repositories.UserRepository, baseURL string) *Handler {
+	return &Handler{
+		userRepository: userRepository,
+		baseURL:        strings.TrimSuffix(baseURL, "/"),
+	}
+}
+
+func (handler *Handler) Register(routerGroup *gin.RouterGroup) {
+	routerGroup.Use(requireProvisioner)
+	routerGroup.GET("/Users", handler.ListUsers)
+	routerGroup.POST("/Users", handler.CreateUser)
+	routerGroup.GET("/Users/:id", handler.GetUser)
+	routerGroup.PUT("/Users/:id", handler.ReplaceUser)
+	routerGroup.PATCH("/Users/:id", handler.PatchUser)
+	routerGroup.DELETE("/Users/:id", handler.DeleteUser)
+	routerGroup.GET("/ServiceProviderConfig", handler.GetServiceProviderConfig)
+	routerGroup.GET("/ResourceTypes", handler.ListResourceTypes)
+	routerGroup.GET("/ResourceTypes/:id", handler.GetResourceType)
+	routerGroup.GET("/Schemas", handler.ListSchemas)
+	routerGroup.GET("/Schemas/:id", handler.GetSchema)
+}
+
+func (handler *Handler) ListUsers(ctx *gin.Context) {
+	startIndex, queryErr := queryInt(ctx, "startIndex", 1)
+	if queryErr != nil {
+		abort(ctx, queryErr)
+		return
+	}
+	if startIndex < 1 {
+		startIndex = 1
+	}
+	count, queryErr := queryInt(ctx, "count", maxResults)
+	if queryErr != nil {
+		abort(ctx, queryErr)
+		return
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > maxResults {
+		count = maxResults
+	}
+
+	var filter *repositories.UserFilter
+	var err error
+	if expression := ctx.Query("filter"); expression != "" {
+		filter, err = ParseFilter(expression)
+		if err != nil {
+			abort(ctx, newError(http.StatusBadRequest, scimTypeInvalidFilter, err.Error()))
+			return
+		}
+	}
+
+	users, total, err := handler.userRepository.SearchUsers(
+		ctx.Request.Context(),
+		filter,
+		repositories.ListOptions{Limit: count, Offset: startIndex - 1},
+	)
+	if err != nil {
+		log.Printf("error searching users: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error searching users"))
+		return
+	}
+
+	resources := make([]any, 0, len(users))
+	for _, user := range users {
+		resources = append(resources, toUser(user, handler.baseURL, true))
+	}
+	respond(ctx, http.StatusOK, newListResponse(resources, total, startIndex))
+}
+
+func (handler *Handler) CreateUser(ctx *gin.Context) {
+	var request User
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		abort(ctx, newError(http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body"))
+		return
+	}
+	if request.Active != nil && !*request.Active {
+		abort(ctx, newError(
+			http.StatusBadRequest,
+			scimTypeInvalidValue,
+			"inactive users are not supported",
+		))
+		return
+	}
+
+	user := fromUser(&request)
+	if err := handler.validate(ctx, user, ""); err != nil {
+		abort(ctx, err)
+		return
+	}
+
+	err = handler.userRepository.CreateUser(ctx.Request.Context(), user)
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		abort(ctx, newError(
+			http.StatusConflict,
+			scimTypeUniqueness,
+			"userName %q is already taken",
+			user.Email,
+		))
+		return
+	}
+	if err != nil {
+		log.Printf("error creating user: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error creating user"))
+		return
+	}
+
+	resource := toUser(user, handler.baseURL, true)
+	ctx.Header("Location", resource.Meta.Location)
+	respond(ctx, http.StatusCreated, resource)
+}
+
+func (handler *Handler) GetUser(ctx *gin.Context) {
+	user, ok := handler.getUser(ctx)
+	if !ok {
+		return
+	}
+	respond(ctx, http.StatusOK, toUser(user, handler.baseURL, true))
+}
+
+func (handler *Handler) ReplaceUser(ctx *gin.Context) {
+	var request User
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil {
+		abort(ctx, newError(http.StatusBadRequest, scimTypeInvalidSyntax, "invalid request body"))
+		return
+	}
+
+	existing, ok := handler.getUser(ctx)
+	if !ok {
+		return
+	}
+	if request.Active != nil && !*request.Active {
+		handler.deactivate(ctx, existing)
+		return
+	}
+	handler.update(ctx, existing, fromUser(&request))
+}
+
+func (handler *Handler) PatchUser(ctx *gin.Context) {
+	var request PatchRequest
+	err := ctx.ShouldBindJSON(&request)
+	if err != nil || len(request.Operations) == 0 {
+		abort(ctx, newError(
+			http.StatusBadRequest,
+			scimTypeInvalidSyntax,
+			"expecting a PatchOp with Operations",
+		))
+		return
+	}
+
+	existing, ok := handler.getUser(ctx)
+	if !ok {
+		return
+	}
+	state := &userState{user: *existing, active: true}
+	if err := state.apply(request.Operations); err != nil {
+		abort(ctx, err)
+		return
+	}
+	if !state.active {
+		handler.deactivate(ctx, existing)
+		return
+	}
+	handler.update(ctx, existing, &state.user)
+}
+
+func (handler *Handler) DeleteUser(ctx *gin.Context) {
+	err := handler.userRepository.DeleteUserById(ctx.Request.Context(), ctx.Param("id"))
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		abort(ctx, newError(http.StatusNotFound, "", "user %q not found", ctx.Param("id")))
+		return
+	}
+	if err != nil {
+		log.Printf("error deleting user: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error deleting user"))
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (handler *Handler) GetServiceProviderConfig(ctx *gin.Context) {
+	respond(ctx, http.StatusOK, newServiceProviderConfig(handler.baseURL))
+}
+
+func (handler *Handler) ListResourceTypes(ctx *gin.Context) {
+	resources := []any{newUserResourceType(handler.baseURL)}
+	respond(ctx, http.StatusOK, newListResponse(resources, 1, 1))
+}
+
+func (handler *Handler) GetResourceType(ctx *gin.Context) {
+	if ctx.Param("id") != userResourceType {
+		abort(ctx, newError(http.StatusNotFound, "", "resource type %q not found", ctx.Param("id")))
+		return
+	}
+	respond(ctx, http.StatusOK, newUserResourceType(handler.baseURL))
+}
+
+func (handler *Handler) ListSchemas(ctx *gin.Context) {
+	resources := []any{newUserSchema(handler.baseURL)}
+	respond(ctx, http.StatusOK, newListResponse(resources, 1, 1))
+}
+
+func (handler *Handler) GetSchema(ctx *gin.Context) {
+	if ctx.Param("id") != UserSchema {
+		abort(ctx, newError(http.StatusNotFound, "", "schema %q not found", ctx.Param("id")))
+		return
+	}
+	respond(ctx, http.StatusOK, newUserSchema(handler.baseURL))
+}
+
+func (handler *Handler) getUser(ctx *gin.Context) (*models.User, bool) {
+	user, err := handler.userRepository.GetUserById(ctx.Request.Context(), ctx.Param("id"))
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		abort(ctx, newError(http.StatusNotFound, "", "user %q not found", ctx.Param("id")))
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("error getting user: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error retrieving user"))
+		return nil, false
+	}
+	return user, true
+}
+
+func (handler *Handler) update(ctx *gin.Context, existing *models.User, updates *models.User) {
+	if err := handler.validate(ctx, updates, existing.Id); err != nil {
+		abort(ctx, err)
+		return
+	}
+
+	user, err := handler.userRepository.UpdateUserById(
+		ctx.Request.Context(),
+		existing.Id,
+		&models.User{
+			FirstName: updates.FirstName,
+			LastName:  updates.LastName,
+			Email:     updates.Email,
+		},
+	)
+	if errors.Is(err, repositories.ErrUserNotFound) {
+		abort(ctx, newError(http.StatusNotFound, "", "user %q not found", existing.Id))
+		return
+	}
+	if errors.Is(err, repositories.ErrEmailTaken) {
+		abort(ctx, newError(
+			http.StatusConflict,
+			scimTypeUniqueness,
+			"userName %q is already taken",
+			updates.Email,
+		))
+		return
+	}
+	if err != nil {
+		log.Printf("error updating user: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error updating user"))
+		return
+	}
+	respond(ctx, http.StatusOK, toUser(user, handler.baseURL, true))
+}
+
+// deactivate deletes the user, since users have no active state.
+func (handler *Handler) deactivate(ctx *gin.Context, user *models.User) {
+	err := handler.userRepository.DeleteUserById(ctx.Request.Context(), user.Id)
+	if err != nil && !errors.Is(err, repositories.ErrUserNotFound) {
+		log.Printf("error deleting user: %v", err)
+		abort(ctx, newError(http.StatusInternalServerError, "", "error deactivating user"))
+		return
+	}
+	respond(ctx, http.StatusOK, toUser(user, handler.baseURL, false))
+}
+
+// validate checks that the userName is an email that no other user has.
+func (handler *Handler) validate(ctx *gin.Context, user *models.User, id string) *Error {
+	address, err := mail.ParseAddress(user.Email)
+	if err != nil || address.Address != user.Email {
+		return newError(
+			http.StatusBadRequest,
+			scimTypeInvalidValue,
+			"userName must be an email address",
+		)
+	}
+
+	users, total, err := handler.userRepository.SearchUsers(
+		ctx.Request.Context(),
+		&repositories.UserFilter{
+			Field:    repositories.UserFieldEmail,
+			Operator: repositories.FilterEqual,
+			Value:    user.Email,
+		},
+		repositories.ListOptions{Limit: 1},
+	)
+	if err != nil {
+		log.Printf("error searching users: %v", err)
+		return newError(http.StatusInternalServerError, "", "error checking userName")
+	}
+	if total > 1 || (total == 1 && users[0].Id != id) {
+		return newError(
+			http.StatusConflict,
+			scimTypeUniqueness,
+			"userName %q is already taken",
+			user.Email,
+		)
+	}
+	return nil
+}
+
+func fromUser(resource *User) *models.User {
+	user := &models.User{Email: resource.UserName}
+	if user.Email == "" {
+		user.Email = primaryEmail(resource.Emails)
+	}
+	if resource.Name != nil {
+		user.FirstName = resource.Name.GivenName
+		user.LastName = resource.Name.FamilyName
+	}
+	return user
+}
+
+func requireProvisioner(ctx *gin.Context) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok {
+		ctx.Header("WWW-Authenticate", "Bearer")
+		abort(ctx, newError(http.StatusUnauthorized, "", "authentication required"))
+		return
+	}
+	if !principal.HasRole(auth.RoleSCIM) && !principal.HasRole(auth.RoleAdmin) {
+		abort(ctx, newError(
+			http.StatusForbidden,
+			"",
+			"provisioning requires the %s role",
+			auth.RoleSCIM,
+		))
+		return
+	}
+	ctx.Next()
+}
+
+func queryInt(ctx *gin.Context, name string, defaultValue int) (int, *Error) {
+	raw, ok := ctx.GetQuery(name)
+	if !ok {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, newError(
+			http.StatusBadRequest,
+			scimTypeInvalidValue,
+			"%s must be an integer",
+			name,
+		)
+	}
+	return value, nil
+}
+
+func respond(ctx *gin.Context, status int, body any) {
+	ctx.Header("Content-Type", contentTypeSCIM+"; charset=utf-8")
+	ctx.JSON(status, body)
+}
+
+func abort(ctx *gin.Context, err *Error) {
+	ctx.Header("Content-Type", contentTypeSCIM+"; charset=utf-8")
+	ctx.AbortWithStatusJSON(err.StatusCode(), err)
+}
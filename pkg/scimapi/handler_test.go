@@ -0,0 +1,178 @@
+package scimapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+const baseURL = "https://api.example.com/scim/v2"
+
+func newRouter(userRepository repositories.UserRepository, roles ...string) *gin.Engine {
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		if roles != nil {
+			principal := &auth.Principal{Subject: "okta", Roles: roles}
+			ctx.Request = ctx.Request.WithContext(
+				auth.WithPrincipal(ctx.Request.Context(), principal),
+			)
+		}
+	})
+	NewHandler(userRepository, baseURL).Register(router.Group("/scim/v2"))
+	return router
+}
+
+func send(router *gin.Engine, method string, path string, body string) *httptest.ResponseRecorder {
+	request, _ := http.NewRequest(method, path, strings.NewReader(body))
+	request.Header.Set("Content-Type", contentTypeSCIM)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func decode(t *testing.T, recorder *httptest.ResponseRecorder) map[string]any {
+	var body map[string]any
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatalf("error unmarshaling response: %v", err)
+	}
+	return body
+}
+
+func createUser(
+	t *testing.T,
+	userRepository repositories.UserRepository,
+	email string,
+) *models.User {
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Email: email}
+	if err := userRepository.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	return user
+}
+
+func TestHandler_CreateUser(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	router := newRouter(userRepository, auth.RoleSCIM)
+	body := `{
+		"schemas": ["urn:ietf:params:scim:schemas:core:2.0:User"],
+		"userName": "jane.doe@mail.com",
+		"name": {"givenName": "Jane", "familyName": "Doe"},
+		"active": true
+	}`
+
+	recorder := send(router, "POST", "/scim/v2/Users", body)
+
+	assert.Equal(t, 201, recorder.Code, "Should create user")
+	assert.Equal(t, contentTypeSCIM+"; charset=utf-8", recorder.Header().Get("Content-Type"))
+	created := decode(t, recorder)
+	assert.Equal(t, baseURL+"/Users/"+created["id"].(string), recorder.Header().Get("Location"))
+	assert.Equal(t, "jane.doe@mail.com", created["userName"])
+	assert.Equal(t, true, created["active"])
+
+	recorder = send(router, "POST", "/scim/v2/Users", body)
+
+	assert.Equal(t, 409, recorder.Code, "Should reject taken userName")
+	assert.Equal(t, map[string]any{
+		"schemas":  []any{ErrorSchema},
+		"status":   "409",
+		"scimType": "uniqueness",
+		"detail":   `userName "jane.doe@mail.com" is already taken`,
+	}, decode(t, recorder), "Should return SCIM error")
+}
+
+func TestHandler_ListUsers(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	createUser(t, userRepository, "jane.doe@mail.com")
+	createUser(t, userRepository, "john.doe@mail.com")
+	createUser(t, userRepository, "jane.roe@example.com")
+	router := newRouter(userRepository, auth.RoleSCIM)
+
+	recorder := send(
+		router,
+		"GET",
+		`/scim/v2/Users?filter=emails.value+co+"mail.com"&startIndex=2&count=5`,
+		"",
+	)
+
+	assert.Equal(t, 200, recorder.Code, "Should list users")
+	list := decode(t, recorder)
+	assert.Equal(t, []any{ListResponseSchema}, list["schemas"])
+	assert.Equal(t, float64(2), list["totalResults"], "Should count all matches")
+	assert.Equal(t, float64(2), list["startIndex"])
+	assert.Equal(t, float64(1), list["itemsPerPage"], "Should return page from startIndex")
+
+	recorder = send(router, "GET", `/scim/v2/Users?filter=userName+eq+"JANE.ROE@example.com"`, "")
+
+	list = decode(t, recorder)
+	assert.Equal(t, float64(1), list["totalResults"], "Should compare userName ignoring case")
+
+	recorder = send(router, "GET", `/scim/v2/Users?filter=userName+gt+"a"`, "")
+
+	assert.Equal(t, 400, recorder.Code, "Should reject unsupported filter")
+	assert.Equal(t, "invalidFilter", decode(t, recorder)["scimType"])
+}
+
+func TestHandler_PatchUser(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+	user := createUser(t, userRepository, "jane.doe@mail.com")
+	router := newRouter(userRepository, auth.RoleSCIM)
+	body := `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [
+			{"op": "Replace", "path": "name.familyName", "value": "Roe"},
+			{"op": "replace", "path": "emails[type eq \"work\"].value", "value": "jane.roe@mail.com"},
+			{"op": "add", "value": {"displayName": "Jane Roe", "name.givenName": "Janet"}}
+		]
+	}`
+
+	recorder := send(router, "PATCH", "/scim/v2/Users/"+user.Id, body)
+
+	assert.Equal(t, 200, recorder.Code, "Should patch user")
+	patched, _ := userRepository.GetUserById(context.Background(), user.Id)
+	assert.Equal(t, "Janet", patched.FirstName)
+	assert.Equal(t, "Roe", patched.LastName)
+	assert.Equal(t, "jane.roe@mail.com", patched.Email)
+
+	body = `{
+		"schemas": ["urn:ietf:params:scim:api:messages:2.0:PatchOp"],
+		"Operations": [{"op": "replace", "value": {"active": "False"}}]
+	}`
+
+	recorder = send(router, "PATCH", "/scim/v2/Users/"+user.Id, body)
+
+	assert.Equal(t, 200, recorder.Code, "Should deactivate user")
+	assert.Equal(t, false, decode(t, recorder)["active"])
+	_, err := userRepository.GetUserById(context.Background(), user.Id)
+	assert.ErrorIs(t, err, repositories.ErrUserNotFound, "Should delete deactivated user")
+}
+
+func TestHandler_Authorization(t *testing.T) {
+	userRepository := repositories.NewMemoryUserRepository()
+
+	recorder := send(newRouter(userRepository), "GET", "/scim/v2/Users", "")
+	assert.Equal(t, 401, recorder.Code, "Should require authentication")
+	assert.Equal(t, "401", decode(t, recorder)["status"])
+
+	recorder = send(newRouter(userRepository, "reader"), "GET", "/scim/v2/Users", "")
+	assert.Equal(t, 403, recorder.Code, "Should require scim role")
+
+	recorder = send(
+		newRouter(userRepository, auth.RoleSCIM),
+		"GET",
+		"/scim/v2/ServiceProviderConfig",
+		"",
+	)
+	assert.Equal(t, 200, recorder.Code, "Should serve discovery")
+	assert.Equal(t, map[string]any{"supported": true}, decode(t, recorder)["patch"])
+}
@@ -0,0 +1,185 @@
+package scimapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+var emailValuePath = regexp.MustCompile(`^emails\[[^\]]*\]\.value$`)
+
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+type userState struct {
+	user   models.User
+	active bool
+}
+
+// apply applies PATCH operations to a user. Read only attributes, externalId
+// and extension attributes are accepted but not stored.
+func (state *userState) apply(operations []PatchOperation) *Error {
+	for _, operation := range operations {
+		switch strings.ToLower(operation.Op) {
+		case "add", "replace":
+			if operation.Path != "" {
+				if err := state.set(operation.Path, operation.Value); err != nil {
+					return err
+				}
+				continue
+			}
+			var attributes map[string]json.RawMessage
+			if err := json.Unmarshal(operation.Value, &attributes); err != nil {
+				return newError(
+					http.StatusBadRequest,
+					scimTypeInvalidValue,
+					"value without path must be an object",
+				)
+			}
+			for path, value := range attributes {
+				if strings.EqualFold(path, "schemas") {
+					continue
+				}
+				if err := state.set(path, value); err != nil {
+					return err
+				}
+			}
+		case "remove":
+			if operation.Path == "" {
+				return newError(http.StatusBadRequest, scimTypeNoTarget, "remove requires a path")
+			}
+			if err := state.remove(operation.Path); err != nil {
+				return err
+			}
+		default:
+			return newError(
+				http.StatusBadRequest,
+				scimTypeInvalidSyntax,
+				"unsupported operation %q",
+				operation.Op,
+			)
+		}
+	}
+	return nil
+}
+
+func (state *userState) set(path string, value json.RawMessage) *Error {
+	normalized := normalizePath(path)
+	switch {
+	case normalized == "username" ||
+		normalized == "emails.value" ||
+		emailValuePath.MatchString(normalized):
+		return decodeString(path, value, &state.user.Email)
+	case normalized == "name.givenname":
+		return decodeString(path, value, &state.user.FirstName)
+	case normalized == "name.familyname":
+		return decodeString(path, value, &state.user.LastName)
+	case normalized == "name":
+		var name Name
+		if err := json.Unmarshal(value, &name); err != nil {
+			return invalidValue(path)
+		}
+		if name.GivenName != "" {
+			state.user.FirstName = name.GivenName
+		}
+		if name.FamilyName != "" {
+			state.user.LastName = name.FamilyName
+		}
+		return nil
+	case normalized == "emails":
+		var emails []Email
+		if err := json.Unmarshal(value, &emails); err != nil {
+			return invalidValue(path)
+		}
+		if email := primaryEmail(emails); email != "" {
+			state.user.Email = email
+		}
+		return nil
+	case normalized == "active":
+		return decodeBool(path, value, &state.active)
+	case isIgnored(normalized):
+		return nil
+	}
+	return newError(http.StatusBadRequest, scimTypeInvalidPath, "unsupported path %q", path)
+}
+
+func (state *userState) remove(path string) *Error {
+	normalized := normalizePath(path)
+	if isIgnored(normalized) {
+		return nil
+	}
+	return newError(
+		http.StatusBadRequest,
+		scimTypeInvalidValue,
+		"attribute %q cannot be removed",
+		path,
+	)
+}
+
+func isIgnored(path string) bool {
+	switch path {
+	case "displayname", "externalid", "name.formatted":
+		return true
+	}
+	return strings.HasPrefix(path, "urn:")
+}
+
+func primaryEmail(emails []Email) string {
+	for _, email := range emails {
+		if email.Primary {
+			return email.Value
+		}
+	}
+	if len(emails) > 0 {
+		return emails[0].Value
+	}
+	return ""
+}
+
+func decodeString(path string, value json.RawMessage, target *string) *Error {
+	var decoded string
+	if err := json.Unmarshal(value, &decoded); err != nil || decoded == "" {
+		return invalidValue(path)
+	}
+	*target = decoded
+	return nil
+}
+
+// decodeBool also accepts booleans sent as strings, as Azure AD does.
+func decodeBool(path string, value json.RawMessage, target *bool) *Error {
+	var decoded any
+	if err := json.Unmarshal(value, &decoded); err != nil {
+		return invalidValue(path)
+	}
+	switch typed := decoded.(type) {
+	case bool:
+		*target = typed
+	case string:
+		switch strings.ToLower(typed) {
+		case "true":
+			*target = true
+		case "false":
+			*target = false
+		default:
+			return invalidValue(path)
+		}
+	default:
+		return invalidValue(path)
+	}
+	return nil
+}
+
+func invalidValue(path string) *Error {
+	return newError(http.StatusBadRequest, scimTypeInvalidValue, "invalid value for %q", path)
+}
@@ -0,0 +1,274 @@
+package scimapi
+
+import (
+	"strings"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+const (
+	UserSchema                  = "urn:ietf:params:scim:schemas:core:2.0:User"
+	ListResponseSchema          = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema               = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema                 = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceProviderConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	ResourceTypeSchema          = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	SchemaSchema                = "urn:ietf:params:scim:schemas:core:2.0:Schema"
+
+	userResourceType = "User"
+	maxResults       = 200
+)
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type Email struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type User struct {
+	Schemas     []string `json:"schemas"`
+	Id          string   `json:"id,omitempty"`
+	ExternalId  string   `json:"externalId,omitempty"`
+	UserName    string   `json:"userName"`
+	Name        *Name    `json:"name,omitempty"`
+	DisplayName string   `json:"displayName,omitempty"`
+	Emails      []Email  `json:"emails,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+	Meta        *Meta    `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int64    `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+	Resources    []any    `json:"Resources"`
+}
+
+func newListResponse(resources []any, total int64, startIndex int) *ListResponse {
+	return &ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}
+}
+
+// toUser maps a user to the core user schema. The email is both the userName
+// and the only, primary, email address.
+func toUser(user *models.User, baseURL string, active bool) *User {
+	fullName := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	return &User{
+		Schemas:  []string{UserSchema},
+		Id:       user.Id,
+		UserName: user.Email,
+		Name: &Name{
+			Formatted:  fullName,
+			GivenName:  user.FirstName,
+			FamilyName: user.LastName,
+		},
+		DisplayName: fullName,
+		Emails:      []Email{{Value: user.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta: &Meta{
+			ResourceType: userResourceType,
+			Created:      &user.CreatedAt,
+			LastModified: &user.UpdatedAt,
+			Location:     baseURL + "/Users/" + user.Id,
+		},
+	}
+}
+
+type supported struct {
+	Supported bool `json:"supported"`
+}
+
+type bulkConfig struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type filterConfig struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type authenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
+
+type ServiceProviderConfig struct {
+	Schemas               []string               `json:"schemas"`
+	Patch                 supported              `json:"patch"`
+	Bulk                  bulkConfig             `json:"bulk"`
+	Filter                filterConfig           `json:"filter"`
+	ChangePassword        supported              `json:"changePassword"`
+	Sort                  supported              `json:"sort"`
+	ETag                  supported              `json:"etag"`
+	AuthenticationSchemes []authenticationScheme `json:"authenticationSchemes"`
+	Meta                  *Meta                  `json:"meta"`
+}
+
+func newServiceProviderConfig(baseURL string) *ServiceProviderConfig {
+	return &ServiceProviderConfig{
+		Schemas: []string{ServiceProviderConfigSchema},
+		Patch:   supported{Supported: true},
+		Filter:  filterConfig{Supported: true, MaxResults: maxResults},
+		AuthenticationSchemes: []authenticationScheme{
+			{
+				Type:        "oauthbearertoken",
+				Name:        "Bearer Token",
+				Description: "Bearer token of a caller with the scim role",
+				Primary:     true,
+			},
+		},
+		Meta: &Meta{
+			ResourceType: "ServiceProviderConfig",
+			Location:     baseURL + "/ServiceProviderConfig",
+		},
+	}
+}
+
+type ResourceType struct {
+	Schemas     []string `json:"schemas"`
+	Id          string   `json:"id"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Description string   `json:"description"`
+	Schema      string   `json:"schema"`
+	Meta        *Meta    `json:"meta"`
+}
+
+func newUserResourceType(baseURL string) *ResourceType {
+	return &ResourceType{
+		Schemas:     []string{ResourceTypeSchema},
+		Id:          userResourceType,
+		Name:        userResourceType,
+		Endpoint:    "/Users",
+		Description: "User Account",
+		Schema:      UserSchema,
+		Meta: &Meta{
+			ResourceType: "ResourceType",
+			Location:     baseURL + "/ResourceTypes/" + userResourceType,
+		},
+	}
+}
+
+type Attribute struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	MultiValued   bool        `json:"multiValued"`
+	Description   string      `json:"description"`
+	Required      bool        `json:"required"`
+	CaseExact     bool        `json:"caseExact"`
+	Mutability    string      `json:"mutability"`
+	Returned      string      `json:"returned"`
+	Uniqueness    string      `json:"uniqueness"`
+	SubAttributes []Attribute `json:"subAttributes,omitempty"`
+}
+
+type Schema struct {
+	Schemas     []string    `json:"schemas"`
+	Id          string      `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description"`
+	Attributes  []Attribute `json:"attributes"`
+	Meta        *Meta       `json:"meta"`
+}
+
+func stringAttribute(name string, description string, required bool) Attribute {
+	return Attribute{
+		Name:        name,
+		Type:        "string",
+		Description: description,
+		Required:    required,
+		Mutability:  "readWrite",
+		Returned:    "default",
+		Uniqueness:  "none",
+	}
+}
+
+func newUserSchema(baseURL string) *Schema {
+	userName := stringAttribute("userName", "Email address of the user", true)
+	userName.Uniqueness = "server"
+	displayName := stringAttribute("displayName", "Full name of the user", false)
+	displayName.Mutability = "readOnly"
+	formatted := stringAttribute("formatted", "Full name of the user", false)
+	formatted.Mutability = "readOnly"
+
+	return &Schema{
+		Schemas:     []string{SchemaSchema},
+		Id:          UserSchema,
+		Name:        userResourceType,
+		Description: "User Account",
+		Attributes: []Attribute{
+			userName,
+			{
+				Name:        "name",
+				Type:        "complex",
+				Description: "Name of the user",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+				SubAttributes: []Attribute{
+					formatted,
+					stringAttribute("givenName", "First name of the user", false),
+					stringAttribute("familyName", "Last name of the user", false),
+				},
+			},
+			displayName,
+			{
+				Name:        "emails",
+				Type:        "complex",
+				MultiValued: true,
+				Description: "Email address of the user, always equal to userName",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+				SubAttributes: []Attribute{
+					stringAttribute("value", "Email address", false),
+					stringAttribute("type", "Always work", false),
+					{
+						Name:        "primary",
+						Type:        "boolean",
+						Description: "Always true",
+						Mutability:  "readWrite",
+						Returned:    "default",
+						Uniqueness:  "none",
+					},
+				},
+			},
+			{
+				Name:        "active",
+				Type:        "boolean",
+				Description: "Deactivating a user deletes it",
+				Mutability:  "readWrite",
+				Returned:    "default",
+				Uniqueness:  "none",
+			},
+		},
+		Meta: &Meta{
+			ResourceType: "Schema",
+			Location:     baseURL + "/Schemas/" + UserSchema,
+		},
+	}
+}
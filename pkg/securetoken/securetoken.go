@@ -0,0 +1,27 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+const tokenLength = 32
+
+// Generate returns a random URL safe token and the hash to store in its place.
+func Generate() (string, string, error) {
+	data := make([]byte, tokenLength)
+	_, err := rand.Read(data)
+	if err != nil {
+		return "", "", fmt.Errorf("error generating token: %v", err)
+	}
+	token := base64.RawURLEncoding.EncodeToString(data)
+	return token, Hash(token), nil
+}
+
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
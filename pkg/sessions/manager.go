@@ -0,0 +1,218 @@
+package sessions
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+type Metadata struct {
+	UserAgent string
+	IPAddress string
+}
+
+type Tokens struct {
+	SessionId    string
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    time.Duration
+}
+
+type Manager struct {
+	sessionRepository repositories.SessionRepository
+	issuer            *auth.TokenIssuer
+	denylist          auth.Denylist
+	ttl               time.Duration
+	admins            map[string]bool
+	plans             map[string]string
+	now               func() time.Time
+}
+
+// NewManager creates a Manager whose sessions expire after being unused for
+// ttl. Revoked sessions are put on the denylist, which should also be given to
+// the issuer.
+func NewManager(
+	sessionRepository repositories.SessionRepository,
+	issuer *auth.TokenIssuer,
+	denylist auth.Denylist,
+	ttl time.Duration,
+) *Manager {
+	return &Manager{
+		sessionRepository: sessionRepository,
+		issuer:            issuer,
+		denylist:          denylist,
+		ttl:               ttl,
+		now:               time.Now,
+	}
+}
+
+// WithAdmins grants the admin role to the sessions of userIds.
+func (manager *Manager) WithAdmins(userIds []string) *Manager {
+	manager.admins = map[string]bool{}
+	for _, userId := range userIds {
+		manager.admins[userId] = true
+	}
+	return manager
+}
+
+// WithPlans puts users on the rate limiting plans given by user id.
+func (manager *Manager) WithPlans(plans map[string]string) *Manager {
+	manager.plans = plans
+	return manager
+}
+
+// ParsePlans parses comma separated userId:plan entries.
+func ParsePlans(value string) (map[string]string, error) {
+	plans := map[string]string{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		userId, plan, ok := strings.Cut(entry, ":")
+		if !ok || userId == "" || plan == "" {
+			return nil, errors.New("expecting plans as comma separated userId:plan entries")
+		}
+		plans[userId] = plan
+	}
+	return plans, nil
+}
+
+func (manager *Manager) IsAdmin(userId string) bool {
+	return manager.admins[userId]
+}
+
+func (manager *Manager) Start(
+	ctx context.Context,
+	userId string,
+	metadata Metadata,
+) (*Tokens, error) {
+	refreshToken, hash, err := securetoken.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	tenantId, ok := tenancy.TenantFromContext(ctx)
+	if !ok {
+		tenantId = tenancy.DefaultTenant
+	}
+
+	now := manager.now()
+	session := &models.Session{
+		UserId:     userId,
+		TenantId:   tenantId,
+		UserAgent:  metadata.UserAgent,
+		IPAddress:  metadata.IPAddress,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(manager.ttl),
+	}
+	err = manager.sessionRepository.CreateSession(ctx, session, hash)
+	if err != nil {
+		return nil, fmt.Errorf("error creating session: %v", err)
+	}
+	return manager.tokens(session, refreshToken)
+}
+
+// Refresh rotates a refresh token. Reusing a rotated token revokes the session,
+// since either the legitimate client or an attacker holds a stolen copy.
+func (manager *Manager) Refresh(
+	ctx context.Context,
+	refreshToken string,
+	metadata Metadata,
+) (*Tokens, error) {
+	newRefreshToken, newHash, err := securetoken.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	now := manager.now()
+	session, err := manager.sessionRepository.RotateRefreshToken(
+		ctx,
+		securetoken.Hash(refreshToken),
+		newHash,
+		&models.Session{
+			UserAgent:  metadata.UserAgent,
+			IPAddress:  metadata.IPAddress,
+			LastUsedAt: now,
+			ExpiresAt:  now.Add(manager.ttl),
+		},
+	)
+	if errors.Is(err, repositories.ErrRefreshTokenReused) {
+		log.Printf(
+			"refresh token reused, revoked session %s of user %s",
+			session.Id,
+			session.UserId,
+		)
+		manager.deny(ctx, session.Id)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	return manager.tokens(session, newRefreshToken)
+}
+
+func (manager *Manager) List(ctx context.Context, userId string) ([]models.Session, error) {
+	return manager.sessionRepository.ListSessions(ctx, userId, manager.now())
+}
+
+func (manager *Manager) Revoke(ctx context.Context, userId string, sessionId string) error {
+	err := manager.sessionRepository.RevokeSession(ctx, userId, sessionId, manager.now())
+	if err != nil {
+		return err
+	}
+	manager.deny(ctx, sessionId)
+	return nil
+}
+
+func (manager *Manager) RevokeAll(ctx context.Context, userId string) error {
+	sessionIds, err := manager.sessionRepository.RevokeUserSessions(ctx, userId, manager.now())
+	if err != nil {
+		return err
+	}
+	for _, sessionId := range sessionIds {
+		manager.deny(ctx, sessionId)
+	}
+	return nil
+}
+
+func (manager *Manager) tokens(session *models.Session, refreshToken string) (*Tokens, error) {
+	var roles []string
+	if manager.IsAdmin(session.UserId) {
+		roles = []string{auth.RoleAdmin}
+	}
+	accessToken, err := manager.issuer.IssueAccessToken(
+		session.UserId,
+		session.Id,
+		session.TenantId,
+		roles,
+		manager.plans[session.UserId],
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &Tokens{
+		SessionId:    session.Id,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    manager.issuer.AccessTTL(),
+	}, nil
+}
+
+// deny rejects access tokens of a revoked session until the last of them has
+// expired. A failure is only logged since the session itself is revoked.
+func (manager *Manager) deny(ctx context.Context, sessionId string) {
+	err := manager.denylist.Deny(ctx, sessionId, manager.issuer.AccessTTL())
+	if err != nil {
+		log.Printf("error adding session %s to denylist: %v", sessionId, err)
+	}
+}
@@ -0,0 +1,109 @@
+package sessions
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+var (
+	tokenColumns   = []string{"id", "session_id", "token_hash", "used_at"}
+	sessionColumns = []string{"id", "user_id", "expires_at"}
+)
+
+func newTestManager(t *testing.T) (*Manager, *auth.TokenIssuer, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	denylist := auth.NewMemoryDenylist()
+	issuer := auth.NewTokenIssuer([]byte("secret"), "test", time.Minute).WithDenylist(denylist)
+	manager := NewManager(repositories.NewSQLSessionRepository(db), issuer, denylist, time.Hour)
+	return manager, issuer, mock
+}
+
+func expectLockToken(mock sqlmock.Sqlmock, tokenHash string, usedAt any) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "refresh_tokens" WHERE token_hash = $1`)).
+		WithArgs(tokenHash).
+		WillReturnRows(sqlmock.NewRows(tokenColumns).AddRow(1, "def456", tokenHash, usedAt))
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "sessions" WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2`,
+	)).
+		WithArgs("def456", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow("def456", "abc123", time.Now().Add(time.Hour)))
+}
+
+func TestManager_Refresh(t *testing.T) {
+	manager, issuer, mock := newTestManager(t)
+	manager.WithAdmins([]string{"abc123"}).WithPlans(map[string]string{"abc123": "pro"})
+	tokenHash := securetoken.Hash("refresh-token")
+
+	expectLockToken(mock, tokenHash, nil)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "refresh_tokens" SET "used_at"=$1 WHERE "id" = $2`)).
+		WithArgs(sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "refresh_tokens"`)).
+		WithArgs("def456", sqlmock.AnyArg(), nil, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "sessions" SET "user_agent"=$1,"ip_address"=$2,"last_used_at"=$3,"expires_at"=$4`,
+	)).
+		WithArgs("curl/7.79.1", "10.0.0.1", sqlmock.AnyArg(), sqlmock.AnyArg(), "def456").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tokens, err := manager.Refresh(
+		context.Background(),
+		"refresh-token",
+		Metadata{UserAgent: "curl/7.79.1", IPAddress: "10.0.0.1"},
+	)
+
+	assert.NoError(t, err, "Should rotate refresh token")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should match queries")
+	assert.NotEqual(t, "refresh-token", tokens.RefreshToken, "Should issue new refresh token")
+	principal, err := issuer.Authenticate(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err, "Should issue valid access token")
+	assert.Equal(t, "def456", principal.SessionId, "Should issue token for session")
+	assert.True(t, principal.HasRole(auth.RoleAdmin), "Should grant admin role to admins")
+	assert.Equal(t, "pro", principal.Plan, "Should issue token with plan of user")
+}
+
+func TestManager_RefreshReused(t *testing.T) {
+	manager, issuer, mock := newTestManager(t)
+	tokenHash := securetoken.Hash("refresh-token")
+	accessToken, err := issuer.IssueAccessToken("abc123", "def456", tenancy.DefaultTenant, nil, "")
+	if err != nil {
+		t.Fatalf("error issuing access token: %v", err)
+	}
+
+	expectLockToken(mock, tokenHash, time.Now().Add(-time.Minute))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "sessions" SET "revoked_at"=$1 WHERE "id" = $2`)).
+		WithArgs(sqlmock.AnyArg(), "def456").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err = manager.Refresh(context.Background(), "refresh-token", Metadata{})
+
+	assert.ErrorIs(t, err, repositories.ErrRefreshTokenReused, "Should detect reuse")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should revoke session")
+	_, err = issuer.Authenticate(context.Background(), accessToken)
+	assert.ErrorIs(t, err, auth.ErrUnauthenticated, "Should deny access tokens of session")
+}
@@ -0,0 +1,115 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+)
+
+var (
+	ErrUnknownTenant  = errors.New("unknown tenant")
+	ErrTenantMismatch = errors.New("requested tenant does not match the tenant of the caller")
+)
+
+type Directory interface {
+	TenantExists(ctx context.Context, id string) (bool, error)
+}
+
+type Resolver struct {
+	directory  Directory
+	baseDomain string
+}
+
+// NewResolver creates a Resolver that takes the first label of hosts below
+// baseDomain as tenant id. Subdomains are ignored if baseDomain is empty.
+func NewResolver(directory Directory, baseDomain string) *Resolver {
+	return &Resolver{
+		directory:  directory,
+		baseDomain: strings.ToLower(strings.Trim(baseDomain, ".")),
+	}
+}
+
+// Resolve returns the tenant of a request. The tenant in the claims of the
+// principal takes precedence and a requested tenant, given in a header or the
+// subdomain of host, has to match it. Principals without a tenant claim may
+// only choose a tenant as admins, and anonymous callers always get the default
+// tenant.
+func (resolver *Resolver) Resolve(
+	ctx context.Context,
+	principal *auth.Principal,
+	requested string,
+	host string,
+) (string, error) {
+	requested = resolver.requested(requested, host)
+	if principal == nil {
+		return DefaultTenant, nil
+	}
+	if principal.TenantId != "" {
+		if requested != "" && requested != principal.TenantId {
+			return "", ErrTenantMismatch
+		}
+		return principal.TenantId, nil
+	}
+	if requested != "" && requested != DefaultTenant && !principal.HasRole(auth.RoleAdmin) {
+		return "", ErrTenantMismatch
+	}
+	return resolver.lookup(ctx, requested)
+}
+
+// ResolveSignIn returns the requested tenant of an anonymous request that
+// authenticates its caller within that tenant, such as a login.
+func (resolver *Resolver) ResolveSignIn(
+	ctx context.Context,
+	requested string,
+	host string,
+) (string, error) {
+	return resolver.lookup(ctx, resolver.requested(requested, host))
+}
+
+func (resolver *Resolver) requested(requested string, host string) string {
+	if requested == "" {
+		requested = resolver.subdomain(host)
+	}
+	return strings.ToLower(requested)
+}
+
+func (resolver *Resolver) lookup(ctx context.Context, requested string) (string, error) {
+	if requested == "" || requested == DefaultTenant {
+		return DefaultTenant, nil
+	}
+	if !ValidTenantId(requested) {
+		return "", ErrUnknownTenant
+	}
+
+	exists, err := resolver.directory.TenantExists(ctx, requested)
+	if err != nil {
+		return "", fmt.Errorf("error looking up tenant: %v", err)
+	}
+	if !exists {
+		return "", ErrUnknownTenant
+	}
+	return requested, nil
+}
+
+func (resolver *Resolver) subdomain(host string) string {
+	if resolver.baseDomain == "" || host == "" {
+		return ""
+	}
+	if hostname, _, err := net.SplitHostPort(host); err == nil {
+		host = hostname
+	}
+	host = strings.ToLower(host)
+	suffix := "." + resolver.baseDomain
+	if !strings.HasSuffix(host, suffix) {
+		return ""
+	}
+	label := strings.TrimSuffix(host, suffix)
+	if strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
@@ -0,0 +1,105 @@
+package tenancy
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Setting is the Postgres setting that the row-level security policies in
+// migrations/ compare the tenant_id column with.
+const Setting = "app.tenant_id"
+
+//go:embed migrations/*.sql
+var migrations embed.FS
+
+// MigrateRowLevelSecurity applies the migrations that install row-level
+// security policies, in order of their names, recording each applied one in
+// the schema_migrations table so it runs once. The policies restrict tables to
+// the rows of the tenant in Setting, including for the owner of the tables.
+// Statements in sessions without the setting see no rows, so background jobs
+// that work across tenants have to connect as a role with BYPASSRLS.
+func MigrateRowLevelSecurity(db *gorm.DB) error {
+	err := db.Exec(
+		"CREATE TABLE IF NOT EXISTS schema_migrations " +
+			"(version text PRIMARY KEY, applied_at timestamptz NOT NULL DEFAULT now())",
+	).Error
+	if err != nil {
+		return fmt.Errorf("error creating schema_migrations: %v", err)
+	}
+
+	entries, err := fs.ReadDir(migrations, "migrations")
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		version := strings.TrimSuffix(entry.Name(), ".sql")
+		data, err := migrations.ReadFile(path.Join("migrations", entry.Name()))
+		if err != nil {
+			return err
+		}
+		err = db.Transaction(func(tx *gorm.DB) error {
+			return migrate(tx, version, string(data))
+		})
+		if err != nil {
+			return fmt.Errorf("error applying migration %s: %v", version, err)
+		}
+	}
+	return nil
+}
+
+func migrate(tx *gorm.DB, version string, script string) error {
+	// The lock makes instances starting at the same time apply a migration
+	// once.
+	err := tx.Exec("LOCK TABLE schema_migrations IN EXCLUSIVE MODE").Error
+	if err != nil {
+		return err
+	}
+	var applied int64
+	err = tx.Raw("SELECT count(*) FROM schema_migrations WHERE version = ?", version).
+		Scan(&applied).
+		Error
+	if err != nil || applied > 0 {
+		return err
+	}
+
+	for _, statement := range strings.Split(script, ";") {
+		statement = strings.TrimSpace(statement)
+		if statement == "" {
+			continue
+		}
+		err = tx.Exec(statement).Error
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", version).Error
+}
+
+// SetLocal sets Setting to the tenant in the context of tx until the end of
+// the transaction. It is the equivalent of SET LOCAL, which does not take
+// parameters.
+func SetLocal(tx *gorm.DB) error {
+	tenantId, ok := TenantFromContext(tx.Statement.Context)
+	if !ok {
+		return nil
+	}
+	return tx.Exec("SELECT set_config(?, ?, true)", Setting, tenantId).Error
+}
+
+// Transaction runs fn in a transaction of db in which Setting holds the tenant
+// in ctx.
+func Transaction(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := SetLocal(tx)
+		if err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
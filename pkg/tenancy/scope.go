@@ -0,0 +1,91 @@
+package tenancy
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	tenantField  = "TenantId"
+	tenantColumn = "tenant_id"
+)
+
+// Scope restricts a query to the tenant in ctx using the tenant_id column of
+// table. Queries without a tenant in ctx, such as those of background jobs,
+// are not restricted.
+func Scope(ctx context.Context, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tenantId, ok := TenantFromContext(ctx)
+		if !ok {
+			return db
+		}
+		return db.Where(tenantCondition(table, tenantId))
+	}
+}
+
+// Plugin applies the tenant in the context of a statement to every statement
+// on a model with a TenantId field, so that repositories cannot forget it.
+// Created records are assigned to the tenant. Raw SQL is not scoped.
+type Plugin struct{}
+
+func (Plugin) Name() string {
+	return "tenancy"
+}
+
+func (Plugin) Initialize(db *gorm.DB) error {
+	callbacks := db.Callback()
+	err := callbacks.Create().Before("gorm:create").Register("tenancy:create", assignTenant)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Query().Before("gorm:query").Register("tenancy:query", scopeStatement)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Row().Before("gorm:row").Register("tenancy:row", scopeStatement)
+	if err != nil {
+		return err
+	}
+	err = callbacks.Update().Before("gorm:update").Register("tenancy:update", scopeStatement)
+	if err != nil {
+		return err
+	}
+	return callbacks.Delete().Before("gorm:delete").Register("tenancy:delete", scopeStatement)
+}
+
+func assignTenant(db *gorm.DB) {
+	tenantId, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+	db.Statement.SetColumn(tenantField, tenantId, true)
+}
+
+func scopeStatement(db *gorm.DB) {
+	tenantId, ok := statementTenant(db)
+	if !ok {
+		return
+	}
+	db.Statement.AddClause(clause.Where{
+		Exprs: []clause.Expression{tenantCondition(db.Statement.Table, tenantId)},
+	})
+}
+
+func statementTenant(db *gorm.DB) (string, bool) {
+	if db.Error != nil || db.Statement.Schema == nil {
+		return "", false
+	}
+	if db.Statement.Schema.LookUpField(tenantField) == nil {
+		return "", false
+	}
+	return TenantFromContext(db.Statement.Context)
+}
+
+func tenantCondition(table string, tenantId string) clause.Expression {
+	return clause.Eq{
+		Column: clause.Column{Table: table, Name: tenantColumn},
+		Value:  tenantId,
+	}
+}
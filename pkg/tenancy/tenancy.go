@@ -0,0 +1,36 @@
+package tenancy
+
+import (
+	"context"
+	"regexp"
+)
+
+const (
+	DefaultTenant = "default"
+	Header        = "X-Tenant-ID"
+)
+
+var tenantIdPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+type tenantKey struct{}
+
+func WithTenant(ctx context.Context, tenantId string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantId)
+}
+
+// WithoutTenant lets queries with ctx find records of every tenant, such as the
+// token of a link, which then selects the tenant.
+func WithoutTenant(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tenantKey{}, nil)
+}
+
+func TenantFromContext(ctx context.Context) (string, bool) {
+	tenantId, ok := ctx.Value(tenantKey{}).(string)
+	return tenantId, ok
+}
+
+// ValidTenantId reports whether id can be used as a tenant id, which is also
+// used as a subdomain.
+func ValidTenantId(id string) bool {
+	return tenantIdPattern.MatchString(id)
+}
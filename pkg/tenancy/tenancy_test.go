@@ -0,0 +1,224 @@
+package tenancy
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+)
+
+type staticDirectory map[string]bool
+
+func (directory staticDirectory) TenantExists(_ context.Context, id string) (bool, error) {
+	return directory[id], nil
+}
+
+type record struct {
+	Id       string
+	TenantId string
+	Name     string
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	resolver := NewResolver(staticDirectory{"acme": true}, "example.com")
+
+	testCases := []struct {
+		name        string
+		principal   *auth.Principal
+		requested   string
+		host        string
+		signIn      bool
+		expected    string
+		expectedErr error
+	}{
+		{
+			name:     "default tenant",
+			host:     "example.com",
+			expected: DefaultTenant,
+		},
+		{
+			name:      "anonymous requested tenant",
+			requested: "acme",
+			host:      "acme.example.com",
+			expected:  DefaultTenant,
+		},
+		{
+			name:      "requested tenant at sign in",
+			requested: "ACME",
+			signIn:    true,
+			expected:  "acme",
+		},
+		{
+			name:     "subdomain at sign in",
+			host:     "acme.example.com:8080",
+			signIn:   true,
+			expected: "acme",
+		},
+		{
+			name:        "unknown tenant at sign in",
+			requested:   "globex",
+			signIn:      true,
+			expectedErr: ErrUnknownTenant,
+		},
+		{
+			name:      "requested tenant of admin",
+			principal: &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}},
+			host:      "acme.example.com",
+			expected:  "acme",
+		},
+		{
+			name:        "unknown tenant of admin",
+			principal:   &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}},
+			requested:   "globex",
+			expectedErr: ErrUnknownTenant,
+		},
+		{
+			name:        "requested tenant of principal without tenant",
+			principal:   &auth.Principal{Subject: "service"},
+			requested:   "acme",
+			expectedErr: ErrTenantMismatch,
+		},
+		{
+			name:      "default tenant of principal without tenant",
+			principal: &auth.Principal{Subject: "service"},
+			expected:  DefaultTenant,
+		},
+		{
+			name:      "tenant of principal",
+			principal: &auth.Principal{Subject: "abc123", TenantId: "acme"},
+			host:      "example.com",
+			expected:  "acme",
+		},
+		{
+			name:        "requested tenant of other principal",
+			principal:   &auth.Principal{Subject: "abc123", TenantId: "acme"},
+			host:        "globex.example.com",
+			expectedErr: ErrTenantMismatch,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var actual string
+			var err error
+			if tc.signIn {
+				actual, err = resolver.ResolveSignIn(context.Background(), tc.requested, tc.host)
+			} else {
+				actual, err = resolver.Resolve(
+					context.Background(),
+					tc.principal,
+					tc.requested,
+					tc.host,
+				)
+			}
+
+			assert.True(t, errors.Is(err, tc.expectedErr), "Error should match expected")
+			assert.Equal(t, tc.expected, actual, "Tenant should match expected")
+		})
+	}
+}
+
+func TestPlugin(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	if err = db.Use(Plugin{}); err != nil {
+		t.Fatalf("error registering plugin: %v", err)
+	}
+	ctx := WithTenant(context.Background(), "acme")
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO "records" ("id","tenant_id","name")`)).
+		WithArgs("abc123", "acme", "Jane").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "records" WHERE id = $1 AND "records"."tenant_id" = $2`,
+	)).
+		WithArgs("abc123", "acme").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(
+		`DELETE FROM "records" WHERE id = $1 AND "records"."tenant_id" = $2`,
+	)).
+		WithArgs("abc123", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "records" WHERE id = $1`)).
+			WithArgs("abc123").
+			WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	}
+
+	err = db.WithContext(ctx).Create(&record{Id: "abc123", Name: "Jane"}).Error
+	assert.NoError(t, err, "Should create record in tenant")
+
+	err = db.WithContext(ctx).Where("id = ?", "abc123").Find(&[]record{}).Error
+	assert.NoError(t, err, "Should query records in tenant")
+
+	err = db.WithContext(ctx).Where("id = ?", "abc123").Delete(&record{}).Error
+	assert.NoError(t, err, "Should delete records in tenant")
+
+	err = db.WithContext(context.Background()).Where("id = ?", "abc123").Find(&[]record{}).Error
+	assert.NoError(t, err, "Should query records without tenant")
+
+	err = db.WithContext(WithoutTenant(ctx)).Where("id = ?", "abc123").Find(&[]record{}).Error
+	assert.NoError(t, err, "Should query records of every tenant")
+
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should scope statements to tenant")
+}
+
+func TestMigrateRowLevelSecurity(t *testing.T) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+
+	condition := `tenant_id = current_setting('app.tenant_id', true)`
+	for _, applied := range []int{0, 1} {
+		mock.ExpectExec(regexp.QuoteMeta(`CREATE TABLE IF NOT EXISTS schema_migrations`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectBegin()
+		mock.ExpectExec(regexp.QuoteMeta(`LOCK TABLE schema_migrations IN EXCLUSIVE MODE`)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT count(*) FROM schema_migrations WHERE version = $1`)).
+			WithArgs("0001_users_row_level_security").
+			WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(applied))
+		if applied == 0 {
+			mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE users ENABLE ROW LEVEL SECURITY`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`ALTER TABLE users FORCE ROW LEVEL SECURITY`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`DROP POLICY IF EXISTS tenant_isolation ON users`)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(`CREATE POLICY tenant_isolation ON users\s+USING \(` +
+				regexp.QuoteMeta(condition) + `\)\s+WITH CHECK \(` + regexp.QuoteMeta(condition)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec(regexp.QuoteMeta(`INSERT INTO schema_migrations (version) VALUES ($1)`)).
+				WithArgs("0001_users_row_level_security").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+		}
+		mock.ExpectCommit()
+
+		err = MigrateRowLevelSecurity(db)
+
+		assert.NoError(t, err, "Should migrate row-level security")
+	}
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should install policy once")
+}
@@ -0,0 +1,44 @@
+package totp
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+	testCases := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 20000000000, expected: "353130"},
+	}
+
+	for _, tc := range testCases {
+		code, err := Code(secret, Step(time.Unix(tc.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, code, "Should match RFC 6238 test vector at %d", tc.unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("error generating secret: %v", err)
+	}
+	now := time.Now()
+	previous, _ := Code(secret, Step(now)-1)
+
+	step, ok := Validate(secret, previous, now, 1)
+	assert.True(t, ok, "Should accept code of previous step")
+	assert.Equal(t, Step(now)-1, step, "Should return step of code")
+
+	_, ok = Validate(secret, previous, now.Add(2*Period), 1)
+	assert.False(t, ok, "Should reject codes outside skew")
+}
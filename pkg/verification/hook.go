@@ -0,0 +1,39 @@
+package verification
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+// Hook marks a user as unverified when the email changes and invalidates the
+// tokens sent to the previous address.
+type Hook struct{}
+
+func NewHook() *Hook {
+	return &Hook{}
+}
+
+func (hook *Hook) OnUserChange(tx *gorm.DB, change *repositories.UserChange) error {
+	if change.Operation != repositories.OperationUpdate ||
+		change.Before.Email == change.After.Email {
+		return nil
+	}
+
+	err := tx.Model(change.After).Update("email_verified", false).Error
+	if err != nil {
+		return fmt.Errorf("error resetting email verification: %v", err)
+	}
+	change.After.EmailVerified = false
+
+	err = tx.Where("user_id = ? AND used_at IS NULL", change.After.Id).
+		Delete(&models.VerificationToken{}).
+		Error
+	if err != nil {
+		return fmt.Errorf("error invalidating verification tokens: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package verification
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+)
+
+// Publisher sends a verification email once a user is created or its email is
+// changed. Failures are logged rather than returned so that mail outages do not
+// hold back other publishers; users can request another email.
+type Publisher struct {
+	service *Service
+}
+
+func NewPublisher(service *Service) *Publisher {
+	return &Publisher{service: service}
+}
+
+func (publisher *Publisher) Publish(ctx context.Context, event *events.Event) error {
+	var snapshot events.UserSnapshot
+	switch event.Type {
+	case events.UserCreated:
+		var payload events.UserCreatedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			log.Printf("error decoding event %d: %v", event.Id, err)
+			return nil
+		}
+		snapshot = payload.User
+	case events.UserUpdated:
+		var payload events.UserUpdatedPayload
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			log.Printf("error decoding event %d: %v", event.Id, err)
+			return nil
+		}
+		if _, ok := payload.Changes["email"]; !ok {
+			return nil
+		}
+		snapshot = payload.User
+	default:
+		return nil
+	}
+
+	user := &models.User{Id: snapshot.Id, FirstName: snapshot.FirstName, Email: snapshot.Email}
+	if err := publisher.service.sendTo(ctx, user); err != nil {
+		log.Printf("error sending verification email to user %s: %v", user.Id, err)
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package verification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+)
+
+var ErrAlreadyVerified = errors.New("email is already verified")
+
+type Service struct {
+	userRepository         repositories.UserRepository
+	verificationRepository repositories.VerificationRepository
+	mailer                 mail.Mailer
+	verifyURL              string
+	ttl                    time.Duration
+	now                    func() time.Time
+}
+
+func NewService(
+	userRepository repositories.UserRepository,
+	verificationRepository repositories.VerificationRepository,
+	mailer mail.Mailer,
+	verifyURL string,
+	ttl time.Duration,
+) *Service {
+	return &Service{
+		userRepository:         userRepository,
+		verificationRepository: verificationRepository,
+		mailer:                 mailer,
+		verifyURL:              verifyURL,
+		ttl:                    ttl,
+		now:                    time.Now,
+	}
+}
+
+func (service *Service) Send(ctx context.Context, userId string) error {
+	user, err := service.userRepository.GetUserById(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return ErrAlreadyVerified
+	}
+	return service.sendTo(ctx, user)
+}
+
+func (service *Service) Verify(
+	ctx context.Context,
+	token string,
+) (*models.VerificationToken, error) {
+	verificationToken, err := service.verificationRepository.ConsumeVerificationToken(
+		ctx,
+		securetoken.Hash(token),
+		service.now(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user is marked as verified by the verification repository.
+	if invalidator, ok := service.userRepository.(repositories.UserInvalidator); ok {
+		invalidator.InvalidateUser(ctx, verificationToken.UserId)
+	}
+	return verificationToken, nil
+}
+
+func (service *Service) sendTo(ctx context.Context, user *models.User) error {
+	token, hash, err := securetoken.Generate()
+	if err != nil {
+		return err
+	}
+
+	err = service.verificationRepository.CreateVerificationToken(ctx, &models.VerificationToken{
+		UserId:    user.Id,
+		TenantId:  user.TenantId,
+		Email:     user.Email,
+		TokenHash: hash,
+		ExpiresAt: service.now().Add(service.ttl),
+	})
+	if err != nil {
+		return fmt.Errorf("error storing verification token: %v", err)
+	}
+
+	link := service.verifyURL + "?token=" + url.QueryEscape(token)
+	return service.mailer.Send(ctx, &mail.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nPlease verify your email address by opening the link below. "+
+				"The link expires in %s.\n\n%s\n",
+			user.FirstName,
+			service.ttl,
+			link,
+		),
+	})
+}
@@ -0,0 +1,175 @@
+package verification
+
+import (
+	"context"
+	"database/sql/driver"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/johannaojeling/go-rest-api/pkg/mail"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/securetoken"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func newMockDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sql mock: %v", err)
+	}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: conn}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("error opening db connection: %v", err)
+	}
+	return db, mock
+}
+
+func TestService_Send(t *testing.T) {
+	db, mock := newMockDB(t)
+	userRepository := repositories.NewMemoryUserRepository()
+	user := &models.User{FirstName: "Jane", LastName: "Doe", Email: "jane.doe@mail.com"}
+	if err := userRepository.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("error creating user: %v", err)
+	}
+	mailer := mail.NewMemoryMailer()
+	service := NewService(
+		userRepository,
+		repositories.NewSQLVerificationRepository(db),
+		mailer,
+		"https://api.example.com/verify-email",
+		time.Hour,
+	)
+
+	storedHash := &capture{}
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`INSERT INTO "email_verification_tokens"`)).
+		WithArgs(
+			user.Id,
+			tenancy.DefaultTenant,
+			user.Email,
+			storedHash,
+			sqlmock.AnyArg(),
+			nil,
+			sqlmock.AnyArg(),
+		).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := service.Send(context.Background(), user.Id)
+
+	assert.NoError(t, err, "Should send verification email")
+	assert.NoError(t, mock.ExpectationsWereMet())
+	messages := mailer.Messages()
+	if !assert.Len(t, messages, 1, "Should send one message") {
+		return
+	}
+	assert.Equal(t, "jane.doe@mail.com", messages[0].To, "Should send to user email")
+
+	start := strings.Index(messages[0].Body, "https://")
+	link, err := url.Parse(strings.Fields(messages[0].Body[start:])[0])
+	if err != nil {
+		t.Fatalf("error parsing link: %v", err)
+	}
+	token := link.Query().Get("token")
+	assert.NotEmpty(t, token, "Should include token in link")
+	assert.Equal(t, securetoken.Hash(token), storedHash.value, "Should only store the token hash")
+}
+
+func TestService_VerifyOtherTenant(t *testing.T) {
+	db, mock := newMockDB(t)
+	if err := db.Use(tenancy.Plugin{}); err != nil {
+		t.Fatalf("error registering tenancy plugin: %v", err)
+	}
+	service := NewService(
+		repositories.NewMemoryUserRepository(),
+		repositories.NewSQLVerificationRepository(db),
+		mail.NewMemoryMailer(),
+		"https://api.example.com/verify-email",
+		time.Hour,
+	)
+	tokenHash := securetoken.Hash("verify-token")
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(
+		`SELECT * FROM "email_verification_tokens" `+
+			`WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2`,
+	)).
+		WithArgs(tokenHash, sqlmock.AnyArg()).
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "user_id", "tenant_id", "email", "token_hash"}).
+				AddRow(1, "abc123", "acme", "jane.doe@mail.com", tokenHash),
+		)
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "email_verification_tokens" SET "used_at"=$1 `+
+			`WHERE "email_verification_tokens"."tenant_id" = $2 AND "id" = $3`,
+	)).
+		WithArgs(sqlmock.AnyArg(), "acme", 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(
+		`UPDATE "users" SET "email_verified"=$1,"updated_at"=$2 `+
+			`WHERE (id = $3 AND email = $4) AND "users"."tenant_id" = $5`,
+	)).
+		WithArgs(true, sqlmock.AnyArg(), "abc123", "jane.doe@mail.com", "acme").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Anonymous callers of the link are in the default tenant.
+	ctx := tenancy.WithTenant(context.Background(), tenancy.DefaultTenant)
+	token, err := service.Verify(ctx, "verify-token")
+
+	assert.NoError(t, err, "Should verify email in tenant of token")
+	assert.Equal(t, "acme", token.TenantId, "Should return token of tenant")
+	assert.NoError(t, mock.ExpectationsWereMet(), "Should update user in tenant of token")
+}
+
+type capture struct {
+	value driver.Value
+}
+
+func (capture *capture) Match(value driver.Value) bool {
+	capture.value = value
+	return true
+}
+
+func TestHook_EmailChanged(t *testing.T) {
+	db, mock := newMockDB(t)
+	userRepository := repositories.NewSQLUserRepository(db, NewHook())
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM "users" WHERE id = $1`)).
+		WithArgs("abc123").
+		WillReturnRows(
+			sqlmock.NewRows([]string{"id", "first_name", "last_name", "email", "email_verified"}).
+				AddRow("abc123", "Jane", "Doe", "jane.doe@mail.com", true),
+		)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "email"=$1`)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE "users" SET "email_verified"=$1`)).
+		WithArgs(false, sqlmock.AnyArg(), "abc123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(
+		`DELETE FROM "email_verification_tokens" WHERE user_id = $1 AND used_at IS NULL`,
+	)).
+		WithArgs("abc123").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	user, err := userRepository.UpdateUserById(
+		context.Background(),
+		"abc123",
+		&models.User{Email: "jane@mail.com"},
+	)
+
+	assert.NoError(t, err, "Should update user")
+	assert.False(t, user.EmailVerified, "Should require verification of new email")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
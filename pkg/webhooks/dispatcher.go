@@ -0,0 +1,266 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+)
+
+const (
+	maxErrorLength   = 512
+	wildcardEvent    = "*"
+	defaultBatchSize = 50
+)
+
+type RetryPolicy struct {
+	MaxAttempts int
+	MinBackoff  time.Duration
+	MaxBackoff  time.Duration
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 8,
+	MinBackoff:  10 * time.Second,
+	MaxBackoff:  6 * time.Hour,
+}
+
+type Option func(dispatcher *Dispatcher)
+
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.httpClient = httpClient
+	}
+}
+
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.retryPolicy = policy
+	}
+}
+
+func WithInterval(interval time.Duration) Option {
+	return func(dispatcher *Dispatcher) {
+		dispatcher.interval = interval
+	}
+}
+
+type Dispatcher struct {
+	webhookRepository repositories.WebhookRepository
+	httpClient        *http.Client
+	retryPolicy       RetryPolicy
+	interval          time.Duration
+	batchSize         int
+	now               func() time.Time
+}
+
+func NewDispatcher(
+	webhookRepository repositories.WebhookRepository,
+	options ...Option,
+) *Dispatcher {
+	dispatcher := &Dispatcher{
+		webhookRepository: webhookRepository,
+		httpClient:        newHTTPClient(10 * time.Second),
+		retryPolicy:       DefaultRetryPolicy,
+		interval:          time.Second,
+		batchSize:         defaultBatchSize,
+		now:               time.Now,
+	}
+	for _, option := range options {
+		option(dispatcher)
+	}
+	return dispatcher
+}
+
+// Publish schedules deliveries of event to the active webhooks that subscribe
+// to it and are registered under the tenant of the event.
+func (dispatcher *Dispatcher) Publish(_ context.Context, event *events.Event) error {
+	webhooks, err := dispatcher.webhookRepository.GetActiveWebhooks()
+	if err != nil {
+		return fmt.Errorf("error getting webhooks: %v", err)
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error marshaling event: %v", err)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	for _, webhook := range webhooks {
+		if webhook.TenantId != event.TenantId || !Subscribed(webhook, event.Type) {
+			continue
+		}
+		deliveries = append(deliveries, &models.WebhookDelivery{
+			WebhookId:     webhook.Id,
+			EventId:       event.Id,
+			EventType:     event.Type,
+			Payload:       body,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: dispatcher.now(),
+		})
+	}
+
+	err = dispatcher.webhookRepository.CreateDeliveries(deliveries)
+	if err != nil {
+		return fmt.Errorf("error scheduling webhook deliveries: %v", err)
+	}
+	return nil
+}
+
+func (dispatcher *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(dispatcher.interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, err := dispatcher.ProcessBatch(ctx)
+		if err != nil {
+			log.Printf("error dispatching webhooks: %v", err)
+		}
+		if delivered == dispatcher.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ProcessBatch claims and delivers up to a batch of due deliveries one at a
+// time, so that each lease only has to cover a single request.
+func (dispatcher *Dispatcher) ProcessBatch(ctx context.Context) (int, error) {
+	lease := dispatcher.httpClient.Timeout + dispatcher.interval
+	processed := 0
+	for processed < dispatcher.batchSize && ctx.Err() == nil {
+		deliveries, err := dispatcher.webhookRepository.ClaimDueDeliveries(
+			dispatcher.now(),
+			lease,
+			1,
+		)
+		if err != nil {
+			return processed, fmt.Errorf("error claiming webhook deliveries: %v", err)
+		}
+		if len(deliveries) == 0 {
+			break
+		}
+
+		err = dispatcher.deliver(ctx, deliveries[0])
+		if err != nil {
+			log.Printf("error delivering webhook delivery %d: %v", deliveries[0].Id, err)
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (dispatcher *Dispatcher) deliver(ctx context.Context, delivery *models.WebhookDelivery) error {
+	webhook, err := dispatcher.webhookRepository.GetWebhookById(delivery.WebhookId)
+	if err != nil {
+		return fmt.Errorf("error getting webhook %s: %v", delivery.WebhookId, err)
+	}
+
+	start := dispatcher.now()
+	statusCode, sendErr := dispatcher.send(ctx, webhook, delivery)
+	delivery.Attempts++
+	attempt := &models.WebhookAttempt{
+		Attempt:     delivery.Attempts,
+		StatusCode:  statusCode,
+		DurationMs:  dispatcher.now().Sub(start).Milliseconds(),
+		AttemptedAt: start,
+	}
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = ""
+
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliverySucceeded
+	case delivery.Attempts >= dispatcher.retryPolicy.MaxAttempts:
+		log.Printf("dead-lettering webhook delivery %d: %v", delivery.Id, sendErr)
+		delivery.Status = models.DeliveryDead
+	default:
+		delivery.Status = models.DeliveryPending
+		delivery.NextAttemptAt = dispatcher.now().Add(dispatcher.backoff(delivery.Attempts))
+	}
+	if sendErr != nil {
+		attempt.Error = truncate(sendErr.Error(), maxErrorLength)
+		delivery.LastError = attempt.Error
+	}
+
+	err = dispatcher.webhookRepository.RecordAttempt(delivery, attempt)
+	if err != nil {
+		return fmt.Errorf("error recording webhook attempt: %v", err)
+	}
+	return nil
+}
+
+func (dispatcher *Dispatcher) send(
+	ctx context.Context,
+	webhook *models.Webhook,
+	delivery *models.WebhookDelivery,
+) (int, error) {
+	request, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		webhook.URL,
+		bytes.NewReader(delivery.Payload),
+	)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %v", err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(IdHeader, strconv.FormatInt(delivery.Id, 10))
+	request.Header.Set(EventHeader, delivery.EventType)
+	request.Header.Set(SignatureHeader, Sign(webhook.Secret, dispatcher.now(), delivery.Payload))
+
+	response, err := dispatcher.httpClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("unexpected status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+func (dispatcher *Dispatcher) backoff(attempt int) time.Duration {
+	backoff := dispatcher.retryPolicy.MinBackoff << (attempt - 1)
+	if backoff <= 0 || backoff > dispatcher.retryPolicy.MaxBackoff {
+		backoff = dispatcher.retryPolicy.MaxBackoff
+	}
+	if backoff <= 0 {
+		return 0
+	}
+	half := int64(backoff) / 2
+	return time.Duration(half + rand.Int63n(half+1))
+}
+
+func Subscribed(webhook *models.Webhook, eventType string) bool {
+	for _, filter := range webhook.Events {
+		if filter == wildcardEvent || filter == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
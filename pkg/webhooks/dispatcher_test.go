@@ -0,0 +1,210 @@
+package webhooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/repositories"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const secret = "0123456789abcdef"
+
+type clock struct {
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	return c.now
+}
+
+func newDispatcher(
+	t *testing.T,
+	handler http.HandlerFunc,
+	events []string,
+) (*Dispatcher, *repositories.WebhookMemoryRepository, *models.Webhook, *clock) {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	webhookRepository := repositories.NewMemoryWebhookRepository()
+	webhook := &models.Webhook{URL: server.URL, Events: events, Secret: secret, Active: true}
+	err := webhookRepository.CreateWebhook(webhook)
+	if err != nil {
+		t.Fatalf("error creating webhook: %v", err)
+	}
+
+	c := &clock{now: time.Now()}
+	dispatcher := NewDispatcher(
+		webhookRepository,
+		WithHTTPClient(server.Client()),
+		WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			MinBackoff:  time.Minute,
+			MaxBackoff:  time.Hour,
+		}),
+	)
+	dispatcher.now = c.Now
+	return dispatcher, webhookRepository, webhook, c
+}
+
+func TestSignature(t *testing.T) {
+	body := []byte(`{"id":1}`)
+	header := Sign(secret, time.Now(), body)
+
+	assert.NoError(t, Verify(secret, header, body, time.Minute), "Should verify signature")
+	assert.ErrorIs(
+		t,
+		Verify("wrong", header, body, time.Minute),
+		ErrInvalidSignature,
+		"Should reject wrong secret",
+	)
+	assert.ErrorIs(
+		t,
+		Verify(secret, header, []byte(`{"id":2}`), time.Minute),
+		ErrInvalidSignature,
+		"Should reject tampered body",
+	)
+	assert.ErrorIs(
+		t,
+		Verify(secret, Sign(secret, time.Now().Add(-time.Hour), body), body, time.Minute),
+		ErrInvalidSignature,
+		"Should reject stale timestamp",
+	)
+}
+
+func newEvent(id int64, eventType string) *events.Event {
+	return &events.Event{Id: id, TenantId: tenancy.DefaultTenant, Type: eventType}
+}
+
+func TestDispatcher_Deliver(t *testing.T) {
+	var received int32
+	dispatcher, webhookRepository, webhook, _ := newDispatcher(
+		t,
+		func(writer http.ResponseWriter, request *http.Request) {
+			body, _ := io.ReadAll(request.Body)
+			err := Verify(secret, request.Header.Get(SignatureHeader), body, time.Minute)
+			assert.NoError(t, err, "Should sign payload")
+			assert.Equal(t, events.UserCreated, request.Header.Get(EventHeader))
+			atomic.AddInt32(&received, 1)
+		},
+		[]string{events.UserCreated},
+	)
+	ctx := context.Background()
+
+	_ = dispatcher.Publish(ctx, newEvent(1, events.UserCreated))
+	_ = dispatcher.Publish(ctx, newEvent(1, events.UserCreated))
+	_ = dispatcher.Publish(ctx, newEvent(2, events.UserDeleted))
+	_ = dispatcher.Publish(ctx, &events.Event{Id: 3, TenantId: "acme", Type: events.UserCreated})
+
+	delivered, err := dispatcher.ProcessBatch(ctx)
+	assert.NoError(t, err, "Should process batch")
+	assert.Equal(t, 1, delivered, "Should only deliver subscribed events of tenant once")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&received), "Should receive one request")
+
+	deliveries, _ := webhookRepository.GetDeliveriesByWebhookId(webhook.Id)
+	assert.Equal(t, models.DeliverySucceeded, deliveries[0].Status, "Should mark delivery succeeded")
+}
+
+func TestDispatcher_RetryAndDeadLetter(t *testing.T) {
+	var received int32
+	dispatcher, webhookRepository, webhook, c := newDispatcher(
+		t,
+		func(writer http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&received, 1)
+			writer.WriteHeader(http.StatusInternalServerError)
+		},
+		[]string{wildcardEvent},
+	)
+	ctx := context.Background()
+	_ = dispatcher.Publish(ctx, newEvent(1, events.UserUpdated))
+
+	for i := 0; i < 3; i++ {
+		delivered, err := dispatcher.ProcessBatch(ctx)
+		assert.NoError(t, err, "Should process batch")
+		assert.Equal(t, 1, delivered, "Should attempt delivery once it is due")
+
+		delivered, _ = dispatcher.ProcessBatch(ctx)
+		assert.Equal(t, 0, delivered, "Should wait for backoff before retrying")
+		c.now = c.now.Add(2 * time.Hour)
+	}
+
+	delivery, _ := webhookRepository.GetDeliveryById(webhook.Id, 1)
+	assert.Equal(t, models.DeliveryDead, delivery.Status, "Should dead-letter after max attempts")
+	assert.Len(t, delivery.AttemptLog, 3, "Should record every attempt")
+	assert.Equal(t, http.StatusInternalServerError, delivery.AttemptLog[2].StatusCode)
+
+	_, err := webhookRepository.ReplayDelivery(webhook.Id, 1)
+	assert.NoError(t, err, "Should replay delivery")
+	delivered, _ := dispatcher.ProcessBatch(ctx)
+	assert.Equal(t, 1, delivered, "Should deliver replayed delivery")
+	assert.Equal(t, int32(4), atomic.LoadInt32(&received), "Should match number of requests")
+}
+
+func TestDispatcher_ContinueAfterError(t *testing.T) {
+	var received int32
+	dispatcher, webhookRepository, _, _ := newDispatcher(
+		t,
+		func(http.ResponseWriter, *http.Request) {
+			atomic.AddInt32(&received, 1)
+		},
+		[]string{wildcardEvent},
+	)
+	ctx := context.Background()
+	_ = webhookRepository.CreateDeliveries([]*models.WebhookDelivery{
+		{
+			WebhookId:     "deleted",
+			EventId:       1,
+			EventType:     events.UserCreated,
+			Status:        models.DeliveryPending,
+			NextAttemptAt: dispatcher.now(),
+		},
+	})
+	_ = dispatcher.Publish(ctx, newEvent(2, events.UserCreated))
+
+	processed, err := dispatcher.ProcessBatch(ctx)
+	assert.NoError(t, err, "Should process batch")
+	assert.Equal(t, 2, processed, "Should process every due delivery")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&received), "Should deliver after failed delivery")
+}
+
+func TestValidateURL(t *testing.T) {
+	testCases := []struct {
+		url         string
+		expectedErr bool
+		reason      string
+	}{
+		{url: "https://example.com/hook", expectedErr: false, reason: "Should accept public host"},
+		{url: "https://93.184.216.34/hook", expectedErr: false, reason: "Should accept public ip"},
+		{url: "ftp://example.com/hook", expectedErr: true, reason: "Should reject other schemes"},
+		{url: "http://localhost:8080/hook", expectedErr: true, reason: "Should reject localhost"},
+		{url: "http://127.0.0.1/hook", expectedErr: true, reason: "Should reject loopback"},
+		{url: "http://[::1]/hook", expectedErr: true, reason: "Should reject ipv6 loopback"},
+		{url: "http://10.0.0.5/hook", expectedErr: true, reason: "Should reject private ip"},
+		{url: "http://169.254.169.254/", expectedErr: true, reason: "Should reject link-local"},
+	}
+
+	for i, tc := range testCases {
+		t.Run(fmt.Sprintf("Test %d: %s", i, tc.reason), func(t *testing.T) {
+			err := ValidateURL(tc.url)
+			assert.Equal(t, tc.expectedErr, err != nil, "Should match validation result")
+		})
+	}
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	_, err := newHTTPClient(time.Second).Get(server.URL)
+	assert.ErrorIs(t, err, ErrForbiddenTarget, "Should refuse to connect to loopback")
+}
@@ -0,0 +1,244 @@
+package wsapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+)
+
+type connection struct {
+	conn      *websocket.Conn
+	principal *auth.Principal
+	tenantId  string
+	limits    Limits
+	send      chan *ServerMessage
+
+	mu      sync.Mutex
+	all     bool
+	userIds map[string]bool
+}
+
+// newConnection creates a connection for principal. Events of other tenants
+// than tenantId are not forwarded, unless tenantId is empty.
+func newConnection(
+	conn *websocket.Conn,
+	principal *auth.Principal,
+	tenantId string,
+	limits Limits,
+) *connection {
+	return &connection{
+		conn:      conn,
+		principal: principal,
+		tenantId:  tenantId,
+		limits:    limits,
+		send:      make(chan *ServerMessage, limits.SendQueueSize),
+		userIds:   map[string]bool{},
+	}
+}
+
+func (connection *connection) run(ctx context.Context, subscription *events.Subscription) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	go connection.readLoop(cancel)
+	go connection.forward(ctx, cancel, subscription)
+	connection.writeLoop(ctx)
+}
+
+func (connection *connection) readLoop(cancel context.CancelFunc) {
+	defer cancel()
+
+	connection.conn.SetReadLimit(connection.limits.MaxMessageSize)
+	_ = connection.conn.SetReadDeadline(time.Now().Add(connection.limits.PongTimeout))
+	connection.conn.SetPongHandler(func(string) error {
+		return connection.conn.SetReadDeadline(time.Now().Add(connection.limits.PongTimeout))
+	})
+
+	for {
+		var message ClientMessage
+		err := connection.conn.ReadJSON(&message)
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(
+				err,
+				websocket.CloseNormalClosure,
+				websocket.CloseGoingAway,
+			) {
+				log.Printf("error reading websocket message: %v", err)
+			}
+			return
+		}
+
+		reply := connection.handle(message)
+		if !connection.enqueue(reply) {
+			return
+		}
+	}
+}
+
+func (connection *connection) handle(message ClientMessage) *ServerMessage {
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+
+	switch message.Type {
+	case MessageSubscribe:
+		if !connection.allowed(message) {
+			return &ServerMessage{
+				Type: MessageError,
+				Message: fmt.Sprintf(
+					"subscribing to other users requires the %s role",
+					auth.RoleAdmin,
+				),
+			}
+		}
+		if message.All {
+			connection.all = true
+		}
+		for _, id := range message.UserIds {
+			if len(connection.userIds) >= connection.limits.MaxSubscriptions {
+				return &ServerMessage{
+					Type: MessageError,
+					Message: fmt.Sprintf(
+						"cannot subscribe to more than %d users",
+						connection.limits.MaxSubscriptions,
+					),
+				}
+			}
+			connection.userIds[id] = true
+		}
+	case MessageUnsubscribe:
+		if message.All {
+			connection.all = false
+		}
+		for _, id := range message.UserIds {
+			delete(connection.userIds, id)
+		}
+	default:
+		return &ServerMessage{
+			Type:    MessageError,
+			Message: fmt.Sprintf("unknown message type %q", message.Type),
+		}
+	}
+	return connection.subscriptions()
+}
+
+// allowed reports whether the principal may subscribe to the users of message.
+// Users can follow their own changes and admins those of every user.
+func (connection *connection) allowed(message ClientMessage) bool {
+	if connection.principal.HasRole(auth.RoleAdmin) {
+		return true
+	}
+	if message.All {
+		return false
+	}
+	for _, id := range message.UserIds {
+		if id != connection.principal.Subject {
+			return false
+		}
+	}
+	return true
+}
+
+func (connection *connection) subscriptions() *ServerMessage {
+	userIds := make([]string, 0, len(connection.userIds))
+	for id := range connection.userIds {
+		userIds = append(userIds, id)
+	}
+	sort.Strings(userIds)
+	return &ServerMessage{Type: MessageSubscribed, UserIds: userIds, All: connection.all}
+}
+
+func (connection *connection) matches(event *events.Event) bool {
+	if connection.tenantId != "" && event.TenantId != connection.tenantId {
+		return false
+	}
+
+	connection.mu.Lock()
+	defer connection.mu.Unlock()
+	return connection.all || connection.userIds[event.AggregateId]
+}
+
+func (connection *connection) forward(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	subscription *events.Subscription,
+) {
+	defer cancel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return
+			}
+			if !connection.matches(event) {
+				continue
+			}
+			if !connection.enqueue(&ServerMessage{Type: MessageEvent, Event: event}) {
+				return
+			}
+		}
+	}
+}
+
+func (connection *connection) enqueue(message *ServerMessage) bool {
+	select {
+	case connection.send <- message:
+		return true
+	default:
+		log.Printf("closing websocket connection with full send queue")
+		return false
+	}
+}
+
+func (connection *connection) writeLoop(ctx context.Context) {
+	ticker := time.NewTicker(connection.limits.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			connection.close(websocket.CloseTryAgainLater, "connection closed")
+			return
+		case message := <-connection.send:
+			data, err := json.Marshal(message)
+			if err != nil {
+				log.Printf("error marshaling websocket message: %v", err)
+				continue
+			}
+			if !connection.write(websocket.TextMessage, data) {
+				return
+			}
+		case <-ticker.C:
+			if !connection.write(websocket.PingMessage, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (connection *connection) write(messageType int, data []byte) bool {
+	_ = connection.conn.SetWriteDeadline(time.Now().Add(connection.limits.WriteTimeout))
+	err := connection.conn.WriteMessage(messageType, data)
+	if err != nil {
+		log.Printf("error writing websocket message: %v", err)
+		return false
+	}
+	return true
+}
+
+func (connection *connection) close(code int, reason string) {
+	deadline := time.Now().Add(connection.limits.WriteTimeout)
+	message := websocket.FormatCloseMessage(code, reason)
+	_ = connection.conn.WriteControl(websocket.CloseMessage, message, deadline)
+}
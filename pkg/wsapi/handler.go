@@ -0,0 +1,101 @@
+package wsapi
+
+import (
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/models"
+	"github.com/johannaojeling/go-rest-api/pkg/openapi"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+const websocketTag = "websocket"
+
+type Handler struct {
+	broker      *events.Broker
+	limits      Limits
+	upgrader    websocket.Upgrader
+	connections int32
+}
+
+func NewHandler(broker *events.Broker, limits Limits) *Handler {
+	return &Handler{
+		broker: broker,
+		limits: limits,
+	}
+}
+
+func (handler *Handler) Register(routerGroup *gin.RouterGroup) {
+	for _, route := range handler.Routes() {
+		routerGroup.Handle(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (handler *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{
+			Method:      http.MethodGet,
+			Path:        "",
+			Handler:     handler.Connect,
+			OperationId: "connectWebSocket",
+			Summary:     "Subscribe to user changes over a WebSocket",
+			Tags:        []string{websocketTag},
+			Responses: map[int]any{
+				http.StatusSwitchingProtocols: nil,
+				http.StatusBadRequest:         nil,
+				http.StatusUnauthorized:       models.ErrorMessage{},
+				http.StatusServiceUnavailable: models.ErrorMessage{},
+			},
+		},
+	}
+}
+
+func (handler *Handler) Connect(ctx *gin.Context) {
+	principal, ok := auth.PrincipalFromContext(ctx.Request.Context())
+	if !ok {
+		ctx.AbortWithStatusJSON(
+			http.StatusUnauthorized,
+			models.NewErrorMessage("authentication required"),
+		)
+		return
+	}
+
+	if !handler.acquire() {
+		log.Printf("rejecting websocket connection, limit of %d reached", handler.limits.MaxConnections)
+		ctx.AbortWithStatusJSON(
+			http.StatusServiceUnavailable,
+			models.NewErrorMessage("too many connections, try again later"),
+		)
+		return
+	}
+	defer atomic.AddInt32(&handler.connections, -1)
+
+	conn, err := handler.upgrader.Upgrade(ctx.Writer, ctx.Request, nil)
+	if err != nil {
+		log.Printf("error upgrading websocket connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	subscription := handler.broker.Subscribe()
+	defer subscription.Close()
+
+	tenantId, _ := tenancy.TenantFromContext(ctx.Request.Context())
+	newConnection(conn, principal, tenantId, handler.limits).
+		run(ctx.Request.Context(), subscription)
+}
+
+func (handler *Handler) acquire() bool {
+	connections := atomic.AddInt32(&handler.connections, 1)
+	if handler.limits.MaxConnections > 0 && int(connections) > handler.limits.MaxConnections {
+		atomic.AddInt32(&handler.connections, -1)
+		return false
+	}
+	return true
+}
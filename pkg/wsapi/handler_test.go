@@ -0,0 +1,160 @@
+package wsapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/johannaojeling/go-rest-api/pkg/auth"
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+	"github.com/johannaojeling/go-rest-api/pkg/tenancy"
+)
+
+func newServer(
+	t *testing.T,
+	broker *events.Broker,
+	limits Limits,
+	principal *auth.Principal,
+) string {
+	router := gin.New()
+	if principal != nil {
+		router.Use(func(ctx *gin.Context) {
+			ctx.Request = ctx.Request.WithContext(
+				auth.WithPrincipal(ctx.Request.Context(), principal),
+			)
+		})
+	}
+	NewHandler(broker, limits).Register(router.Group("/ws"))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+}
+
+func dial(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err, "Should open websocket connection")
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) ServerMessage {
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var message ServerMessage
+	require.NoError(t, conn.ReadJSON(&message), "Should read message")
+	return message
+}
+
+func TestHandler_Subscriptions(t *testing.T) {
+	broker := events.NewBroker(8, 0)
+	admin := &auth.Principal{Subject: "ops", Roles: []string{auth.RoleAdmin}}
+	conn := dial(t, newServer(t, broker, DefaultLimits, admin))
+	ctx := context.Background()
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{
+		Type:    MessageSubscribe,
+		UserIds: []string{"abc123", "abc124"},
+	}))
+	assert.Equal(
+		t,
+		ServerMessage{Type: MessageSubscribed, UserIds: []string{"abc123", "abc124"}},
+		readMessage(t, conn),
+		"Should confirm subscription",
+	)
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{
+		Type:    MessageUnsubscribe,
+		UserIds: []string{"abc124"},
+	}))
+	assert.Equal(
+		t,
+		[]string{"abc123"},
+		readMessage(t, conn).UserIds,
+		"Should confirm unsubscription",
+	)
+
+	_ = broker.Publish(ctx, &events.Event{Id: 1, Type: events.UserCreated, AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 2, Type: events.UserUpdated, AggregateId: "abc123"})
+
+	message := readMessage(t, conn)
+	assert.Equal(t, MessageEvent, message.Type, "Should push event")
+	assert.Equal(t, int64(2), message.Event.Id, "Should only push subscribed users")
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{Type: "publish"}))
+	assert.Equal(t, MessageError, readMessage(t, conn).Type, "Should reject unknown messages")
+}
+
+func TestHandler_ConnectionLimit(t *testing.T) {
+	limits := DefaultLimits
+	limits.MaxConnections = 1
+	url := newServer(t, events.NewBroker(8, 0), limits, &auth.Principal{Subject: "abc123"})
+
+	_ = dial(t, url)
+	_, response, err := websocket.DefaultDialer.Dial(url, nil)
+
+	assert.Error(t, err, "Should reject connections above limit")
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+}
+
+func TestHandler_Authorization(t *testing.T) {
+	broker := events.NewBroker(8, 0)
+
+	_, response, err := websocket.DefaultDialer.Dial(newServer(t, broker, DefaultLimits, nil), nil)
+	assert.Error(t, err, "Should reject anonymous connections")
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	principal := &auth.Principal{Subject: "abc123"}
+	conn := dial(t, newServer(t, broker, DefaultLimits, principal))
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{Type: MessageSubscribe, All: true}))
+	assert.Equal(t, MessageError, readMessage(t, conn).Type, "Should reserve all users for admins")
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{
+		Type:    MessageSubscribe,
+		UserIds: []string{"abc123", "abc124"},
+	}))
+	assert.Equal(t, MessageError, readMessage(t, conn).Type, "Should reject other users")
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{
+		Type:    MessageSubscribe,
+		UserIds: []string{"abc123"},
+	}))
+	assert.Equal(
+		t,
+		ServerMessage{Type: MessageSubscribed, UserIds: []string{"abc123"}},
+		readMessage(t, conn),
+		"Should allow own user",
+	)
+}
+
+func TestHandler_TenantEvents(t *testing.T) {
+	broker := events.NewBroker(8, 0)
+	admin := &auth.Principal{Subject: "ops", TenantId: "acme", Roles: []string{auth.RoleAdmin}}
+	router := gin.New()
+	router.Use(func(ctx *gin.Context) {
+		requestCtx := auth.WithPrincipal(ctx.Request.Context(), admin)
+		ctx.Request = ctx.Request.WithContext(tenancy.WithTenant(requestCtx, "acme"))
+	})
+	NewHandler(broker, DefaultLimits).Register(router.Group("/ws"))
+	server := httptest.NewServer(router)
+	t.Cleanup(server.Close)
+	conn := dial(t, "ws"+strings.TrimPrefix(server.URL, "http")+"/ws")
+	ctx := context.Background()
+
+	require.NoError(t, conn.WriteJSON(ClientMessage{Type: MessageSubscribe, All: true}))
+	assert.Equal(t, MessageSubscribed, readMessage(t, conn).Type, "Should confirm subscription")
+
+	_ = broker.Publish(ctx, &events.Event{Id: 1, TenantId: "globex", AggregateId: "abc124"})
+	_ = broker.Publish(ctx, &events.Event{Id: 2, TenantId: "acme", AggregateId: "abc123"})
+
+	message := readMessage(t, conn)
+	assert.Equal(t, MessageEvent, message.Type, "Should push event")
+	assert.Equal(t, int64(2), message.Event.Id, "Should only push events of tenant")
+}
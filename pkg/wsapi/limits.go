@@ -0,0 +1,25 @@
+package wsapi
+
+import (
+	"time"
+)
+
+type Limits struct {
+	MaxConnections   int
+	MaxSubscriptions int
+	SendQueueSize    int
+	MaxMessageSize   int64
+	PingInterval     time.Duration
+	PongTimeout      time.Duration
+	WriteTimeout     time.Duration
+}
+
+var DefaultLimits = Limits{
+	MaxConnections:   1000,
+	MaxSubscriptions: 100,
+	SendQueueSize:    64,
+	MaxMessageSize:   4096,
+	PingInterval:     30 * time.Second,
+	PongTimeout:      60 * time.Second,
+	WriteTimeout:     10 * time.Second,
+}
@@ -0,0 +1,27 @@
+package wsapi
+
+import (
+	"github.com/johannaojeling/go-rest-api/pkg/events"
+)
+
+const (
+	MessageSubscribe   = "subscribe"
+	MessageUnsubscribe = "unsubscribe"
+	MessageSubscribed  = "subscribed"
+	MessageEvent       = "event"
+	MessageError       = "error"
+)
+
+type ClientMessage struct {
+	Type    string   `json:"type"`
+	UserIds []string `json:"user_ids,omitempty"`
+	All     bool     `json:"all,omitempty"`
+}
+
+type ServerMessage struct {
+	Type    string        `json:"type"`
+	UserIds []string      `json:"user_ids,omitempty"`
+	All     bool          `json:"all,omitempty"`
+	Event   *events.Event `json:"event,omitempty"`
+	Message string        `json:"message,omitempty"`
+}